@@ -0,0 +1,63 @@
+/*
+Copyright 2020 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ApplicationRevisionSpec is the spec of ApplicationRevision, it's an immutable snapshot of
+// an Application and everything used to render it.
+type ApplicationRevisionSpec struct {
+	// Application records the snapshot of the Application spec
+	Application ApplicationSpec `json:"application"`
+
+	// WorkloadDefinitions records the snapshot of the WorkloadDefinitions referenced by the Application, keyed by name
+	// +kubebuilder:pruning:PreserveUnknownFields
+	WorkloadDefinitions map[string]runtime.RawExtension `json:"workloadDefinitions,omitempty"`
+
+	// TraitDefinitions records the snapshot of the TraitDefinitions referenced by the Application, keyed by name
+	// +kubebuilder:pruning:PreserveUnknownFields
+	TraitDefinitions map[string]runtime.RawExtension `json:"traitDefinitions,omitempty"`
+
+	// ApplicationConfiguration records the ApplicationConfiguration rendered from the Application
+	// +kubebuilder:pruning:PreserveUnknownFields
+	ApplicationConfiguration runtime.RawExtension `json:"applicationConfiguration"`
+
+	// Components records the Components rendered from the Application
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Components []runtime.RawExtension `json:"components,omitempty"`
+}
+
+// ApplicationRevision is the Schema for the ApplicationRevision API
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:categories={oam},shortName=apprev
+type ApplicationRevision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ApplicationRevisionSpec `json:"spec,omitempty"`
+}
+
+// ApplicationRevisionList contains a list of ApplicationRevision
+// +kubebuilder:object:root=true
+type ApplicationRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ApplicationRevision `json:"items"`
+}
@@ -54,11 +54,52 @@ type AppStatus struct {
 
 	// Services record the status of the application services
 	Services []ApplicationComponentStatus `json:"services,omitempty"`
+
+	// LatestRevision of the application, it refers to the latest ApplicationRevision snapshot
+	// +optional
+	LatestRevision *Revision `json:"latestRevision,omitempty"`
+
+	// Clusters record the clusters the application is dispatched to according to its placement
+	// +optional
+	Clusters []string `json:"clusters,omitempty"`
+
+	// RenderError records why the application fails to be rendered by the templates of its definitions
+	// +optional
+	RenderError *ApplicationRenderError `json:"renderError,omitempty"`
+}
+
+// ApplicationRenderError records the error of rendering a component or trait by the template of its definition
+type ApplicationRenderError struct {
+	Component string `json:"component,omitempty"`
+	// Trait is the type of the trait failed to be rendered, it's empty if the workload fails
+	Trait      string `json:"trait,omitempty"`
+	Definition string `json:"definition,omitempty"`
+	// Reason is InvalidParameter if the parameters of the component or trait are at fault,
+	// or InvalidTemplate if the template of the definition is at fault
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	// +optional
+	Details []RenderErrorDetail `json:"details,omitempty"`
+}
+
+// RenderErrorDetail is a single error reported by the template engine
+type RenderErrorDetail struct {
+	Message string `json:"message"`
+	// Path is the path of the field in error, e.g. output.spec.replicas
+	Path string `json:"path,omitempty"`
+	// ParameterPath is the path of the offending parameter, e.g. replicas
+	ParameterPath string `json:"parameterPath,omitempty"`
+	// Values are the conflicting values of the field
+	Values []string `json:"values,omitempty"`
+	// Positions are the positions in the template which the error relates to, in the format of file:line:column
+	Positions []string `json:"positions,omitempty"`
 }
 
 // ApplicationComponentStatus record the health status of App component
 type ApplicationComponentStatus struct {
-	Name    string                   `json:"name"`
+	Name string `json:"name"`
+	// Cluster is the name of the cluster the component is dispatched to, empty for the local cluster
+	Cluster string                   `json:"cluster,omitempty"`
 	Healthy bool                     `json:"healthy"`
 	Message string                   `json:"message,omitempty"`
 	Traits  []ApplicationTraitStatus `json:"traits,omitempty"`
@@ -92,13 +133,54 @@ type ApplicationComponent struct {
 	// scopes in ApplicationComponent defines the component-level scopes
 	// the format is <scope-type:scope-instance-name> pairs, the key represents type of `ScopeDefinition` while the value represent the name of scope instance.
 	Scopes map[string]string `json:"scopes,omitempty"`
+
+	// DependsOn specifies the names of the components in the same application which must be ready
+	// before this component is applied.
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// ReadyConditions specify the conditions on the workload of this component that should be satisfied
+	// before the components depending on it are applied. Different conditions are AND-ed together.
+	// If no conditions is specified, the component is considered ready once its workload is created.
+	// +optional
+	ReadyConditions []ConditionRequirement `json:"readyConditions,omitempty"`
 }
 
 // ApplicationSpec is the spec of Application
 type ApplicationSpec struct {
 	Components []ApplicationComponent `json:"components"`
 
-	// TODO(wonderflow): we should have application level scopes supported here
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// scopes in ApplicationSpec defines the application-level scopes which will be applied to every component,
+	// the format is the same as component-level scopes. A component-level scope of the same type takes precedence.
+	Scopes map[string]string `json:"scopes,omitempty"`
+
+	// Policies are application-level traits which will be attached to every component,
+	// a trait with the same name declared by a component takes precedence.
+	// +optional
+	Policies []ApplicationTrait `json:"policies,omitempty"`
+
+	// Placement specifies the clusters the application is dispatched to,
+	// the application is applied to the cluster the controller runs in if it's not specified.
+	// +optional
+	Placement *ApplicationPlacement `json:"placement,omitempty"`
+
+	// RevisionHistoryLimit is the number of ApplicationRevisions kept for rollback, the oldest ones are
+	// deleted once it's exceeded. Defaults to 10.
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+}
+
+// ApplicationPlacement selects the registered clusters, a cluster is registered by a secret which holds its kubeconfig.
+// The clusters selected by names and by labels are merged.
+type ApplicationPlacement struct {
+	// ClusterNames are the names of the registered clusters
+	// +optional
+	ClusterNames []string `json:"clusterNames,omitempty"`
+
+	// ClusterSelector selects the registered clusters by the labels of their secrets
+	// +optional
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -74,7 +74,8 @@ type WorkloadDefinitionSpec struct {
 	Template string `json:"template,omitempty"`
 
 	// TemplateType defines the data format of the template, by default it's CUE format
-	// Terraform HCL, Helm Chart will also be candidates in the near future.
+	// kube(a Kubernetes manifest with parameters patched by field paths) and helm(a Helm chart rendered with parameters as values)
+	// are also supported, Terraform HCL will also be a candidate in the near future.
 	// +optional
 	TemplateType string `json:"templateType,omitempty"`
 
@@ -155,8 +156,7 @@ type TraitDefinitionSpec struct {
 	// +optional
 	Template string `json:"template,omitempty"`
 
-	// TemplateType defines the data format of the template, by default it's CUE format
-	// Terraform HCL, Helm Chart will also be candidates in the near future.
+	// TemplateType defines the data format of the template, only CUE format is supported by trait for now
 	// +optional
 	TemplateType string `json:"templateType,omitempty"`
 
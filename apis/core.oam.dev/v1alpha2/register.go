@@ -109,6 +109,14 @@ var (
 	ApplicationKindVersionKind = SchemeGroupVersion.WithKind(ApplicationKind)
 )
 
+// ApplicationRevision type metadata.
+var (
+	ApplicationRevisionKind             = reflect.TypeOf(ApplicationRevision{}).Name()
+	ApplicationRevisionGroupKind        = schema.GroupKind{Group: Group, Kind: ApplicationRevisionKind}.String()
+	ApplicationRevisionKindAPIVersion   = ApplicationRevisionKind + "." + SchemeGroupVersion.String()
+	ApplicationRevisionGroupVersionKind = SchemeGroupVersion.WithKind(ApplicationRevisionKind)
+)
+
 // Application type metadata.
 var (
 	ApplicationDeploymentKind            = reflect.TypeOf(ApplicationDeployment{}).Name()
@@ -128,4 +136,5 @@ func init() {
 	SchemeBuilder.Register(&HealthScope{}, &HealthScopeList{})
 	SchemeBuilder.Register(&Application{}, &ApplicationList{})
 	SchemeBuilder.Register(&ApplicationDeployment{}, &ApplicationDeploymentList{})
+	SchemeBuilder.Register(&ApplicationRevision{}, &ApplicationRevisionList{})
 }
@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -164,6 +165,11 @@ type RolloutWebhook struct {
 	// Request timeout for this webhook
 	Timeout string `json:"timeout,omitempty"`
 
+	// Retries is the number of times the webhook is retried if it fails, the rollout fails after that
+	// the default is 3
+	// +optional
+	Retries *int32 `json:"retries,omitempty"`
+
 	// Metadata (key-value pairs) for this webhook
 	// +optional
 	Metadata *map[string]string `json:"metadata,omitempty"`
@@ -171,6 +177,13 @@ type RolloutWebhook struct {
 
 // RolloutWebhookPayload holds the info and metadata sent to webhooks
 type RolloutWebhookPayload struct {
+	// Type of the webhook, it tells at which point of the rollout the webhook is called
+	Type HookType `json:"type,omitempty"`
+
+	// Batch is the batch that the webhook is called for, it's only set for the batch webhooks
+	// +optional
+	Batch *int32 `json:"batch,omitempty"`
+
 	// ResourceRef refers to the resource we are operating on
 	ResourceRef *runtimev1alpha1.TypedReference `json:"resourceRef"`
 
@@ -181,12 +194,39 @@ type RolloutWebhookPayload struct {
 	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
+// RolloutWebhookStatus is the result of calling a rollout webhook
+type RolloutWebhookStatus struct {
+	// Type of the webhook
+	Type HookType `json:"type"`
+
+	// Name of the webhook
+	Name string `json:"name"`
+
+	// Batch is the batch that the webhook is called for, it's only meaningful for the batch webhooks
+	// +optional
+	Batch int32 `json:"batch,omitempty"`
+
+	// Succeeded indicates whether the webhook returned a 2xx response
+	Succeeded bool `json:"succeeded"`
+
+	// Attempts is the number of times the webhook is called
+	Attempts int32 `json:"attempts"`
+
+	// Message is the error of the last failed call
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastCallTime is the last time the webhook is called
+	LastCallTime metav1.Time `json:"lastCallTime"`
+}
+
 // CanaryMetric holds the reference to metrics used for canary analysis
 type CanaryMetric struct {
 	// Name of the metric
 	Name string `json:"name"`
 
-	// Interval represents the windows size
+	// Interval represents the windows size, it's a duration like 2m, and the metric is only checked
+	// after the interval has elapsed since the pods of the batch are available
 	Interval string `json:"interval,omitempty"`
 
 	// Range value accepted for this metric
@@ -194,21 +234,42 @@ type CanaryMetric struct {
 	MetricsRange *MetricsExpectedRange `json:"metricsRange,omitempty"`
 
 	// TemplateRef references a metric template object
+	// it's a ConfigMap which contains the provider type, the address of the provider and the query of the metric
 	// +optional
 	TemplateRef *runtimev1alpha1.TypedReference `json:"templateRef,omitempty"`
 }
 
 // MetricsExpectedRange defines the range used for metrics validation
 type MetricsExpectedRange struct {
-	// Minimum value
+	// Minimum value, a string value is parsed as a float number
 	// +optional
 	Min *intstr.IntOrString `json:"min,omitempty"`
 
-	// Maximum value
+	// Maximum value, a string value is parsed as a float number
 	// +optional
 	Max *intstr.IntOrString `json:"max,omitempty"`
 }
 
+// CanaryMetricStatus is the observed value of a canary metric
+type CanaryMetricStatus struct {
+	// Name of the metric
+	Name string `json:"name"`
+
+	// Value is the last observed value of the metric
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// InRange indicates whether the observed value is in the range accepted for this metric
+	InRange bool `json:"inRange"`
+
+	// Message explains why the metric can't be observed
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastCheckTime is the last time the metric is queried
+	LastCheckTime metav1.Time `json:"lastCheckTime"`
+}
+
 // RolloutStatus defines the observed state of a rollout plan
 type RolloutStatus struct {
 	// Conditions represents the latest available observations of a CloneSet's current state.
@@ -234,9 +295,28 @@ type RolloutStatus struct {
 	// it starts from 0
 	CurrentBatch int32 `json:"currentBatch"`
 
+	// RolloutTargetSize is the total number of pods of the workloads in rollout, it's recorded when the rollout
+	// is verified for the workloads whose sizes change during the rollout, e.g. the source and target Deployments
+	// +optional
+	RolloutTargetSize int32 `json:"rolloutTargetSize,omitempty"`
+
 	// UpgradedReplicas is the number of Pods upgraded by the rollout controller
 	UpgradedReplicas int32 `json:"upgradedReplicas"`
 
 	// UpgradedReplicas is the number of Pods upgraded by the rollout controller that have a Ready Condition.
 	UpgradedReadyReplicas int32 `json:"upgradedReadyReplicas"`
+
+	// BatchAvailableTime is the time when all the pods of the current batch became available,
+	// the canary metrics are only checked after their intervals have elapsed since then
+	// +optional
+	BatchAvailableTime *metav1.Time `json:"batchAvailableTime,omitempty"`
+
+	// CanaryMetricStatus records the observed values of the canary metrics of the current batch,
+	// or the canary metrics of the rollout plan when the rollout is finalising
+	// +optional
+	CanaryMetricStatus []CanaryMetricStatus `json:"canaryMetricStatus,omitempty"`
+
+	// RolloutWebhookStatus records the results of the rollout webhooks that have been called
+	// +optional
+	RolloutWebhookStatus []RolloutWebhookStatus `json:"rolloutWebhookStatus,omitempty"`
 }
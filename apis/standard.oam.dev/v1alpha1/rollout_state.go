@@ -183,6 +183,7 @@ func (r *RolloutStatus) StateTransition(event RolloutEvent) {
 	case InitializingState:
 		if event == RollingInitializedEvent {
 			r.RollingState = RollingInBatchesState
+			r.BatchRollingState = BatchInitializingState
 			r.SetConditions(NewPositiveCondition(r.getRolloutConditionType()))
 			return
 		}
@@ -196,6 +196,22 @@ func (in *CanaryMetric) DeepCopy() *CanaryMetric {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryMetricStatus) DeepCopyInto(out *CanaryMetricStatus) {
+	*out = *in
+	in.LastCheckTime.DeepCopyInto(&out.LastCheckTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryMetricStatus.
+func (in *CanaryMetricStatus) DeepCopy() *CanaryMetricStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryMetricStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricsExpectedRange) DeepCopyInto(out *MetricsExpectedRange) {
 	*out = *in
@@ -527,6 +543,24 @@ func (in *RolloutPlan) DeepCopy() *RolloutPlan {
 func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
 	*out = *in
 	in.ConditionedStatus.DeepCopyInto(&out.ConditionedStatus)
+	if in.BatchAvailableTime != nil {
+		in, out := &in.BatchAvailableTime, &out.BatchAvailableTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CanaryMetricStatus != nil {
+		in, out := &in.CanaryMetricStatus, &out.CanaryMetricStatus
+		*out = make([]CanaryMetricStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RolloutWebhookStatus != nil {
+		in, out := &in.RolloutWebhookStatus, &out.RolloutWebhookStatus
+		*out = make([]RolloutWebhookStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
@@ -634,6 +668,11 @@ func (in *RolloutWebhook) DeepCopyInto(out *RolloutWebhook) {
 			}
 		}
 	}
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutWebhook.
@@ -649,6 +688,11 @@ func (in *RolloutWebhook) DeepCopy() *RolloutWebhook {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RolloutWebhookPayload) DeepCopyInto(out *RolloutWebhookPayload) {
 	*out = *in
+	if in.Batch != nil {
+		in, out := &in.Batch, &out.Batch
+		*out = new(int32)
+		**out = **in
+	}
 	if in.ResourceRef != nil {
 		in, out := &in.ResourceRef, &out.ResourceRef
 		*out = new(corev1alpha1.TypedReference)
@@ -678,6 +722,22 @@ func (in *RolloutWebhookPayload) DeepCopy() *RolloutWebhookPayload {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutWebhookStatus) DeepCopyInto(out *RolloutWebhookStatus) {
+	*out = *in
+	in.LastCallTime.DeepCopyInto(&out.LastCallTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutWebhookStatus.
+func (in *RolloutWebhookStatus) DeepCopy() *RolloutWebhookStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutWebhookStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Route) DeepCopyInto(out *Route) {
 	*out = *in
@@ -20,11 +20,22 @@ const (
 	AnnDescription = "definition.oam.dev/description"
 )
 
+const (
+	// CUETemplateType is the default data format of definition templates
+	CUETemplateType = "cue"
+	// KubeTemplateType is the data format of definition templates which are Kubernetes manifests with parameters patched by field paths
+	KubeTemplateType = "kube"
+	// HelmTemplateType is the data format of definition templates which render Helm charts with parameters as values
+	HelmTemplateType = "helm"
+)
+
 const (
 	// StatusDeployed represents the App was deployed
 	StatusDeployed = "Deployed"
 	// StatusStaging represents the App was changed locally and it's spec is diff from the deployed one, or not deployed at all
 	StatusStaging = "Staging"
+	// StatusRenderFailed represents the App failed to be rendered by the templates of its definitions
+	StatusRenderFailed = "RenderFailed"
 )
 
 // EnvMeta stores the info for app environment
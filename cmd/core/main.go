@@ -101,6 +101,10 @@ func main() {
 		"For the purpose of some production environment that workload or trait should not be affected if no spec change, available options: on, off, force.")
 	flag.StringVar(&controllerArgs.CustomRevisionHookURL, "custom-revision-hook-url", "",
 		"custom-revision-hook-url is a webhook url which will let KubeVela core to call with applicationConfiguration and component info and return a customized component revision")
+	flag.StringVar(&controllerArgs.ClusterSecretNamespace, "cluster-secret-namespace", "vela-system",
+		"The namespace of the secrets which hold the kubeconfig of the clusters that applications can be placed to.")
+	flag.StringVar(&controllerArgs.DefinitionSchemaNamespace, "definition-schema-namespace", "vela-system",
+		"The namespace of the ConfigMaps which store the OpenAPI schema of the parameters of WorkloadDefinitions and TraitDefinitions.")
 	flag.StringVar(&disableCaps, "disable-caps", "", "To be disabled builtin capability list.")
 	flag.StringVar(&storageDriver, "storage-driver", driver.LocalDriverName, "Application file save to the storage driver")
 	flag.DurationVar(&syncPeriod, "informer-re-sync-interval", 5*time.Minute,
@@ -155,7 +159,7 @@ func main() {
 
 	if useWebhook {
 		setupLog.Info("vela webhook enabled, will serving at :" + strconv.Itoa(webhookPort))
-		if err = oamwebhook.Register(mgr); err != nil {
+		if err = oamwebhook.Register(mgr, controllerArgs); err != nil {
 			setupLog.Error(err, "unable to setup oam runtime webhook")
 			os.Exit(1)
 		}
@@ -52,7 +52,7 @@ func ApplyTerraform(app *v1alpha2.Application, k8sClient client.Client, ioStream
 			name := wl.Name
 			ioStream.Infof("\nApplying cloud resources %s\n", name)
 
-			tf, err := getTerraformJSONFiles(k8sClient, wl, appFile.Name, namespace)
+			tf, err := getTerraformJSONFiles(k8sClient, wl, appFile, namespace)
 			if err != nil {
 				return nil, fmt.Errorf("failed to get Terraform JSON files from workload %s: %w", name, err)
 			}
@@ -90,6 +90,32 @@ func ApplyTerraform(app *v1alpha2.Application, k8sClient client.Client, ioStream
 	return nativeVelaComponents, nil
 }
 
+// DestroyTerraform destroys the cloud resources of the services which have been applied by Terraform,
+// and removes the secret which stores the output of the cloud resources.
+// The Terraform state is kept locally by the CLI which applied the resources, so the cleanup can only be done
+// by the CLI, the application controller keeps the application from being finalized until the CLI destroys them.
+func DestroyTerraform(services []string, k8sClient client.Client, namespace string) error {
+	for _, name := range services {
+		tfJSONDir := filepath.Join(TerraformBaseLocation, name)
+		if _, err := os.Stat(filepath.Join(tfJSONDir, "main.tf.json")); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		cmd := exec.Command("bash", "-c", "terraform destroy --auto-approve")
+		cmd.Dir = tfJSONDir
+		if err := common.RealtimePrintCommandOutput(cmd, filepath.Join(tfJSONDir, TerraformLog)); err != nil {
+			return fmt.Errorf("failed to destroy cloud resource %s: %w", name, err)
+		}
+		secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		if err := k8sClient.Delete(context.TODO(), secret); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete the output secret of cloud resource %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
 func callTerraform(tfJSONDir string) ([]byte, error) {
 	if err := os.Chdir(tfJSONDir); err != nil {
 		return nil, err
@@ -166,8 +192,8 @@ func generateSecretFromTerraformOutput(k8sClient client.Client, outputList []str
 }
 
 // getTerraformJSONFiles gets Terraform JSON files or modules from workload
-func getTerraformJSONFiles(k8sClient client.Client, wl *Workload, applicationName string, namespace string) ([]byte, error) {
-	pCtx, err := PrepareProcessContext(k8sClient, wl, applicationName, namespace)
+func getTerraformJSONFiles(k8sClient client.Client, wl *Workload, app *Appfile, namespace string) ([]byte, error) {
+	pCtx, err := PrepareProcessContext(k8sClient, wl, app, namespace)
 	if err != nil {
 		return nil, err
 	}
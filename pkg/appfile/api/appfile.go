@@ -46,6 +46,8 @@ type AppFile struct {
 	UpdateTime time.Time          `json:"updateTime,omitempty"`
 	Services   map[string]Service `json:"services"`
 	Secrets    map[string]string  `json:"secrets,omitempty"`
+	// Overrides patches the services for the env with the same name
+	Overrides map[string]Override `json:"overrides,omitempty"`
 
 	configGetter config.Store
 	initialized  bool
@@ -140,8 +142,16 @@ func (app *AppFile) BuildOAMApplication(env *types.EnvMeta, io cmdutil.IOStreams
 	servApp := new(v1alpha2.Application)
 	servApp.SetNamespace(env.Namespace)
 	servApp.SetName(app.Name)
+	if env.Name != "" {
+		// the env is exposed to templates as `context.env`
+		servApp.SetLabels(map[string]string{oam.LabelAppEnv: env.Name})
+	}
 	servApp.Spec.Components = []v1alpha2.ApplicationComponent{}
-	for serviceName, svc := range app.GetServices() {
+	services, err := app.GetServicesForEnv(env.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	for serviceName, svc := range services {
 		if !silence {
 			io.Infof("\nRendering configs for service (%s)...\n", serviceName)
 		}
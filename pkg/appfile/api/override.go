@@ -0,0 +1,52 @@
+package api
+
+import (
+	"fmt"
+)
+
+// Override is the patches of services for an env, keyed by service name
+type Override map[string]Service
+
+// GetServicesForEnv returns the services with the override of the env applied.
+// The override of a service is merged into it recursively: maps are merged, other values are replaced,
+// and a null value removes the field. The services of the AppFile are not changed.
+func (af *AppFile) GetServicesForEnv(envName string) (map[string]Service, error) {
+	override, ok := af.Overrides[envName]
+	if !ok {
+		return af.GetServices(), nil
+	}
+	services := make(map[string]Service, len(af.Services))
+	for name, svc := range af.Services {
+		services[name] = svc
+	}
+	for name, patch := range override {
+		svc, ok := services[name]
+		if !ok {
+			return nil, fmt.Errorf("service %s in the override of env %s is not defined", name, envName)
+		}
+		services[name] = mergeMap(svc, patch)
+	}
+	return services, nil
+}
+
+// mergeMap returns a new map with the patch merged into the base, the base is not changed
+func mergeMap(base, patch map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(patch))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, pv := range patch {
+		if pv == nil {
+			delete(merged, k)
+			continue
+		}
+		baseMap, baseIsMap := merged[k].(map[string]interface{})
+		patchMap, patchIsMap := pv.(map[string]interface{})
+		if baseIsMap && patchIsMap {
+			merged[k] = mergeMap(baseMap, patchMap)
+			continue
+		}
+		merged[k] = pv
+	}
+	return merged
+}
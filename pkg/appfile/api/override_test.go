@@ -0,0 +1,73 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetServicesForEnv(t *testing.T) {
+	af := &AppFile{
+		Name: "test",
+		Services: map[string]Service{
+			"frontend": map[string]interface{}{
+				"type":  "webservice",
+				"image": "nginx:1.19",
+				"env":   []interface{}{map[string]interface{}{"name": "LOG", "value": "debug"}},
+				"scaler": map[string]interface{}{
+					"replicas": 1,
+				},
+				"route": map[string]interface{}{
+					"domain": "dev.example.com",
+					"rules":  map[string]interface{}{"path": "/"},
+				},
+			},
+			"backend": map[string]interface{}{
+				"type":  "worker",
+				"image": "busybox",
+			},
+		},
+		Overrides: map[string]Override{
+			"prod": {
+				"frontend": map[string]interface{}{
+					"image": "nginx:1.20",
+					"env":   nil,
+					"scaler": map[string]interface{}{
+						"replicas": 3,
+					},
+					"route": map[string]interface{}{
+						"domain": "example.com",
+					},
+				},
+			},
+			"broken": {
+				"unknown": map[string]interface{}{"image": "busybox"},
+			},
+		},
+	}
+
+	services, err := af.GetServicesForEnv("dev")
+	assert.NoError(t, err)
+	assert.Equal(t, af.Services, services)
+
+	services, err = af.GetServicesForEnv("prod")
+	assert.NoError(t, err)
+	assert.Equal(t, Service{
+		"type":  "webservice",
+		"image": "nginx:1.20",
+		"scaler": map[string]interface{}{
+			"replicas": 3,
+		},
+		"route": map[string]interface{}{
+			"domain": "example.com",
+			"rules":  map[string]interface{}{"path": "/"},
+		},
+	}, services["frontend"])
+	assert.Equal(t, af.Services["backend"], services["backend"])
+	// the services of the appfile are not changed
+	assert.Equal(t, "nginx:1.19", af.Services["frontend"]["image"])
+	assert.Equal(t, 1, af.Services["frontend"]["scaler"].(map[string]interface{})["replicas"])
+
+	_, err = af.GetServicesForEnv("broken")
+	assert.Error(t, err)
+}
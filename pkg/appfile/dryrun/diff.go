@@ -0,0 +1,340 @@
+package dryrun
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// DiffType is the type of the change of a resource
+type DiffType string
+
+const (
+	// DiffAdded means the resource doesn't exist in the cluster and will be created
+	DiffAdded DiffType = "added"
+	// DiffModified means the resource exists in the cluster and will be changed
+	DiffModified DiffType = "modified"
+	// DiffRemoved means the resource exists in the cluster but is not rendered any more
+	DiffRemoved DiffType = "removed"
+	// DiffUnchanged means the resource in the cluster is the same as the rendered one
+	DiffUnchanged DiffType = "unchanged"
+)
+
+// ResourceDiff is the change of a resource if the application is applied
+type ResourceDiff struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Name       string   `json:"name,omitempty"`
+	Component  string   `json:"component,omitempty"`
+	Type       DiffType `json:"type"`
+	// Diff is the human readable difference between the live resource (-) and the rendered one (+)
+	Diff string `json:"diff,omitempty"`
+}
+
+// LiveDiffer compares the resources rendered from an application with the live ones in the cluster
+type LiveDiffer struct {
+	c client.Reader
+}
+
+// NewLiveDiffer creates a LiveDiffer
+func NewLiveDiffer(c client.Reader) *LiveDiffer {
+	return &LiveDiffer{c: c}
+}
+
+// Diff returns the changes of the ApplicationConfiguration, Components and the workloads and traits they produce,
+// the resources which exist in the cluster but are not rendered any more are reported as removed.
+func (d *LiveDiffer) Diff(ctx context.Context, app *v1alpha2.Application, ac *v1alpha2.ApplicationConfiguration,
+	comps []*v1alpha2.Component) ([]ResourceDiff, error) {
+	var diffs []ResourceDiff
+
+	liveAC := &v1alpha2.ApplicationConfiguration{}
+	err := d.c.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: ac.Name}, liveAC)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "get ApplicationConfiguration %s", ac.Name)
+	}
+	var liveObj interface{}
+	if err == nil {
+		liveObj = liveAC
+	}
+	acDiff, err := diffResource(liveObj, ac)
+	if err != nil {
+		return nil, err
+	}
+	diffs = append(diffs, newResourceDiff(v1alpha2.ApplicationConfigurationGroupVersionKind, ac.Name, "", acDiff))
+
+	// resources referenced by the live AC, the ones not matched by rendered resources will be removed
+	liveRefs := map[liveRef]string{}
+	if liveObj != nil {
+		for _, w := range liveAC.Status.Workloads {
+			liveRefs[newLiveRef(w.Reference)] = w.ComponentName
+			for _, tr := range w.Traits {
+				liveRefs[newLiveRef(tr.Reference)] = w.ComponentName
+			}
+		}
+	}
+
+	for _, comp := range comps {
+		liveComp := &v1alpha2.Component{}
+		err := d.c.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: comp.Name}, liveComp)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "get Component %s", comp.Name)
+		}
+		var liveObj interface{}
+		if err == nil {
+			liveObj = liveComp
+		}
+		compDiff, err := diffResource(liveObj, comp)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, newResourceDiff(v1alpha2.ComponentGroupVersionKind, comp.Name, comp.Name, compDiff))
+
+		wl, ok := comp.Spec.Workload.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		wlDiff, err := d.diffRendered(ctx, app, comp.Name, wl, map[string]string{
+			oam.LabelAppName:         app.Name,
+			oam.LabelAppComponent:    comp.Name,
+			oam.LabelOAMResourceType: oam.ResourceTypeWorkload,
+		}, liveRefs)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, wlDiff)
+	}
+
+	for _, acComp := range ac.Spec.Components {
+		for _, ct := range acComp.Traits {
+			tr, ok := ct.Trait.Object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			selector := map[string]string{
+				oam.LabelAppName:         app.Name,
+				oam.LabelAppComponent:    acComp.ComponentName,
+				oam.LabelOAMResourceType: oam.ResourceTypeTrait,
+			}
+			for _, key := range []string{oam.TraitTypeLabel, oam.TraitResource} {
+				if v, exist := tr.GetLabels()[key]; exist {
+					selector[key] = v
+				}
+			}
+			trDiff, err := d.diffRendered(ctx, app, acComp.ComponentName, tr, selector, liveRefs)
+			if err != nil {
+				return nil, err
+			}
+			diffs = append(diffs, trDiff)
+		}
+	}
+
+	var removed []ResourceDiff
+	for ref, compName := range liveRefs {
+		removed = append(removed, ResourceDiff{
+			APIVersion: ref.apiVersion,
+			Kind:       ref.kind,
+			Name:       ref.name,
+			Component:  compName,
+			Type:       DiffRemoved,
+		})
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].String() < removed[j].String()
+	})
+	diffs = append(diffs, removed...)
+
+	rendered := make(map[string]bool, len(comps))
+	for _, comp := range comps {
+		rendered[comp.Name] = true
+	}
+	liveComps := &v1alpha2.ComponentList{}
+	if err := d.c.List(ctx, liveComps, client.InNamespace(app.Namespace),
+		client.MatchingLabels{appfile.OAMApplicationLabel: app.Name}); err != nil {
+		return nil, errors.Wrap(err, "list Components of the application")
+	}
+	for _, comp := range liveComps.Items {
+		if rendered[comp.Name] {
+			continue
+		}
+		diffs = append(diffs, ResourceDiff{
+			APIVersion: v1alpha2.ComponentGroupVersionKind.GroupVersion().String(),
+			Kind:       v1alpha2.ComponentKind,
+			Name:       comp.Name,
+			Component:  comp.Name,
+			Type:       DiffRemoved,
+		})
+	}
+	return diffs, nil
+}
+
+// diffRendered compares a rendered workload or trait with the live one, which is found by name if the rendered
+// resource is named, otherwise by the labels the ApplicationConfiguration controller puts on it.
+func (d *LiveDiffer) diffRendered(ctx context.Context, app *v1alpha2.Application, compName string, obj *unstructured.Unstructured,
+	selector map[string]string, liveRefs map[liveRef]string) (ResourceDiff, error) {
+	gvk := obj.GroupVersionKind()
+	var live *unstructured.Unstructured
+	if obj.GetName() != "" {
+		live = &unstructured.Unstructured{}
+		live.SetGroupVersionKind(gvk)
+		err := d.c.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: obj.GetName()}, live)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return ResourceDiff{}, errors.Wrapf(err, "get %s %s of component %s", gvk.Kind, obj.GetName(), compName)
+		}
+		if err != nil {
+			live = nil
+		}
+	} else {
+		list, err := util.GetObjectsGivenGVKAndLabels(ctx, d.c, gvk, app.Namespace, selector)
+		if err != nil {
+			return ResourceDiff{}, errors.WithMessagef(err, "get %s of component %s", gvk.Kind, compName)
+		}
+		if len(list.Items) > 0 {
+			live = &list.Items[0]
+		}
+	}
+
+	name := obj.GetName()
+	if live != nil {
+		name = live.GetName()
+		delete(liveRefs, liveRef{apiVersion: live.GetAPIVersion(), kind: live.GetKind(), name: live.GetName()})
+		// the rendered resource is named by the controller, compare it with the name of the live one
+		if obj.GetName() == "" {
+			obj = obj.DeepCopy()
+			obj.SetName(name)
+		}
+	}
+	var liveObj interface{}
+	if live != nil {
+		liveObj = live
+	}
+	diff, err := diffResource(liveObj, obj)
+	if err != nil {
+		return ResourceDiff{}, err
+	}
+	return newResourceDiff(gvk, name, compName, diff), nil
+}
+
+// liveRef identifies a resource referenced by the status of the live ApplicationConfiguration
+type liveRef struct {
+	apiVersion string
+	kind       string
+	name       string
+}
+
+func newLiveRef(ref runtimev1alpha1.TypedReference) liveRef {
+	return liveRef{apiVersion: ref.APIVersion, kind: ref.Kind, name: ref.Name}
+}
+
+type resourceChange struct {
+	typ  DiffType
+	diff string
+}
+
+func newResourceDiff(gvk schema.GroupVersionKind, name, compName string, change resourceChange) ResourceDiff {
+	return ResourceDiff{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Name:       name,
+		Component:  compName,
+		Type:       change.typ,
+		Diff:       change.diff,
+	}
+}
+
+// diffResource compares the live resource with the rendered one, live is nil if it doesn't exist.
+// Only the fields set by the rendered resource are compared, so that the fields filled by the api server
+// and controllers, like status and resourceVersion, are not reported as changes.
+func diffResource(live, rendered interface{}) (resourceChange, error) {
+	renderedMap, err := util.Object2Map(rendered)
+	if err != nil {
+		return resourceChange{}, errors.Wrap(err, "convert rendered resource")
+	}
+	if live == nil {
+		return resourceChange{typ: DiffAdded}, nil
+	}
+	liveMap, err := util.Object2Map(live)
+	if err != nil {
+		return resourceChange{}, errors.Wrap(err, "convert live resource")
+	}
+	// drop the empty fields of the typed objects, e.g. `creationTimestamp: null` and `status: {}`
+	renderedMap, _ = pruneEmpty(renderedMap).(map[string]interface{})
+	liveMap, _ = prune(liveMap, renderedMap).(map[string]interface{})
+	diff := cmp.Diff(liveMap, renderedMap)
+	if diff == "" {
+		return resourceChange{typ: DiffUnchanged}, nil
+	}
+	return resourceChange{typ: DiffModified, diff: diff}, nil
+}
+
+// prune drops the fields of live which are not set in rendered, lists are compared as a whole
+func prune(live, rendered interface{}) interface{} {
+	liveMap, ok := live.(map[string]interface{})
+	if !ok {
+		return live
+	}
+	renderedMap, ok := rendered.(map[string]interface{})
+	if !ok {
+		return live
+	}
+	out := make(map[string]interface{}, len(renderedMap))
+	for k, v := range renderedMap {
+		if lv, exist := liveMap[k]; exist {
+			out[k] = prune(lv, v)
+		}
+	}
+	return out
+}
+
+func pruneEmpty(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, sub := range val {
+			sub = pruneEmpty(sub)
+			if sub == nil {
+				continue
+			}
+			if m, ok := sub.(map[string]interface{}); ok && len(m) == 0 {
+				continue
+			}
+			out[k] = sub
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, sub := range val {
+			out[i] = pruneEmpty(sub)
+		}
+		return out
+	}
+	return v
+}
+
+// String returns the summary of the change
+func (r ResourceDiff) String() string {
+	var mark string
+	switch r.Type {
+	case DiffAdded:
+		mark = "+"
+	case DiffModified:
+		mark = "~"
+	case DiffRemoved:
+		mark = "-"
+	default:
+		mark = "="
+	}
+	return fmt.Sprintf("%s %s/%s (%s)", mark, r.Kind, r.Name, r.Type)
+}
@@ -0,0 +1,106 @@
+package dryrun
+
+import (
+	"context"
+	"testing"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+
+	//lint:ignore SA1019 We will use pkg/envtest before upgrading controller-runtime to v1.0.0
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	core "github.com/oam-dev/kubevela/apis/core.oam.dev"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+func TestDiffResource(t *testing.T) {
+	rendered := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web"},
+		"spec":     map[string]interface{}{"replicas": 2},
+	}
+	change, err := diffResource(nil, rendered)
+	assert.NoError(t, err)
+	assert.Equal(t, DiffAdded, change.typ)
+
+	live := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web", "resourceVersion": "10"},
+		"spec":     map[string]interface{}{"replicas": 2, "paused": false},
+		"status":   map[string]interface{}{"readyReplicas": 2},
+	}
+	change, err = diffResource(live, rendered)
+	assert.NoError(t, err)
+	assert.Equal(t, DiffUnchanged, change.typ)
+
+	live["spec"] = map[string]interface{}{"replicas": 1}
+	change, err = diffResource(live, rendered)
+	assert.NoError(t, err)
+	assert.Equal(t, DiffModified, change.typ)
+	assert.Contains(t, change.diff, "replicas")
+}
+
+func TestLiveDiffer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = core.AddToScheme(scheme)
+
+	app := &v1alpha2.Application{ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: "default"}}
+	liveAC := &v1alpha2.ApplicationConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: "default"},
+		Status: v1alpha2.ApplicationConfigurationStatus{
+			Workloads: []v1alpha2.WorkloadStatus{{
+				ComponentName: "web",
+				Reference:     runtimev1alpha1.TypedReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web-v1"},
+			}, {
+				ComponentName: "worker",
+				Reference:     runtimev1alpha1.TypedReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "worker-v1"},
+			}},
+		},
+	}
+	liveWorker := &v1alpha2.Component{ObjectMeta: metav1.ObjectMeta{
+		Name: "worker", Namespace: "default", Labels: map[string]string{appfile.OAMApplicationLabel: "myapp"},
+	}}
+	liveDeploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-v1", Namespace: "default", Labels: map[string]string{
+			oam.LabelAppName:         "myapp",
+			oam.LabelAppComponent:    "web",
+			oam.LabelOAMResourceType: oam.ResourceTypeWorkload,
+		}},
+		Spec: appsv1.DeploymentSpec{Replicas: pointer.Int32Ptr(1)},
+	}
+	c := fake.NewFakeClientWithScheme(scheme, liveAC, liveWorker, liveDeploy)
+
+	ac := &v1alpha2.ApplicationConfiguration{ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: "default"}}
+	ac.Spec.Components = []v1alpha2.ApplicationConfigurationComponent{{ComponentName: "web"}}
+	comp := &v1alpha2.Component{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	comp.Spec.Workload.Object = &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{"labels": map[string]interface{}{
+			oam.LabelAppName:      "myapp",
+			oam.LabelAppComponent: "web",
+		}},
+		"spec": map[string]interface{}{"replicas": int64(2)},
+	}}
+
+	diffs, err := NewLiveDiffer(c).Diff(context.Background(), app, ac, []*v1alpha2.Component{comp})
+	assert.NoError(t, err)
+	types := map[string]DiffType{}
+	for _, d := range diffs {
+		types[d.Kind+"/"+d.Name] = d.Type
+	}
+	assert.Equal(t, map[string]DiffType{
+		"ApplicationConfiguration/myapp": DiffModified,
+		"Component/web":                  DiffAdded,
+		"Deployment/web-v1":              DiffModified,
+		"Deployment/worker-v1":           DiffRemoved,
+		"Component/worker":               DiffRemoved,
+	}, types)
+}
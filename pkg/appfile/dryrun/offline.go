@@ -0,0 +1,155 @@
+package dryrun
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	crdv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	//lint:ignore SA1019 We will use pkg/envtest before upgrading controller-runtime to v1.0.0
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	core "github.com/oam-dev/kubevela/apis/core.oam.dev"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// NewLocalDefinitionLoader reads WorkloadDefinitions, TraitDefinitions, ScopeDefinitions and CustomResourceDefinitions
+// from the YAML or JSON files in the directory, so that applications can be rendered without a cluster.
+// The GVKs of scopes are resolved from the CustomResourceDefinitions in the directory and the built-in OAM kinds.
+func NewLocalDefinitionLoader(dir string) (appfile.DefinitionLoader, error) {
+	scheme := runtime.NewScheme()
+	if err := core.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for kind := range scheme.KnownTypes(v1alpha2.SchemeGroupVersion) {
+		if strings.HasSuffix(kind, "List") {
+			continue
+		}
+		mapper.Add(v1alpha2.SchemeGroupVersion.WithKind(kind), meta.RESTScopeNamespace)
+	}
+
+	objs, err := readObjects(dir)
+	if err != nil {
+		return nil, err
+	}
+	var defs []runtime.Object
+	for _, obj := range objs {
+		var def runtime.Object
+		switch obj.GetKind() {
+		case v1alpha2.WorkloadDefinitionKind:
+			obj.SetNamespace(util.GenNamespacedDefinitionName(obj.GetName()).Namespace)
+			def = &v1alpha2.WorkloadDefinition{}
+		case v1alpha2.TraitDefinitionKind:
+			obj.SetNamespace(util.GenNamespacedDefinitionName(obj.GetName()).Namespace)
+			def = &v1alpha2.TraitDefinition{}
+		case v1alpha2.ScopeDefinitionKind:
+			// scope definitions are looked up without namespace
+			obj.SetNamespace("")
+			def = &v1alpha2.ScopeDefinition{}
+		case "CustomResourceDefinition":
+			crd := &crdv1.CustomResourceDefinition{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, crd); err != nil {
+				return nil, errors.Wrapf(err, "convert CustomResourceDefinition %s", obj.GetName())
+			}
+			addCRDToMapper(mapper, crd)
+			continue
+		default:
+			continue
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, def); err != nil {
+			return nil, errors.Wrapf(err, "convert %s %s", obj.GetKind(), obj.GetName())
+		}
+		defs = append(defs, def)
+	}
+	return appfile.NewDefinitionLoader(fake.NewFakeClientWithScheme(scheme, defs...), &staticMapper{mapper: mapper}), nil
+}
+
+// readObjects reads all the objects in the YAML or JSON files of the directory, a file can contain multiple documents
+func readObjects(dir string) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		f, err := os.Open(filepath.Clean(path))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		decoder := k8syaml.NewYAMLOrJSONDecoder(f, 4096)
+		for {
+			obj := &unstructured.Unstructured{}
+			if err := decoder.Decode(&obj.Object); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return errors.Wrapf(err, "decode file %s", path)
+			}
+			// skip empty documents
+			if len(obj.Object) == 0 {
+				continue
+			}
+			objs = append(objs, obj)
+		}
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "read definitions from %s", dir)
+	}
+	return objs, nil
+}
+
+func addCRDToMapper(mapper *meta.DefaultRESTMapper, crd *crdv1.CustomResourceDefinition) {
+	scope := meta.RESTScopeNamespace
+	if crd.Spec.Scope == crdv1.ClusterScoped {
+		scope = meta.RESTScopeRoot
+	}
+	for _, v := range crd.Spec.Versions {
+		gvk := schema.GroupVersionKind{Group: crd.Spec.Group, Version: v.Name, Kind: crd.Spec.Names.Kind}
+		plural := gvk.GroupVersion().WithResource(crd.Spec.Names.Plural)
+		singular := gvk.GroupVersion().WithResource(crd.Spec.Names.Singular)
+		mapper.AddSpecific(gvk, plural, singular, scope)
+	}
+}
+
+// staticMapper is a DiscoveryMapper which maps the resources known in advance
+type staticMapper struct {
+	mapper meta.RESTMapper
+}
+
+var _ discoverymapper.DiscoveryMapper = &staticMapper{}
+
+func (m *staticMapper) GetMapper() (meta.RESTMapper, error) {
+	return m.mapper, nil
+}
+
+func (m *staticMapper) Refresh() (meta.RESTMapper, error) {
+	return m.mapper, nil
+}
+
+func (m *staticMapper) RESTMapping(gk schema.GroupKind, version ...string) (*meta.RESTMapping, error) {
+	return m.mapper.RESTMapping(gk, version...)
+}
+
+func (m *staticMapper) KindsFor(input schema.GroupVersionResource) ([]schema.GroupVersionKind, error) {
+	return m.mapper.KindsFor(input)
+}
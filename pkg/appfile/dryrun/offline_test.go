@@ -0,0 +1,57 @@
+package dryrun
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+)
+
+func TestRenderWithLocalDefinitions(t *testing.T) {
+	loader, err := NewLocalDefinitionLoader("./testdata/definitions")
+	assert.NoError(t, err)
+
+	settings, _ := json.Marshal(map[string]interface{}{"image": "busybox", "cmd": []string{"sleep", "1000"}})
+	properties, _ := json.Marshal(map[string]interface{}{"replicas": 2})
+	app := &v1alpha2.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapp", Namespace: "default"},
+		Spec: v1alpha2.ApplicationSpec{
+			Components: []v1alpha2.ApplicationComponent{{
+				Name:         "myworker",
+				WorkloadType: "worker",
+				Settings:     runtime.RawExtension{Raw: settings},
+				Traits:       []v1alpha2.ApplicationTrait{{Name: "scaler", Properties: runtime.RawExtension{Raw: properties}}},
+				Scopes:       map[string]string{"healthscopes.core.oam.dev": "myscope"},
+			}},
+		},
+	}
+
+	parser := appfile.NewOfflineParser(loader)
+	af, err := parser.GenerateAppFile(app.Name, app)
+	assert.NoError(t, err)
+	ac, comps, err := parser.GenerateApplicationConfiguration(af, app.Namespace)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, len(comps))
+	wl, ok := comps[0].Spec.Workload.Object.(*unstructured.Unstructured)
+	assert.True(t, ok)
+	assert.Equal(t, "Deployment", wl.GetKind())
+
+	assert.Equal(t, 1, len(ac.Spec.Components))
+	assert.Equal(t, 1, len(ac.Spec.Components[0].Traits))
+	tr, ok := ac.Spec.Components[0].Traits[0].Trait.Object.(*unstructured.Unstructured)
+	assert.True(t, ok)
+	assert.Equal(t, "ManualScalerTrait", tr.GetKind())
+	assert.Equal(t, 1, len(ac.Spec.Components[0].Scopes))
+	assert.Equal(t, "HealthScope", ac.Spec.Components[0].Scopes[0].ScopeReference.Kind)
+
+	app.Spec.Components[0].WorkloadType = "webservice"
+	_, err = parser.GenerateAppFile(app.Name, app)
+	assert.Error(t, err, "definition not found in the local files")
+}
@@ -0,0 +1,37 @@
+package appfile
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// DefinitionLoader looks up the definitions referred by an application
+type DefinitionLoader interface {
+	// LoadTemplate loads the template of the workload or trait definition
+	LoadTemplate(name string, kind types.CapType) (*util.Template, error)
+	// GetScopeGVK gets the GVK of the scope defined by the scope definition
+	GetScopeGVK(name string) (schema.GroupVersionKind, error)
+}
+
+type definitionLoader struct {
+	cli client.Reader
+	dm  discoverymapper.DiscoveryMapper
+}
+
+// NewDefinitionLoader creates a DefinitionLoader which reads definitions by the client,
+// the client can be a cluster client or a fake one filled with local definitions.
+func NewDefinitionLoader(cli client.Reader, dm discoverymapper.DiscoveryMapper) DefinitionLoader {
+	return &definitionLoader{cli: cli, dm: dm}
+}
+
+func (l *definitionLoader) LoadTemplate(name string, kind types.CapType) (*util.Template, error) {
+	return util.LoadTemplate(l.cli, name, kind)
+}
+
+func (l *definitionLoader) GetScopeGVK(name string) (schema.GroupVersionKind, error) {
+	return util.GetScopeGVK(l.cli, l.dm, name)
+}
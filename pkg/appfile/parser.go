@@ -1,6 +1,8 @@
 package appfile
 
 import (
+	"sort"
+
 	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
 	"github.com/pkg/errors"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
@@ -34,8 +36,12 @@ type Workload struct {
 	Params             map[string]interface{}
 	Traits             []*Trait
 	Scopes             []Scope
+	DependsOn          []string
+	ReadyConditions    []v1alpha2.ConditionRequirement
 
-	Template           string
+	Template string
+	// TemplateType is the data format of Template, it's CUE if empty
+	TemplateType       string
 	HealthCheckPolicy  string
 	CustomStatusFormat string
 }
@@ -58,7 +64,9 @@ func (wl *Workload) GetUserConfigName() string {
 
 // EvalContext eval workload template and set result to context
 func (wl *Workload) EvalContext(ctx process.Context) error {
-	return definition.NewWorkloadAbstractEngine(wl.Name).Params(wl.Params).Complete(ctx, wl.Template)
+	err := definition.NewWorkloadAbstractEngine(wl.Name).Params(wl.Params).TemplateType(wl.TemplateType).Complete(ctx, wl.Template)
+	definition.SetRenderErrorSource(err, wl.Name, "", wl.Type)
+	return err
 }
 
 // EvalStatus eval workload status
@@ -106,8 +114,33 @@ func (trait *Trait) EvalHealth(ctx process.Context, client client.Client, namesp
 
 // Appfile describes application
 type Appfile struct {
-	Name      string
+	Name string
+	// RevisionName is the name of the ApplicationRevision this Appfile is rendered for
+	RevisionName string
+	// InReconcile is set if the Appfile is rendered in the reconcile of the controller,
+	// the processing tasks in templates fail with a retryable error rather than blocking the reconcile to retry
+	InReconcile bool
+	// Labels and Annotations are copied from the Application, they are exposed to templates by the context
+	Labels      map[string]string
+	Annotations map[string]string
+	// Env is the name of the environment which the Application is deployed to, it's recorded by oam.LabelAppEnv
+	Env       string
 	Workloads []*Workload
+	// Scopes are application-level scopes which will be applied to every workload
+	Scopes []Scope
+
+	// resolved are the copies of the workloads whose references to other components are resolved
+	// by the last rendering, keyed by the workload name
+	resolved map[string]*Workload
+}
+
+// ResolvedWorkload returns the workload with the references to other components resolved by the last rendering,
+// the workload itself is returned if it has no references
+func (af *Appfile) ResolvedWorkload(wl *Workload) *Workload {
+	if resolved, ok := af.resolved[wl.Name]; ok {
+		return resolved
+	}
+	return wl
 }
 
 // TemplateValidate validate Template format
@@ -117,15 +150,32 @@ func (af *Appfile) TemplateValidate() error {
 
 // Parser is an application parser
 type Parser struct {
+	// client is used to read user configs and live resources, it's nil when rendering offline
 	client client.Client
-	dm     discoverymapper.DiscoveryMapper
+	loader DefinitionLoader
 }
 
 // NewApplicationParser create appfile parser
 func NewApplicationParser(cli client.Client, dm discoverymapper.DiscoveryMapper) *Parser {
 	return &Parser{
 		client: cli,
-		dm:     dm,
+		loader: NewDefinitionLoader(cli, dm),
+	}
+}
+
+// NewApplicationParserWithLoader creates appfile parser which looks up definitions by the loader,
+// the client is still used to read user configs and live resources
+func NewApplicationParserWithLoader(cli client.Client, loader DefinitionLoader) *Parser {
+	return &Parser{
+		client: cli,
+		loader: loader,
+	}
+}
+
+// NewOfflineParser creates an appfile parser which looks up definitions by the loader without a cluster
+func NewOfflineParser(loader DefinitionLoader) *Parser {
+	return &Parser{
+		loader: loader,
 	}
 }
 
@@ -133,16 +183,28 @@ func NewApplicationParser(cli client.Client, dm discoverymapper.DiscoveryMapper)
 func (p *Parser) GenerateAppFile(name string, app *v1alpha2.Application) (*Appfile, error) {
 	appfile := new(Appfile)
 	appfile.Name = name
+	appfile.Labels = app.Labels
+	appfile.Annotations = app.Annotations
+	appfile.Env = app.Labels[oam.LabelAppEnv]
 	var wds []*Workload
 	for _, comp := range app.Spec.Components {
 		wd, err := p.parseWorkload(comp)
 		if err != nil {
 			return nil, err
 		}
+		if err := p.parsePolicies(wd, app.Spec.Policies); err != nil {
+			return nil, errors.WithMessage(err, "parse application-level policies")
+		}
 		wds = append(wds, wd)
 	}
 	appfile.Workloads = wds
 
+	scopes, err := p.parseScopes(app.Spec.Scopes)
+	if err != nil {
+		return nil, errors.WithMessage(err, "parse application-level scopes")
+	}
+	appfile.Scopes = scopes
+
 	return appfile, nil
 }
 
@@ -151,12 +213,13 @@ func (p *Parser) parseWorkload(comp v1alpha2.ApplicationComponent) (*Workload, e
 	workload.Traits = []*Trait{}
 	workload.Name = comp.Name
 	workload.Type = comp.WorkloadType
-	templ, err := util.LoadTemplate(p.client, workload.Type, types.TypeWorkload)
+	templ, err := p.loader.LoadTemplate(workload.Type, types.TypeWorkload)
 	if err != nil && !kerrors.IsNotFound(err) {
 		return nil, errors.WithMessagef(err, "fetch type of %s", comp.Name)
 	}
 	workload.CapabilityCategory = templ.CapabilityCategory
 	workload.Template = templ.TemplateStr
+	workload.TemplateType = templ.TemplateType
 	workload.HealthCheckPolicy = templ.Health
 	workload.CustomStatusFormat = templ.CustomStatus
 	settings, err := util.RawExtension2Map(&comp.Settings)
@@ -176,21 +239,66 @@ func (p *Parser) parseWorkload(comp v1alpha2.ApplicationComponent) (*Workload, e
 
 		workload.Traits = append(workload.Traits, trait)
 	}
-	for scopeType, instanceName := range comp.Scopes {
-		gvk, err := util.GetScopeGVK(p.client, p.dm, scopeType)
+	scopes, err := p.parseScopes(comp.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	workload.Scopes = scopes
+	workload.DependsOn = comp.DependsOn
+	workload.ReadyConditions = comp.ReadyConditions
+	return workload, nil
+}
+
+// parsePolicies attaches the application-level policies to the workload as traits,
+// a policy is skipped if the workload already has a trait with the same name.
+func (p *Parser) parsePolicies(wl *Workload, policies []v1alpha2.ApplicationTrait) error {
+	for _, policy := range policies {
+		var exist bool
+		for _, tr := range wl.Traits {
+			if tr.Name == policy.Name {
+				exist = true
+				break
+			}
+		}
+		if exist {
+			continue
+		}
+		properties, err := util.RawExtension2Map(&policy.Properties)
+		if err != nil {
+			return errors.Errorf("fail to parse properties of %s for %s", policy.Name, wl.Name)
+		}
+		trait, err := p.parseTrait(policy.Name, properties)
+		if err != nil {
+			return errors.WithMessagef(err, "component(%s) parse policy(%s)", wl.Name, policy.Name)
+		}
+		wl.Traits = append(wl.Traits, trait)
+	}
+	return nil
+}
+
+// parseScopes converts <scope-type:scope-instance-name> pairs to scopes, sorted by scope type to keep the order stable
+func (p *Parser) parseScopes(scopes map[string]string) ([]Scope, error) {
+	var scopeTypes []string
+	for scopeType := range scopes {
+		scopeTypes = append(scopeTypes, scopeType)
+	}
+	sort.Strings(scopeTypes)
+	var result []Scope
+	for _, scopeType := range scopeTypes {
+		gvk, err := p.loader.GetScopeGVK(scopeType)
 		if err != nil {
 			return nil, err
 		}
-		workload.Scopes = append(workload.Scopes, Scope{
-			Name: instanceName,
+		result = append(result, Scope{
+			Name: scopes[scopeType],
 			GVK:  gvk,
 		})
 	}
-	return workload, nil
+	return result, nil
 }
 
 func (p *Parser) parseTrait(name string, properties map[string]interface{}) (*Trait, error) {
-	templ, err := util.LoadTemplate(p.client, name, types.TypeTrait)
+	templ, err := p.loader.LoadTemplate(name, types.TypeTrait)
 	if kerrors.IsNotFound(err) {
 		return nil, errors.Errorf("trait definition of %s not found", name)
 	}
@@ -208,7 +316,9 @@ func (p *Parser) parseTrait(name string, properties map[string]interface{}) (*Tr
 	}, nil
 }
 
-// GenerateApplicationConfiguration converts an appFile to applicationConfig & Components
+// GenerateApplicationConfiguration converts an appFile to applicationConfig & Components.
+// The components referring to the live resources which aren't ready yet are left out together with the components
+// referring to or depending on them, a ReferencePendingError is returned along with the others in that case.
 func (p *Parser) GenerateApplicationConfiguration(app *Appfile, ns string) (*v1alpha2.ApplicationConfiguration,
 	[]*v1alpha2.Component, error) {
 	appconfig := &v1alpha2.ApplicationConfiguration{}
@@ -221,15 +331,48 @@ func (p *Parser) GenerateApplicationConfiguration(app *Appfile, ns string) (*v1a
 		appconfig.Labels = map[string]string{}
 	}
 	appconfig.Labels[OAMApplicationLabel] = app.Name
+	if app.RevisionName != "" {
+		appconfig.Annotations = map[string]string{oam.AnnotationAppRevision: app.RevisionName}
+	}
 
-	var components []*v1alpha2.Component
-	for _, wl := range app.Workloads {
-		pCtx, err := PrepareProcessContext(p.client, wl, app.Name, ns)
+	// components referenced by others must be rendered first
+	sorted, err := sortByReferences(app.Workloads)
+	if err != nil {
+		return nil, nil, err
+	}
+	rendered := make(map[string]*renderedComponent, len(sorted))
+	comps := make(map[string]*v1alpha2.Component, len(sorted))
+	acComps := make(map[string]*v1alpha2.ApplicationConfigurationComponent, len(sorted))
+	pending := map[string]bool{}
+	var pendingReason string
+	app.resolved = map[string]*Workload{}
+	for _, wl := range sorted {
+		if refersTo(wl, pending) {
+			pending[wl.Name] = true
+			continue
+		}
+		if len(componentReferences(wl)) > 0 {
+			resolved, err := p.resolveReferences(wl, rendered, app.Name, ns)
+			if IsReferencePending(err) {
+				pending[wl.Name] = true
+				if pendingReason == "" {
+					pendingReason = errors.Cause(err).(*ReferencePendingError).Reason
+				}
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			app.resolved[wl.Name] = resolved
+			wl = resolved
+		}
+		pCtx, err := PrepareProcessContext(p.client, wl, app, ns)
 		if err != nil {
 			return nil, nil, err
 		}
 		for _, tr := range wl.Traits {
 			if err := tr.EvalContext(pCtx); err != nil {
+				definition.SetRenderErrorSource(err, wl.Name, tr.Name, tr.Name)
 				return nil, nil, errors.Wrapf(err, "evaluate template trait=%s app=%s", tr.Name, wl.Name)
 			}
 		}
@@ -240,7 +383,7 @@ func (p *Parser) GenerateApplicationConfiguration(app *Appfile, ns string) (*v1a
 		comp.Name = wl.Name
 		acComp.ComponentName = comp.Name
 
-		for _, sc := range wl.Scopes {
+		for _, sc := range mergeScopes(wl.Scopes, app.Scopes) {
 			acComp.Scopes = append(acComp.Scopes, v1alpha2.ComponentScope{ScopeReference: v1alpha1.TypedReference{
 				APIVersion: sc.GVK.GroupVersion().String(),
 				Kind:       sc.GVK.Kind,
@@ -255,12 +398,114 @@ func (p *Parser) GenerateApplicationConfiguration(app *Appfile, ns string) (*v1a
 		comp.Labels[OAMApplicationLabel] = app.Name
 		comp.SetGroupVersionKind(v1alpha2.ComponentGroupVersionKind)
 
-		components = append(components, comp)
-		appconfig.Spec.Components = append(appconfig.Spec.Components, *acComp)
+		rendered[wl.Name] = newRenderedComponent(comp, acComp)
+		comps[wl.Name] = comp
+		acComps[wl.Name] = acComp
+	}
+	// the components depending on the pending ones by dependsOn can't be applied either
+	for changed := len(pending) > 0; changed; {
+		changed = false
+		for _, wl := range app.Workloads {
+			if !pending[wl.Name] && refersTo(wl, pending) {
+				pending[wl.Name] = true
+				changed = true
+			}
+		}
+	}
+
+	var components []*v1alpha2.Component
+	var workloads []*Workload
+	var pendingComps []string
+	for _, wl := range app.Workloads {
+		if pending[wl.Name] {
+			pendingComps = append(pendingComps, wl.Name)
+			continue
+		}
+		workloads = append(workloads, wl)
+		components = append(components, comps[wl.Name])
+		appconfig.Spec.Components = append(appconfig.Spec.Components, *acComps[wl.Name])
+	}
+	if err := setDependencies(workloads, appconfig.Spec.Components); err != nil {
+		return nil, nil, errors.WithMessagef(err, "app=%s", app.Name)
+	}
+	if len(pendingComps) > 0 {
+		return appconfig, components, &ReferencePendingError{Components: pendingComps, Reason: pendingReason}
 	}
 	return appconfig, components, nil
 }
 
+// refersTo checks if the workload refers to or depends on any of the components
+func refersTo(wl *Workload, comps map[string]bool) bool {
+	for _, ref := range componentReferences(wl) {
+		if comps[ref] {
+			return true
+		}
+	}
+	for _, dep := range wl.DependsOn {
+		if comps[dep] {
+			return true
+		}
+	}
+	return false
+}
+
+// setDependencies translates dependsOn of the workloads into DataOutputs and DataInputs of the ApplicationConfiguration,
+// so that a component and its traits won't be applied until the components it depends on are ready.
+func setDependencies(wls []*Workload, acComps []v1alpha2.ApplicationConfigurationComponent) error {
+	index := make(map[string]int, len(wls))
+	for i, wl := range wls {
+		index[wl.Name] = i
+	}
+	outputs := map[string]bool{}
+	for i, wl := range wls {
+		for _, dep := range wl.DependsOn {
+			j, ok := index[dep]
+			if !ok {
+				return errors.Errorf("component %s depends on %s which doesn't exist", wl.Name, dep)
+			}
+			outputName := dependencyOutputName(dep)
+			if !outputs[outputName] {
+				// the workload is considered ready once it's created if no ready conditions specified
+				acComps[j].DataOutputs = append(acComps[j].DataOutputs, v1alpha2.DataOutput{
+					Name:       outputName,
+					FieldPath:  "metadata.name",
+					Conditions: wls[j].ReadyConditions,
+				})
+				outputs[outputName] = true
+			}
+			input := v1alpha2.DataInput{ValueFrom: v1alpha2.DataInputValueFrom{DataOutputName: outputName}}
+			acComps[i].DataInputs = append(acComps[i].DataInputs, input)
+			for k := range acComps[i].Traits {
+				acComps[i].Traits[k].DataInputs = append(acComps[i].Traits[k].DataInputs, input)
+			}
+		}
+	}
+	return nil
+}
+
+func dependencyOutputName(compName string) string {
+	return compName + "-dependency"
+}
+
+// mergeScopes appends application-level scopes to the component-level scopes,
+// the component-level scope wins if both of them have the same scope kind.
+func mergeScopes(compScopes, appScopes []Scope) []Scope {
+	scopes := append([]Scope{}, compScopes...)
+	for _, appScope := range appScopes {
+		var exist bool
+		for _, compScope := range compScopes {
+			if compScope.GVK == appScope.GVK {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			scopes = append(scopes, appScope)
+		}
+	}
+	return scopes
+}
+
 // evalWorkloadWithContext evaluate the workload's template to generate component and ACComponent
 func evalWorkloadWithContext(pCtx process.Context, wl *Workload, appName, compName string) (*v1alpha2.Component, *v1alpha2.ApplicationConfigurationComponent, error) {
 	base, assists := pCtx.Output()
@@ -304,11 +549,33 @@ func evalWorkloadWithContext(pCtx process.Context, wl *Workload, appName, compNa
 	return component, acComponent, nil
 }
 
+// NewProcessContext creates a DSL process Context of the workload with the metadata of the application
+func NewProcessContext(wl *Workload, app *Appfile, namespace string) process.Context {
+	pCtx := process.NewContext(wl.Name, app.Name, app.RevisionName)
+	pCtx.SetNamespace(namespace)
+	pCtx.SetComponentType(wl.Type)
+	pCtx.SetAppLabels(app.Labels)
+	pCtx.SetAppAnnotations(app.Annotations)
+	env := app.Env
+	if env == "" {
+		// TODO(wonderflow): envName should not be namespace when we have serverside env
+		env = namespace
+	}
+	pCtx.SetEnv(env)
+	pCtx.SetInReconcile(app.InReconcile)
+	return pCtx
+}
+
 // PrepareProcessContext prepares a DSL process Context
-func PrepareProcessContext(k8sClient client.Client, wl *Workload, applicationName string, namespace string) (process.Context, error) {
-	pCtx := process.NewContext(wl.Name, applicationName)
+func PrepareProcessContext(k8sClient client.Client, wl *Workload, app *Appfile, namespace string) (process.Context, error) {
+	applicationName := app.Name
+	pCtx := NewProcessContext(wl, app, namespace)
+	pCtx.SetClient(k8sClient)
 	userConfig := wl.GetUserConfigName()
 	if userConfig != "" {
+		if k8sClient == nil {
+			return nil, errors.Errorf("config=%s of app=%s cannot be read without a cluster", userConfig, applicationName)
+		}
 		cg := config.Configmap{Client: k8sClient}
 		// TODO(wonderflow): envName should not be namespace when we have serverside env
 		var envName = namespace
@@ -212,38 +212,83 @@ spec:
             replicas: 10
 `
 
+const appfileWithPoliciesYaml = `
+apiVersion: core.oam.dev/v1alpha2
+kind: Application
+metadata:
+  name: application-sample
+spec:
+  components:
+    - name: myweb
+      type: worker
+      settings:
+        image: "busybox"
+      traits:
+        - name: scaler
+          properties:
+            replicas: 10
+    - name: myworker
+      type: worker
+      settings:
+        image: "busybox"
+  policies:
+    - name: scaler
+      properties:
+        replicas: 3
+`
+
 var _ = Describe("Test application parser", func() {
+	// Create mock client
+	tclient := test.MockClient{
+		MockGet: func(ctx context.Context, key types.NamespacedName, obj runtime.Object) error {
+			switch o := obj.(type) {
+			case *v1alpha2.WorkloadDefinition:
+				wd, err := util.UnMarshalStringToWorkloadDefinition(workloadDefinition)
+				if err != nil {
+					return err
+				}
+				*o = *wd
+			case *v1alpha2.TraitDefinition:
+				td, err := util.UnMarshalStringToTraitDefinition(traitDefinition)
+				if err != nil {
+					return err
+				}
+				*o = *td
+			}
+			return nil
+		},
+	}
+
 	It("Test we can parse an application to an appFile", func() {
 		o := v1alpha2.Application{}
 		err := yaml.Unmarshal([]byte(appfileYaml), &o)
 		Expect(err).ShouldNot(HaveOccurred())
 
-		// Create mock client
-		tclient := test.MockClient{
-			MockGet: func(ctx context.Context, key types.NamespacedName, obj runtime.Object) error {
-				switch o := obj.(type) {
-				case *v1alpha2.WorkloadDefinition:
-					wd, err := util.UnMarshalStringToWorkloadDefinition(workloadDefinition)
-					if err != nil {
-						return err
-					}
-					*o = *wd
-				case *v1alpha2.TraitDefinition:
-					td, err := util.UnMarshalStringToTraitDefinition(traitDefinition)
-					if err != nil {
-						return err
-					}
-					*o = *td
-				}
-				return nil
-			},
-		}
-
 		appfile, err := NewApplicationParser(&tclient, nil).GenerateAppFile("test", &o)
 		Expect(err).ShouldNot(HaveOccurred())
 
 		Expect(equal(expectedExceptApp, appfile)).Should(BeTrue())
 	})
+
+	It("Test application-level policies are attached to every component", func() {
+		o := v1alpha2.Application{}
+		err := yaml.Unmarshal([]byte(appfileWithPoliciesYaml), &o)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		appfile, err := NewApplicationParser(&tclient, nil).GenerateAppFile("test", &o)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(appfile.Workloads).Should(HaveLen(2))
+
+		By("the trait declared by the component takes precedence over the policy")
+		Expect(appfile.Workloads[0].Traits).Should(HaveLen(1))
+		Expect(appfile.Workloads[0].Traits[0].Name).Should(Equal("scaler"))
+		Expect(appfile.Workloads[0].Traits[0].Params).Should(Equal(map[string]interface{}{"replicas": float64(10)}))
+
+		By("the policy is attached to the component without the trait")
+		Expect(appfile.Workloads[1].Traits).Should(HaveLen(1))
+		Expect(appfile.Workloads[1].Traits[0].Name).Should(Equal("scaler"))
+		Expect(appfile.Workloads[1].Traits[0].Params).Should(Equal(map[string]interface{}{"replicas": float64(3)}))
+	})
 })
 
 func equal(af, dest *Appfile) bool {
@@ -487,3 +532,135 @@ var _ = Describe("Test appFile parser", func() {
 	})
 
 })
+
+var _ = Describe("Test merge application-level scopes", func() {
+	healthScope := schema.GroupVersionKind{Group: "core.oam.dev", Version: "v1alpha2", Kind: "HealthScope"}
+	networkScope := schema.GroupVersionKind{Group: "core.oam.dev", Version: "v1alpha2", Kind: "NetworkScope"}
+
+	It("application-level scopes are emitted into every component of the ApplicationConfiguration", func() {
+		template := `
+output: {
+	apiVersion: "v1"
+	kind:       "ConfigMap"
+	data: name: context.name
+}
+`
+		app := &Appfile{
+			Name: "test-scopes",
+			Workloads: []*Workload{
+				{Name: "myweb", Type: "worker", Template: template, Traits: []*Trait{},
+					Scopes: []Scope{{Name: "comp-health", GVK: healthScope}}},
+				{Name: "myworker", Type: "worker", Template: template, Traits: []*Trait{}},
+			},
+			Scopes: []Scope{{Name: "app-health", GVK: healthScope}, {Name: "app-network", GVK: networkScope}},
+		}
+		ac, _, err := NewApplicationParser(k8sClient, nil).GenerateApplicationConfiguration(app, "default")
+		Expect(err).Should(BeNil())
+		Expect(ac.Spec.Components).Should(HaveLen(2))
+		scopeRef := func(kind, name string) v1alpha2.ComponentScope {
+			return v1alpha2.ComponentScope{ScopeReference: v1alpha1.TypedReference{
+				APIVersion: "core.oam.dev/v1alpha2", Kind: kind, Name: name}}
+		}
+
+		By("the component-level scope overrides the application-level scope of the same kind")
+		Expect(ac.Spec.Components[0].ComponentName).Should(Equal("myweb"))
+		Expect(ac.Spec.Components[0].Scopes).Should(Equal([]v1alpha2.ComponentScope{
+			scopeRef("HealthScope", "comp-health"),
+			scopeRef("NetworkScope", "app-network"),
+		}))
+
+		By("the component without scopes gets all the application-level scopes")
+		Expect(ac.Spec.Components[1].ComponentName).Should(Equal("myworker"))
+		Expect(ac.Spec.Components[1].Scopes).Should(Equal([]v1alpha2.ComponentScope{
+			scopeRef("HealthScope", "app-health"),
+			scopeRef("NetworkScope", "app-network"),
+		}))
+	})
+
+	It("component-level scope takes precedence over application-level scope with the same kind", func() {
+		compScopes := []Scope{{Name: "comp-health", GVK: healthScope}}
+		appScopes := []Scope{{Name: "app-health", GVK: healthScope}, {Name: "app-network", GVK: networkScope}}
+		Expect(mergeScopes(compScopes, appScopes)).Should(Equal([]Scope{
+			{Name: "comp-health", GVK: healthScope},
+			{Name: "app-network", GVK: networkScope},
+		}))
+		Expect(compScopes).Should(HaveLen(1))
+	})
+
+	It("application-level scopes are applied to component without scopes", func() {
+		appScopes := []Scope{{Name: "app-health", GVK: healthScope}}
+		Expect(mergeScopes(nil, appScopes)).Should(Equal(appScopes))
+	})
+})
+
+var _ = Describe("Test set dependencies of components", func() {
+	It("dependsOn is translated into DataOutputs and DataInputs", func() {
+		readyCond := v1alpha2.ConditionRequirement{Operator: v1alpha2.ConditionNotEmpty, FieldPath: "status.readyReplicas"}
+		wls := []*Workload{
+			{Name: "mydb", ReadyConditions: []v1alpha2.ConditionRequirement{readyCond}},
+			{Name: "myweb", DependsOn: []string{"mydb"}},
+		}
+		acComps := []v1alpha2.ApplicationConfigurationComponent{
+			{ComponentName: "mydb"},
+			{ComponentName: "myweb", Traits: []v1alpha2.ComponentTrait{{}}},
+		}
+		Expect(setDependencies(wls, acComps)).Should(BeNil())
+		Expect(acComps[0].DataOutputs).Should(Equal([]v1alpha2.DataOutput{{
+			Name:       "mydb-dependency",
+			FieldPath:  "metadata.name",
+			Conditions: []v1alpha2.ConditionRequirement{readyCond},
+		}}))
+		expInput := []v1alpha2.DataInput{{ValueFrom: v1alpha2.DataInputValueFrom{DataOutputName: "mydb-dependency"}}}
+		Expect(acComps[1].DataInputs).Should(Equal(expInput))
+		Expect(acComps[1].Traits[0].DataInputs).Should(Equal(expInput))
+	})
+
+	It("depends on a component not existed", func() {
+		wls := []*Workload{{Name: "myweb", DependsOn: []string{"mydb"}}}
+		acComps := []v1alpha2.ApplicationConfigurationComponent{{ComponentName: "myweb"}}
+		Expect(setDependencies(wls, acComps)).ShouldNot(BeNil())
+	})
+})
+
+var _ = Describe("Test process context of workload", func() {
+	It("exposes the metadata of application to the template", func() {
+		wl := &Workload{
+			Name: "myweb",
+			Type: "worker",
+			Template: `
+output: {
+	apiVersion: "v1"
+	kind:       "ConfigMap"
+	metadata: {
+		name:      context.name
+		namespace: context.namespace
+		labels: {
+			"app.oam.dev/type": context.componentType
+			"app.oam.dev/env":  context.env
+			team:               context.appLabels.team
+		}
+		annotations: context.appAnnotations
+	}
+}
+`,
+		}
+		app := &Appfile{
+			Name:        "myapp",
+			Labels:      map[string]string{"team": "infra"},
+			Annotations: map[string]string{"owner": "alice"},
+		}
+		pCtx, err := PrepareProcessContext(nil, wl, app, "myns")
+		Expect(err).Should(BeNil())
+		base, _ := pCtx.Output()
+		obj, err := base.Unstructured()
+		Expect(err).Should(BeNil())
+		Expect(obj.GetNamespace()).Should(Equal("myns"))
+		// env is the namespace when the application isn't labeled with an env
+		Expect(obj.GetLabels()).Should(Equal(map[string]string{
+			"app.oam.dev/type": "worker",
+			"app.oam.dev/env":  "myns",
+			"team":             "infra",
+		}))
+		Expect(obj.GetAnnotations()).Should(Equal(map[string]string{"owner": "alice"}))
+	})
+})
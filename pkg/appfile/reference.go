@@ -0,0 +1,299 @@
+package appfile
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// componentRefPattern matches the references to other components in parameters,
+// e.g. $(components.db.output.metadata.name) or $(components.db.outputs.service.spec.clusterIP)
+var componentRefPattern = regexp.MustCompile(`\$\(components\.([^.()]+)\.([^()]+)\)`)
+
+// IsComponentReference checks if the whole value is a reference to another component,
+// the type of such a value is unknown until the reference is resolved when rendering
+func IsComponentReference(v interface{}) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	loc := componentRefPattern.FindStringIndex(s)
+	return loc != nil && loc[0] == 0 && loc[1] == len(s)
+}
+
+// ReferencePendingError is returned if a component refers to a field of the live resource of another component,
+// but the resource isn't created yet or the field isn't set yet, e.g. the status of a workload on the first deploy.
+// It's not a failure of rendering, the reference is resolved once the resource is ready.
+type ReferencePendingError struct {
+	// Components are the components which can't be rendered until the references are resolvable
+	Components []string
+	// Reason is why the reference isn't resolvable yet
+	Reason string
+}
+
+func (e *ReferencePendingError) Error() string {
+	return fmt.Sprintf("components %s are pending: %s", strings.Join(e.Components, ", "), e.Reason)
+}
+
+// IsReferencePending checks if err is caused by the references to the live resources which aren't resolvable yet
+func IsReferencePending(err error) bool {
+	_, ok := errors.Cause(err).(*ReferencePendingError)
+	return ok
+}
+
+// renderedComponent records the resources rendered from a component which can be referenced by other components
+type renderedComponent struct {
+	output  *unstructured.Unstructured
+	outputs map[string]*unstructured.Unstructured
+}
+
+func newRenderedComponent(comp *v1alpha2.Component, acComp *v1alpha2.ApplicationConfigurationComponent) *renderedComponent {
+	rc := &renderedComponent{outputs: map[string]*unstructured.Unstructured{}}
+	if wl, ok := comp.Spec.Workload.Object.(*unstructured.Unstructured); ok {
+		rc.output = wl
+	}
+	for _, tr := range acComp.Traits {
+		obj, ok := tr.Trait.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		if name := obj.GetLabels()[oam.TraitResource]; name != "" {
+			rc.outputs[name] = obj
+		}
+	}
+	return rc
+}
+
+// componentReferences returns the names of the components referenced by the parameters of the workload and its traits
+func componentReferences(wl *Workload) []string {
+	var refs []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case string:
+			for _, match := range componentRefPattern.FindAllStringSubmatch(val, -1) {
+				refs = append(refs, match[1])
+			}
+		case map[string]interface{}:
+			for _, sub := range val {
+				walk(sub)
+			}
+		case []interface{}:
+			for _, sub := range val {
+				walk(sub)
+			}
+		}
+	}
+	walk(wl.Params)
+	for _, tr := range wl.Traits {
+		walk(tr.Params)
+	}
+	return refs
+}
+
+// sortByReferences sorts the workloads so that the components referenced by others are rendered first
+func sortByReferences(wls []*Workload) ([]*Workload, error) {
+	index := make(map[string]*Workload, len(wls))
+	for _, wl := range wls {
+		index[wl.Name] = wl
+	}
+	var sorted []*Workload
+	visited := map[string]bool{}
+	visiting := map[string]bool{}
+	var visit func(wl *Workload) error
+	visit = func(wl *Workload) error {
+		if visited[wl.Name] {
+			return nil
+		}
+		if visiting[wl.Name] {
+			return errors.Errorf("cycle detected in the references of component %s", wl.Name)
+		}
+		visiting[wl.Name] = true
+		for _, ref := range componentReferences(wl) {
+			refWl, ok := index[ref]
+			if !ok {
+				return errors.Errorf("component %s references component %s which doesn't exist", wl.Name, ref)
+			}
+			if err := visit(refWl); err != nil {
+				return err
+			}
+		}
+		visiting[wl.Name] = false
+		visited[wl.Name] = true
+		sorted = append(sorted, wl)
+		return nil
+	}
+	for _, wl := range wls {
+		if err := visit(wl); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// resolveReferences returns a copy of the workload whose references to other components in the parameters of the
+// workload and its traits are resolved. The workload itself is left untouched, so the references are kept
+// and can be resolved again against the latest resources.
+func (p *Parser) resolveReferences(wl *Workload, rendered map[string]*renderedComponent, appName, ns string) (*Workload, error) {
+	resolved := *wl
+	params, err := p.resolveValue(wl.Params, rendered, appName, ns)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "resolve references in settings of component %s", wl.Name)
+	}
+	resolved.Params, _ = params.(map[string]interface{})
+	resolved.Traits = make([]*Trait, 0, len(wl.Traits))
+	for _, tr := range wl.Traits {
+		params, err := p.resolveValue(tr.Params, rendered, appName, ns)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "resolve references in properties of trait %s for component %s", tr.Name, wl.Name)
+		}
+		resolvedTrait := *tr
+		resolvedTrait.Params, _ = params.(map[string]interface{})
+		resolved.Traits = append(resolved.Traits, &resolvedTrait)
+	}
+	return &resolved, nil
+}
+
+func (p *Parser) resolveValue(v interface{}, rendered map[string]*renderedComponent, appName, ns string) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		matches := componentRefPattern.FindAllStringSubmatchIndex(val, -1)
+		if len(matches) == 0 {
+			return val, nil
+		}
+		// the whole string is a reference, keep the type of the referenced value
+		if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(val) {
+			return p.lookupReference(val[matches[0][2]:matches[0][3]], val[matches[0][4]:matches[0][5]], rendered, appName, ns)
+		}
+		var resolved strings.Builder
+		last := 0
+		for _, m := range matches {
+			refVal, err := p.lookupReference(val[m[2]:m[3]], val[m[4]:m[5]], rendered, appName, ns)
+			if err != nil {
+				return nil, err
+			}
+			resolved.WriteString(val[last:m[0]])
+			resolved.WriteString(fmt.Sprint(refVal))
+			last = m[1]
+		}
+		resolved.WriteString(val[last:])
+		return resolved.String(), nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, sub := range val {
+			resolvedSub, err := p.resolveValue(sub, rendered, appName, ns)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolvedSub
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, sub := range val {
+			resolvedSub, err := p.resolveValue(sub, rendered, appName, ns)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolvedSub
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// lookupReference gets the value of the referenced field from the rendered resource of the component,
+// if the field is not rendered (e.g. status), it will be read from the live resource in the cluster.
+func (p *Parser) lookupReference(compName, path string, rendered map[string]*renderedComponent, appName, ns string) (interface{}, error) {
+	rc, ok := rendered[compName]
+	if !ok {
+		return nil, errors.Errorf("component %s is not rendered", compName)
+	}
+	var obj *unstructured.Unstructured
+	var resource, fieldPath string
+	switch {
+	case strings.HasPrefix(path, definition.OutputFieldName+"."):
+		obj = rc.output
+		fieldPath = strings.TrimPrefix(path, definition.OutputFieldName+".")
+	case strings.HasPrefix(path, definition.OutputsFieldName+"."):
+		parts := strings.SplitN(strings.TrimPrefix(path, definition.OutputsFieldName+"."), ".", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid reference %s of component %s", path, compName)
+		}
+		resource, fieldPath = parts[0], parts[1]
+		obj = rc.outputs[resource]
+	default:
+		return nil, errors.Errorf("invalid reference %s of component %s, must start with %s or %s",
+			path, compName, definition.OutputFieldName, definition.OutputsFieldName)
+	}
+	if obj == nil {
+		return nil, errors.Errorf("resource of reference %s not found in component %s", path, compName)
+	}
+
+	val, err := fieldpath.Pave(obj.Object).GetValue(fieldPath)
+	if err == nil {
+		return val, nil
+	}
+	if !fieldpath.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "get %s from component %s", path, compName)
+	}
+	live, err := p.getLiveResource(obj, appName, compName, resource, ns)
+	if err != nil {
+		if IsReferencePending(err) {
+			return nil, err
+		}
+		return nil, errors.WithMessagef(err, "get live resource of reference %s in component %s", path, compName)
+	}
+	val, err = fieldpath.Pave(live.Object).GetValue(fieldPath)
+	if fieldpath.IsNotFound(err) {
+		return nil, &ReferencePendingError{Reason: fmt.Sprintf("%s of component %s is not set yet", path, compName)}
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "get %s from component %s", path, compName)
+	}
+	return val, nil
+}
+
+func (p *Parser) getLiveResource(obj *unstructured.Unstructured, appName, compName, resource, ns string) (*unstructured.Unstructured, error) {
+	if p.client == nil {
+		return nil, errors.New("live resources cannot be read without a cluster")
+	}
+	ctx := context.Background()
+	if obj.GetName() != "" {
+		live, err := util.GetObjectGivenGVKAndName(ctx, p.client, obj.GroupVersionKind(), ns, obj.GetName())
+		if apierrors.IsNotFound(errors.Cause(err)) {
+			return nil, &ReferencePendingError{Reason: fmt.Sprintf("%s %s of component %s is not created yet",
+				obj.GetKind(), obj.GetName(), compName)}
+		}
+		return live, err
+	}
+	labels := map[string]string{
+		oam.LabelAppName:      appName,
+		oam.LabelAppComponent: compName,
+	}
+	if resource == "" {
+		labels[oam.LabelOAMResourceType] = oam.ResourceTypeWorkload
+	} else {
+		labels[oam.TraitResource] = resource
+	}
+	list, err := util.GetObjectsGivenGVKAndLabels(ctx, p.client, obj.GroupVersionKind(), ns, labels)
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, &ReferencePendingError{Reason: fmt.Sprintf("%s of component %s is not created yet",
+			obj.GetKind(), compName)}
+	}
+	return &list.Items[0], nil
+}
@@ -0,0 +1,112 @@
+package appfile
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Test references among components", func() {
+	It("referenced components are sorted first", func() {
+		web := &Workload{Name: "web", Params: map[string]interface{}{
+			"env": []interface{}{map[string]interface{}{"value": "$(components.db.output.metadata.name)"}},
+		}}
+		db := &Workload{Name: "db", Params: map[string]interface{}{"image": "mysql"}}
+		sorted, err := sortByReferences([]*Workload{web, db})
+		Expect(err).Should(BeNil())
+		Expect(sorted).Should(Equal([]*Workload{db, web}))
+
+		By("reference to a component not existed")
+		_, err = sortByReferences([]*Workload{web})
+		Expect(err).ShouldNot(BeNil())
+
+		By("cycle in references")
+		db.Traits = []*Trait{{Name: "expose", Params: map[string]interface{}{"host": "$(components.web.output.metadata.name)"}}}
+		_, err = sortByReferences([]*Workload{web, db})
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("references are resolved from rendered resources", func() {
+		p := &Parser{}
+		rendered := map[string]*renderedComponent{
+			"db": {
+				output: &unstructured.Unstructured{Object: map[string]interface{}{
+					"metadata": map[string]interface{}{"name": "mydb"},
+					"spec":     map[string]interface{}{"port": int64(3306)},
+				}},
+				outputs: map[string]*unstructured.Unstructured{
+					"service": {Object: map[string]interface{}{
+						"metadata": map[string]interface{}{"name": "mydb-svc"},
+					}},
+				},
+			},
+		}
+		wl := &Workload{Name: "web", Params: map[string]interface{}{
+			"dbHost": "$(components.db.outputs.service.metadata.name)",
+			"dbPort": "$(components.db.output.spec.port)",
+			"dbURL":  "mysql://$(components.db.outputs.service.metadata.name):$(components.db.output.spec.port)",
+			"image":  "nginx",
+		}}
+		params := wl.Params
+		resolved, err := p.resolveReferences(wl, rendered, "myapp", "default")
+		Expect(err).Should(BeNil())
+		Expect(resolved.Params).Should(Equal(map[string]interface{}{
+			"dbHost": "mydb-svc",
+			"dbPort": int64(3306),
+			"dbURL":  "mysql://mydb-svc:3306",
+			"image":  "nginx",
+		}))
+
+		By("the references are kept in the workload to be resolved again")
+		Expect(wl.Params).Should(Equal(params))
+		Expect(wl.Params["dbPort"]).Should(Equal("$(components.db.output.spec.port)"))
+
+		By("reference to a resource not rendered")
+		wl.Params = map[string]interface{}{"dbHost": "$(components.db.outputs.ingress.metadata.name)"}
+		_, err = p.resolveReferences(wl, rendered, "myapp", "default")
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("references to the live resources not created yet are pending", func() {
+		var live *unstructured.Unstructured
+		p := &Parser{client: &test.MockClient{
+			MockGet: func(ctx context.Context, key types.NamespacedName, obj runtime.Object) error {
+				if live == nil {
+					return kerrors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "deployments"}, key.Name)
+				}
+				live.DeepCopyInto(obj.(*unstructured.Unstructured))
+				return nil
+			},
+		}}
+		rendered := map[string]*renderedComponent{
+			"db": {output: &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "mydb"},
+			}}},
+		}
+		wl := &Workload{Name: "web", Params: map[string]interface{}{
+			"dbReplicas": "$(components.db.output.status.readyReplicas)",
+		}}
+		_, err := p.resolveReferences(wl, rendered, "myapp", "default")
+		Expect(IsReferencePending(err)).Should(BeTrue())
+
+		By("the live resource is created but the field is not set yet")
+		live = rendered["db"].output.DeepCopy()
+		_, err = p.resolveReferences(wl, rendered, "myapp", "default")
+		Expect(IsReferencePending(err)).Should(BeTrue())
+
+		By("the field is set")
+		Expect(unstructured.SetNestedField(live.Object, int64(1), "status", "readyReplicas")).Should(BeNil())
+		resolved, err := p.resolveReferences(wl, rendered, "myapp", "default")
+		Expect(err).Should(BeNil())
+		Expect(resolved.Params["dbReplicas"]).Should(Equal(int64(1)))
+	})
+})
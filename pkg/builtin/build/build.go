@@ -16,6 +16,7 @@ func init() {
 	registry.RegisterTask("build", ImageBuildHandler)
 }
 
+// ImageBuildHandler builds the image and pushes it by the backend specified in `build.backend`
 func ImageBuildHandler(ctx registry.CallCtx, params interface{}) error {
 	pm, err := json.Marshal(params)
 	if err != nil {
@@ -33,27 +34,54 @@ func ImageBuildHandler(ctx registry.CallCtx, params interface{}) error {
 	if !ok {
 		return errors.New("image must be 'string'")
 	}
-	if err := b.buildImage(ctx.IO(), image); err != nil {
+	builder, err := NewBuilder(b)
+	if err != nil {
 		return err
 	}
-	if err := b.pushImage(ctx.IO(), image); err != nil {
+	if err := builder.Build(ctx.IO(), image); err != nil {
 		return err
 	}
-	return nil
+	return builder.Push(ctx.IO(), image)
 }
 
 // Build defines the build section of AppFile
 type Build struct {
-	Push   Push   `json:"push,omitempty"`
-	Docker Docker `json:"docker,omitempty"`
+	// Backend builds and pushes the image, it's one of docker, buildah, podman and oci, docker by default
+	Backend string `json:"backend,omitempty"`
+	Push    Push   `json:"push,omitempty"`
+	Docker  Docker `json:"docker,omitempty"`
+	OCI     OCI    `json:"oci,omitempty"`
 }
 
-// Docker defines the docker build section
+// Docker defines the docker build section, it's used by the backends which build the image by Dockerfile
 type Docker struct {
 	File    string `json:"file"`
 	Context string `json:"context"`
 }
 
+// OCI defines the build section of the oci backend, which assembles the image in process without a daemon
+// by adding the files in context as a layer onto the base image
+type OCI struct {
+	// Base is the base image, the image is built from scratch if it's empty or "scratch"
+	Base string `json:"base,omitempty"`
+	// Platform is the platform picked from the base image if it's multi-platform, linux/amd64 by default
+	Platform string `json:"platform,omitempty"`
+	// Context is the directory whose files are added into the image, e.g. the directory of a prebuilt binary
+	Context string `json:"context"`
+	// Dir is the directory in the image which the files are added into, "/" by default
+	Dir        string            `json:"dir,omitempty"`
+	Entrypoint []string          `json:"entrypoint,omitempty"`
+	Cmd        []string          `json:"cmd,omitempty"`
+	Env        []string          `json:"env,omitempty"`
+	WorkingDir string            `json:"workingDir,omitempty"`
+	User       string            `json:"user,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	// Layout is the directory which the OCI image layout is written into, a temporary directory is used if it's empty
+	Layout string `json:"layout,omitempty"`
+	// Insecure pulls and pushes the image by http, it's always true for the registries on localhost
+	Insecure bool `json:"insecure,omitempty"`
+}
+
 // Push defines where to push your image
 type Push struct {
 	Local    string `json:"local,omitempty"`
@@ -81,83 +109,28 @@ func asyncLog(reader io.Reader, stream cmdutil.IOStreams) {
 	}
 }
 
-// buildImage will build a image with name and context.
-func (b *Build) buildImage(io cmdutil.IOStreams, image string) error {
-	//nolint:gosec
-	// TODO(hongchaodeng): remove this dependency by using go lib
-	cmd := exec.Command("docker", "build", "-t", image, "-f", b.Docker.File, b.Docker.Context)
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		io.Errorf("BuildImage exec command error, message:%s\n", err.Error())
-		return err
-	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		io.Errorf("BuildImage exec command error, message:%s\n", err.Error())
-		return err
-	}
-	if err := cmd.Start(); err != nil {
-		io.Errorf("BuildImage exec command error, message:%s\n", err.Error())
-		return err
-	}
-	go asyncLog(stdout, io)
-	go asyncLog(stderr, io)
-	if err := cmd.Wait(); err != nil {
-		io.Errorf("BuildImage wait for command execution error:%s", err.Error())
-		return err
-	}
-	return nil
-}
-
-func (b *Build) pushImage(io cmdutil.IOStreams, image string) error {
-	io.Infof("pushing image (%s)...\n", image)
-	switch {
-	case b.Push.Local == "kind":
-		//nolint:gosec
-		cmd := exec.Command("kind", "load", "docker-image", image)
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
-			io.Errorf("pushImage(kind) exec command error, message:%s\n", err.Error())
-			return err
-		}
-		stderr, err := cmd.StderrPipe()
-		if err != nil {
-			io.Errorf("pushImage(kind) exec command error, message:%s\n", err.Error())
-			return err
-		}
-		if err := cmd.Start(); err != nil {
-			io.Errorf("pushImage(kind) exec command error, message:%s\n", err.Error())
-			return err
-		}
-		go asyncLog(stdout, io)
-		go asyncLog(stderr, io)
-		if err := cmd.Wait(); err != nil {
-			io.Errorf("pushImage(kind) wait for command execution error:%s", err.Error())
-			return err
-		}
-		return nil
-	default:
-	}
+// runCommand runs the command and logs its output
+func runCommand(io cmdutil.IOStreams, name string, args ...string) error {
 	//nolint:gosec
-	cmd := exec.Command("docker", "push", image)
+	cmd := exec.Command(name, args...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		io.Errorf("pushImage(docker push) exec command error, message:%s\n", err.Error())
+		io.Errorf("%s exec command error, message:%s\n", name, err.Error())
 		return err
 	}
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		io.Errorf("pushImage(docker push) exec command error, message:%s\n", err.Error())
+		io.Errorf("%s exec command error, message:%s\n", name, err.Error())
 		return err
 	}
 	if err := cmd.Start(); err != nil {
-		io.Errorf("pushImage(docker push) exec command error, message:%s\n", err.Error())
+		io.Errorf("%s exec command error, message:%s\n", name, err.Error())
 		return err
 	}
 	go asyncLog(stdout, io)
 	go asyncLog(stderr, io)
 	if err := cmd.Wait(); err != nil {
-		io.Errorf("pushImage(docker push) wait for command execution error:%s", err.Error())
+		io.Errorf("%s wait for command execution error:%s", name, err.Error())
 		return err
 	}
 	return nil
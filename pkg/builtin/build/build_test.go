@@ -1,6 +1,7 @@
 package build
 
 import (
+	"io/ioutil"
 	"testing"
 
 	"github.com/bmizerany/assert"
@@ -70,3 +71,29 @@ func TestBuild(t *testing.T) {
 	}
 
 }
+
+func TestNewBuilder(t *testing.T) {
+	for backend, expected := range map[string]string{
+		"":             "docker",
+		BackendDocker:  "docker",
+		BackendBuildah: "buildah",
+		BackendPodman:  "podman",
+	} {
+		builder, err := NewBuilder(&Build{Backend: backend})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, expected, builder.(*commandBuilder).command)
+	}
+	builder, err := NewBuilder(&Build{Backend: BackendOCI})
+	assert.Equal(t, nil, err)
+	_, ok := builder.(*ociBuilder)
+	assert.Equal(t, true, ok)
+
+	_, err = NewBuilder(&Build{Backend: "kaniko"})
+	assert.Equal(t, "unsupported build backend kaniko, it must be one of docker, buildah, podman and oci", err.Error())
+
+	_, err = registry.Run(map[string]interface{}{
+		"image": "test.io/app:v1",
+		"build": map[string]interface{}{"backend": "oci"},
+	}, cmdutil.IOStreams{Out: ioutil.Discard})
+	assert.Equal(t, "do task build: context is required by oci backend", err.Error())
+}
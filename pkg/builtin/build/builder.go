@@ -0,0 +1,110 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	cmdutil "github.com/oam-dev/kubevela/pkg/commands/util"
+)
+
+const (
+	// BackendDocker builds the image by `docker build` and pushes it by `docker push`
+	BackendDocker = "docker"
+	// BackendBuildah builds the image by `buildah bud` and pushes it by `buildah push`, no daemon is required
+	BackendBuildah = "buildah"
+	// BackendPodman builds the image by `podman build` and pushes it by `podman push`, no daemon is required
+	BackendPodman = "podman"
+	// BackendOCI assembles the image in process and pushes it to the registry directly, no daemon or tool is required
+	BackendOCI = "oci"
+
+	// LocalKind pushes the image into the local kind cluster
+	LocalKind = "kind"
+)
+
+// Builder builds the image and pushes it to the registry or the local cluster
+type Builder interface {
+	Build(io cmdutil.IOStreams, image string) error
+	Push(io cmdutil.IOStreams, image string) error
+}
+
+var backends = map[string]func(b *Build) Builder{
+	BackendDocker:  newDockerBuilder,
+	BackendBuildah: newBuildahBuilder,
+	BackendPodman:  newPodmanBuilder,
+	BackendOCI:     newOCIBuilder,
+}
+
+// NewBuilder creates the builder of the backend in build section, docker is used if the backend is not specified
+func NewBuilder(b *Build) (Builder, error) {
+	backend := b.Backend
+	if backend == "" {
+		backend = BackendDocker
+	}
+	newBuilder, ok := backends[backend]
+	if !ok {
+		return nil, errors.Errorf("unsupported build backend %s, it must be one of %s, %s, %s and %s",
+			backend, BackendDocker, BackendBuildah, BackendPodman, BackendOCI)
+	}
+	return newBuilder(b), nil
+}
+
+// commandBuilder builds and pushes the image by a container tool which builds the image by Dockerfile
+type commandBuilder struct {
+	build *Build
+	// command is the name of the tool
+	command string
+	// buildArgs are the args of the tool to build an image, they are followed by `-t <image> -f <file> <context>`
+	buildArgs []string
+	// saveArchive saves the image into a docker archive at the path, it's used to load the image into kind
+	saveArchive func(io cmdutil.IOStreams, image, path string) error
+}
+
+func newDockerBuilder(b *Build) Builder {
+	return &commandBuilder{build: b, command: "docker", buildArgs: []string{"build"}}
+}
+
+func newBuildahBuilder(b *Build) Builder {
+	return &commandBuilder{build: b, command: "buildah", buildArgs: []string{"bud"},
+		saveArchive: func(io cmdutil.IOStreams, image, path string) error {
+			return runCommand(io, "buildah", "push", image, "docker-archive:"+path+":"+image)
+		}}
+}
+
+func newPodmanBuilder(b *Build) Builder {
+	return &commandBuilder{build: b, command: "podman", buildArgs: []string{"build"},
+		saveArchive: func(io cmdutil.IOStreams, image, path string) error {
+			return runCommand(io, "podman", "save", "-o", path, image)
+		}}
+}
+
+// Build builds the image with name and context
+func (c *commandBuilder) Build(io cmdutil.IOStreams, image string) error {
+	args := append(append([]string{}, c.buildArgs...), "-t", image, "-f", c.build.Docker.File, c.build.Docker.Context)
+	return runCommand(io, c.command, args...)
+}
+
+// Push pushes the image to registry, or loads it into kind if `push.local` is kind
+func (c *commandBuilder) Push(io cmdutil.IOStreams, image string) error {
+	io.Infof("pushing image (%s)...\n", image)
+	if c.build.Push.Local != LocalKind {
+		return runCommand(io, c.command, "push", image)
+	}
+	if c.saveArchive == nil {
+		return runCommand(io, "kind", "load", "docker-image", image)
+	}
+	// kind can only load the images in docker daemon, so others are loaded by archive
+	dir, err := ioutil.TempDir("", "vela-build")
+	if err != nil {
+		return err
+	}
+	//nolint:errcheck
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "image.tar")
+	if err := c.saveArchive(io, image, archive); err != nil {
+		return err
+	}
+	return runCommand(io, "kind", "load", "image-archive", archive)
+}
@@ -0,0 +1,465 @@
+package build
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/content/local"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/platforms"
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+
+	cmdutil "github.com/oam-dev/kubevela/pkg/commands/util"
+)
+
+const (
+	// defaultPlatform is the platform of the image picked from a multi-platform base image
+	defaultPlatform = "linux/amd64"
+	// scratch is the name of the empty base image
+	scratch = "scratch"
+)
+
+// ociBuilder assembles the image into an OCI image layout by adding the files in context as a layer onto the base image,
+// the layout is also the content store which the base image is pulled into and the image is pushed from
+type ociBuilder struct {
+	build *Build
+	// layout is the directory of the OCI image layout built
+	layout string
+	// temporary marks the layout is created in a temporary directory which is removed after pushed
+	temporary bool
+}
+
+func newOCIBuilder(b *Build) Builder {
+	return &ociBuilder{build: b}
+}
+
+// Build builds the image into the OCI image layout, the temporary layout is removed if it fails
+func (o *ociBuilder) Build(ioStreams cmdutil.IOStreams, image string) (err error) {
+	spec := o.build.OCI
+	if spec.Context == "" {
+		return errors.New("context is required by oci backend")
+	}
+	ref, err := parseReference(image)
+	if err != nil {
+		return err
+	}
+	if o.layout = spec.Layout; o.layout == "" {
+		if o.layout, err = ioutil.TempDir("", "vela-oci"); err != nil {
+			return err
+		}
+		o.temporary = true
+		defer func() {
+			if err != nil {
+				//nolint:errcheck
+				os.RemoveAll(o.layout)
+				o.layout, o.temporary = "", false
+			}
+		}()
+	}
+	if err := os.MkdirAll(filepath.Join(o.layout, "blobs", "sha256"), 0750); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(o.layout, ocispec.ImageLayoutFile), []byte(`{"imageLayoutVersion":"`+ocispec.ImageLayoutVersion+`"}`), 0600); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	config, layers, err := o.pullBase(ctx, ioStreams)
+	if err != nil {
+		return err
+	}
+	ioStreams.Infof("adding %s into %s of image (%s)...\n", spec.Context, o.imageDir(), image)
+	layer, diffID, err := o.writeLayer()
+	if err != nil {
+		return errors.WithMessagef(err, "add %s into image", spec.Context)
+	}
+	layers = append(layers, layer)
+	o.configure(config, diffID)
+
+	configDesc, err := o.writeJSONBlob(ocispec.MediaTypeImageConfig, config)
+	if err != nil {
+		return err
+	}
+	manifestDesc, err := o.writeJSONBlob(ocispec.MediaTypeImageManifest, &ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Config:    configDesc,
+		Layers:    layers,
+	})
+	if err != nil {
+		return err
+	}
+	manifestDesc.Annotations = map[string]string{ocispec.AnnotationRefName: tagOrDigest(ref), images.AnnotationImageName: ref.String()}
+	idx, err := json.Marshal(&ocispec.Index{Versioned: specs.Versioned{SchemaVersion: 2}, Manifests: []ocispec.Descriptor{manifestDesc}})
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(o.layout, "index.json"), idx, 0600); err != nil {
+		return err
+	}
+	ioStreams.Infof("image (%s) is built into OCI image layout %s\n", image, o.layout)
+	return nil
+}
+
+// Push pushes the image in OCI image layout to registry, or loads it into kind if `push.local` is kind
+func (o *ociBuilder) Push(ioStreams cmdutil.IOStreams, image string) error {
+	if o.layout == "" {
+		return errors.New("image is not built")
+	}
+	if o.temporary {
+		//nolint:errcheck
+		defer os.RemoveAll(o.layout)
+	}
+	ioStreams.Infof("pushing image (%s)...\n", image)
+	if o.build.Push.Local == LocalKind {
+		archive := o.layout + ".tar"
+		if err := tarDir(o.layout, archive); err != nil {
+			return errors.WithMessage(err, "archive OCI image layout")
+		}
+		//nolint:errcheck
+		defer os.Remove(archive)
+		return runCommand(ioStreams, "kind", "load", "image-archive", archive)
+	}
+
+	ref, err := parseReference(image)
+	if err != nil {
+		return err
+	}
+	idx := &ocispec.Index{}
+	if err := readJSON(filepath.Join(o.layout, "index.json"), idx); err != nil {
+		return err
+	}
+	if len(idx.Manifests) == 0 {
+		return errors.Errorf("no image in OCI image layout %s", o.layout)
+	}
+	store, err := o.store()
+	if err != nil {
+		return err
+	}
+	//nolint:errcheck
+	defer os.RemoveAll(filepath.Join(o.layout, "ingest"))
+	ctx := context.Background()
+	pusher, err := newResolver(o.build.OCI.Insecure).Pusher(ctx, ref.String())
+	if err != nil {
+		return errors.Wrapf(err, "push image %s", image)
+	}
+	return errors.Wrapf(remotes.PushContent(ctx, pusher, idx.Manifests[0], store, nil, nil), "push image %s", image)
+}
+
+// pullBase pulls the manifest of the platform, and the config and layers of the base image into the layout
+func (o *ociBuilder) pullBase(ctx context.Context, ioStreams cmdutil.IOStreams) (*ocispec.Image, []ocispec.Descriptor, error) {
+	spec := o.build.OCI
+	p := spec.Platform
+	if p == "" {
+		p = defaultPlatform
+	}
+	want, err := platforms.Parse(p)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid platform %s", p)
+	}
+	if spec.Base == "" || spec.Base == scratch {
+		return &ocispec.Image{OS: want.OS, Architecture: want.Architecture, RootFS: ocispec.RootFS{Type: "layers"}}, nil, nil
+	}
+
+	ioStreams.Infof("pulling base image (%s)...\n", spec.Base)
+	ref, err := parseReference(spec.Base)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := o.store()
+	if err != nil {
+		return nil, nil, err
+	}
+	//nolint:errcheck
+	defer os.RemoveAll(filepath.Join(o.layout, "ingest"))
+	resolver := newResolver(spec.Insecure)
+	name, desc, err := resolver.Resolve(ctx, ref.String())
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "resolve base image %s", spec.Base)
+	}
+	fetcher, err := resolver.Fetcher(ctx, name)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "pull base image %s", spec.Base)
+	}
+	// only the manifest of the platform is pulled if the base image is multi-platform
+	matcher := platforms.Only(want)
+	children := images.LimitManifests(images.FilterPlatforms(images.ChildrenHandler(store), matcher), matcher, 1)
+	if err := images.Dispatch(ctx, images.Handlers(remotes.FetchHandler(store, fetcher), children), nil, desc); err != nil {
+		return nil, nil, errors.Wrapf(err, "pull base image %s", spec.Base)
+	}
+	m, err := images.Manifest(ctx, store, desc, matcher)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "no image of platform %s in base image %s", p, spec.Base)
+	}
+	data, err := content.ReadBlob(ctx, store, m.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	config := &ocispec.Image{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid config of base image %s", spec.Base)
+	}
+	layers := make([]ocispec.Descriptor, 0, len(m.Layers)+1)
+	for _, layer := range m.Layers {
+		if layer.MediaType == images.MediaTypeDockerSchema2LayerGzip {
+			layer.MediaType = ocispec.MediaTypeImageLayerGzip
+		}
+		layers = append(layers, ocispec.Descriptor{MediaType: layer.MediaType, Digest: layer.Digest, Size: layer.Size})
+	}
+	return config, layers, nil
+}
+
+// store opens the layout as a content store, the blobs are written into the ingest directory of the layout until
+// they are complete
+func (o *ociBuilder) store() (content.Store, error) {
+	store, err := local.NewStore(o.layout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open OCI image layout %s", o.layout)
+	}
+	return store, nil
+}
+
+// configure sets the config of image by the build section and appends the layer added
+func (o *ociBuilder) configure(config *ocispec.Image, diffID digest.Digest) {
+	spec := o.build.OCI
+	now := time.Now().UTC().Truncate(time.Second)
+	config.Created = &now
+	config.RootFS.DiffIDs = append(config.RootFS.DiffIDs, diffID)
+	config.History = append(config.History, ocispec.History{
+		Created:   &now,
+		CreatedBy: fmt.Sprintf("vela build: add %s into %s", filepath.Base(spec.Context), o.imageDir()),
+	})
+	c := &config.Config
+	for _, env := range spec.Env {
+		c.Env = mergeEnv(c.Env, env)
+	}
+	if len(spec.Entrypoint) > 0 {
+		// the cmd of base image is reset with entrypoint in the same way as Dockerfile
+		c.Entrypoint = spec.Entrypoint
+		c.Cmd = nil
+	}
+	if len(spec.Cmd) > 0 {
+		c.Cmd = spec.Cmd
+	}
+	if spec.WorkingDir != "" {
+		c.WorkingDir = spec.WorkingDir
+	}
+	if spec.User != "" {
+		c.User = spec.User
+	}
+	for k, v := range spec.Labels {
+		if c.Labels == nil {
+			c.Labels = map[string]string{}
+		}
+		c.Labels[k] = v
+	}
+}
+
+// mergeEnv sets the env in format `key=value`, the env with the same key is replaced
+func mergeEnv(envs []string, env string) []string {
+	key := strings.SplitN(env, "=", 2)[0]
+	for i, e := range envs {
+		if strings.SplitN(e, "=", 2)[0] == key {
+			envs[i] = env
+			return envs
+		}
+	}
+	return append(envs, env)
+}
+
+// imageDir returns the directory in the image which the files are added into
+func (o *ociBuilder) imageDir() string {
+	if o.build.OCI.Dir == "" {
+		return "/"
+	}
+	return path.Clean("/" + o.build.OCI.Dir)
+}
+
+// writeLayer writes the files in context as a gzip compressed tar layer, the diff id of the layer is returned along with it
+func (o *ociBuilder) writeLayer() (ocispec.Descriptor, digest.Digest, error) {
+	f, err := ioutil.TempFile(filepath.Join(o.layout, "blobs", "sha256"), "layer")
+	if err != nil {
+		return ocispec.Descriptor{}, "", err
+	}
+	//nolint:errcheck
+	defer os.Remove(f.Name())
+	compressed := digest.Canonical.Digester()
+	counter := &countWriter{}
+	gw := gzip.NewWriter(io.MultiWriter(f, compressed.Hash(), counter))
+	uncompressed := digest.Canonical.Digester()
+	tw := tar.NewWriter(io.MultiWriter(gw, uncompressed.Hash()))
+	if err := o.addFiles(tw); err != nil {
+		//nolint:errcheck
+		f.Close()
+		return ocispec.Descriptor{}, "", err
+	}
+	for _, c := range []io.Closer{tw, gw, f} {
+		if err := c.Close(); err != nil {
+			return ocispec.Descriptor{}, "", err
+		}
+	}
+	if err := os.Rename(f.Name(), o.blobPath(compressed.Digest())); err != nil {
+		return ocispec.Descriptor{}, "", err
+	}
+	return ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: compressed.Digest(), Size: counter.n},
+		uncompressed.Digest(), nil
+}
+
+// addFiles adds the directory in image and the files in context into the tar, the files are owned by root and
+// their modification time are reset, so that the layer is reproducible
+func (o *ociBuilder) addFiles(tw *tar.Writer) error {
+	dir := strings.TrimPrefix(o.imageDir(), "/")
+	if dir != "" {
+		var parent string
+		for _, segment := range strings.Split(dir, "/") {
+			parent = path.Join(parent, segment)
+			if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: parent + "/", Mode: 0755, ModTime: time.Unix(0, 0)}); err != nil {
+				return err
+			}
+		}
+	}
+	root := o.build.OCI.Context
+	rootInfo, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := filepath.Base(p)
+		if rootInfo.IsDir() {
+			if rel, err = filepath.Rel(root, p); err != nil {
+				return err
+			}
+			if rel == "." {
+				return nil
+			}
+		}
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(dir, filepath.ToSlash(rel))
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.ModTime = time.Unix(0, 0)
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(filepath.Clean(p))
+		if err != nil {
+			return err
+		}
+		//nolint:errcheck
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+}
+
+// writeJSONBlob writes the object as a blob in the layout
+func (o *ociBuilder) writeJSONBlob(mediaType string, obj interface{}) (ocispec.Descriptor, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	dgst := digest.FromBytes(data)
+	if err := ioutil.WriteFile(o.blobPath(dgst), data, 0600); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return ocispec.Descriptor{MediaType: mediaType, Digest: dgst, Size: int64(len(data))}, nil
+}
+
+// blobPath returns the path of the blob in the layout
+func (o *ociBuilder) blobPath(dgst digest.Digest) string {
+	return filepath.Join(o.layout, "blobs", dgst.Algorithm().String(), dgst.Encoded())
+}
+
+func readJSON(file string, obj interface{}) error {
+	data, err := ioutil.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, obj)
+}
+
+// tarDir archives the files in the directory into the tar file
+func tarDir(dir, file string) error {
+	f, err := os.Create(filepath.Clean(file))
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(f)
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		src, err := os.Open(filepath.Clean(p))
+		if err != nil {
+			return err
+		}
+		//nolint:errcheck
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		//nolint:errcheck
+		f.Close()
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		//nolint:errcheck
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// countWriter counts the bytes written
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
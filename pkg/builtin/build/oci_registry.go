@@ -0,0 +1,71 @@
+package build
+
+import (
+	"os"
+
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+const (
+	// dockerHubHost is the host of the registry API of Docker Hub
+	dockerHubHost = "registry-1.docker.io"
+	// dockerHubAuthKey is the key of the credentials of Docker Hub in docker config
+	dockerHubAuthKey = "https://index.docker.io/v1/"
+)
+
+// parseReference parses the image in format [host/]repo[:tag][@digest] into its fully qualified name, the image is
+// in Docker Hub if no host, and it's tagged latest if neither tag nor digest is specified
+func parseReference(image string) (reference.Named, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid image %s", image)
+	}
+	return reference.TagNameOnly(named), nil
+}
+
+// tagOrDigest returns the digest of image if it's referred by digest, otherwise its tag
+func tagOrDigest(ref reference.Named) string {
+	if digested, ok := ref.(reference.Digested); ok {
+		return digested.Digest().String()
+	}
+	if tagged, ok := ref.(reference.Tagged); ok {
+		return tagged.Tag()
+	}
+	return ""
+}
+
+// newResolver creates the resolver which pulls and pushes images by the registry HTTP API V2, it authenticates with
+// the credentials in docker config, and the registry is accessed by plain HTTP if it's insecure or on localhost
+func newResolver(insecure bool) remotes.Resolver {
+	return docker.NewResolver(docker.ResolverOptions{Credentials: lookupCredentials, PlainHTTP: insecure})
+}
+
+// lookupCredentials returns the username and secret of the registry in docker config, the credentials are got from
+// the credential helper if it's configured for the registry
+func lookupCredentials(host string) (string, string, error) {
+	// the config dir of docker cli is resolved once on init, so DOCKER_CONFIG is read here in case it's changed
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		dir = config.Dir()
+	}
+	cfg, err := config.Load(dir)
+	if err != nil {
+		return "", "", errors.Wrap(err, "load docker config")
+	}
+	if host == dockerHubHost {
+		host = dockerHubAuthKey
+	}
+	auth, err := cfg.GetAuthConfig(host)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "get credentials of registry %s", host)
+	}
+	// an identity token is used as a long lived token without username
+	if auth.IdentityToken != "" {
+		return "", auth.IdentityToken, nil
+	}
+	return auth.Username, auth.Password, nil
+}
@@ -0,0 +1,243 @@
+package build
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/containerd/containerd/images"
+	"github.com/docker/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	cmdutil "github.com/oam-dev/kubevela/pkg/commands/util"
+)
+
+// fakeRegistry is a registry which only supports the API used by oci backend, a token is required to access it
+type fakeRegistry struct {
+	sync.Mutex
+	url string
+	// blobs are the blobs by digest
+	blobs map[string][]byte
+	// manifests are the manifests by path `<repo>/manifests/<tag or digest>`
+	manifests map[string][]byte
+	uploads   int
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+	if req.URL.Path == "/token" {
+		w.Write([]byte(`{"token":"test-token"}`))
+		return
+	}
+	if req.Header.Get("Authorization") != "Bearer test-token" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake"`, r.url))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	p := strings.TrimPrefix(req.URL.Path, "/v2/")
+	write := func(data []byte, ok bool, mediaType string) {
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", mediaType)
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		w.Header().Set("Docker-Content-Digest", digest.FromBytes(data).String())
+		if req.Method != http.MethodHead {
+			w.Write(data)
+		}
+	}
+	switch {
+	case strings.Contains(p, "/manifests/") && req.Method == http.MethodPut:
+		r.manifests[p] = body
+		r.manifests[p[:strings.LastIndex(p, "/")+1]+digest.FromBytes(body).String()] = body
+		w.Header().Set("Docker-Content-Digest", digest.FromBytes(body).String())
+		w.WriteHeader(http.StatusCreated)
+	case strings.Contains(p, "/manifests/"):
+		data, ok := r.manifests[p]
+		write(data, ok, ocispec.MediaTypeImageManifest)
+	case strings.HasSuffix(p, "/blobs/uploads/") && req.Method == http.MethodPost:
+		r.uploads++
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s%d", p, r.uploads))
+		w.WriteHeader(http.StatusAccepted)
+	case strings.Contains(p, "/blobs/uploads/") && req.Method == http.MethodPut:
+		r.blobs[req.URL.Query().Get("digest")] = body
+		w.Header().Set("Docker-Content-Digest", req.URL.Query().Get("digest"))
+		w.WriteHeader(http.StatusCreated)
+	case strings.Contains(p, "/blobs/"):
+		data, ok := r.blobs[p[strings.LastIndex(p, "/")+1:]]
+		write(data, ok, "application/octet-stream")
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestOCIBuilder(t *testing.T) {
+	registry := &fakeRegistry{blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+	ts := httptest.NewServer(registry)
+	defer ts.Close()
+	registry.url = ts.URL
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	dir, err := ioutil.TempDir("", "oci-test")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	// no credentials are in docker config
+	os.Setenv("DOCKER_CONFIG", dir)
+	defer os.Unsetenv("DOCKER_CONFIG")
+	context := filepath.Join(dir, "context")
+	assert.Equal(t, nil, os.MkdirAll(context, 0750))
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(context, "app"), []byte("binary"), 0700))
+	ioStreams := cmdutil.IOStreams{In: os.Stdin, Out: ioutil.Discard, ErrOut: ioutil.Discard}
+
+	build := func(image string, oci OCI) *ocispec.Manifest {
+		builder, err := NewBuilder(&Build{Backend: BackendOCI, OCI: oci})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, nil, builder.Build(ioStreams, image))
+		assert.Equal(t, nil, builder.Push(ioStreams, image))
+		ref, _ := parseReference(image)
+		m := &ocispec.Manifest{}
+		assert.Equal(t, nil, json.Unmarshal(registry.manifests[reference.Path(ref)+"/manifests/"+tagOrDigest(ref)], m))
+		return m
+	}
+	getConfig := func(m *ocispec.Manifest) *ocispec.Image {
+		config := &ocispec.Image{}
+		assert.Equal(t, nil, json.Unmarshal(registry.blobs[m.Config.Digest.String()], config))
+		return config
+	}
+
+	base := build(host+"/base:v1", OCI{Context: context, Dir: "/bin", Env: []string{"PATH=/bin"}, Entrypoint: []string{"/bin/app"}})
+	assert.Equal(t, 1, len(base.Layers))
+	config := getConfig(base)
+	assert.Equal(t, "linux", config.OS)
+	assert.Equal(t, "amd64", config.Architecture)
+	assert.Equal(t, 1, len(config.RootFS.DiffIDs))
+	assert.Equal(t, []string{"/bin/app"}, config.Config.Entrypoint)
+
+	gr, err := gzip.NewReader(strings.NewReader(string(registry.blobs[base.Layers[0].Digest.String()])))
+	assert.Equal(t, nil, err)
+	tr := tar.NewReader(gr)
+	var files []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Equal(t, nil, err)
+		files = append(files, hdr.Name)
+	}
+	assert.Equal(t, []string{"bin/", "bin/app"}, files)
+
+	layout := filepath.Join(dir, "layout")
+	app := build(host+"/app:v1", OCI{Base: host + "/base:v1", Context: filepath.Join(context, "app"), Dir: "/opt",
+		Env: []string{"PATH=/bin:/opt", "MODE=test"}, Cmd: []string{"--verbose"}, Layout: layout})
+	assert.Equal(t, 2, len(app.Layers))
+	assert.Equal(t, base.Layers[0], app.Layers[0])
+	config = getConfig(app)
+	assert.Equal(t, []string{"PATH=/bin:/opt", "MODE=test"}, config.Config.Env)
+	assert.Equal(t, []string{"/bin/app"}, config.Config.Entrypoint)
+	assert.Equal(t, []string{"--verbose"}, config.Config.Cmd)
+	assert.Equal(t, 2, len(config.RootFS.DiffIDs))
+
+	idx := &ocispec.Index{}
+	assert.Equal(t, nil, readJSON(filepath.Join(layout, "index.json"), idx))
+	assert.Equal(t, "v1", idx.Manifests[0].Annotations[ocispec.AnnotationRefName])
+	assert.Equal(t, host+"/app:v1", idx.Manifests[0].Annotations[images.AnnotationImageName])
+	_, err = os.Stat(filepath.Join(layout, "blobs", "sha256", app.Layers[1].Digest.Encoded()))
+	assert.Equal(t, nil, err)
+	// the blobs being pulled are not left in the layout
+	_, err = os.Stat(filepath.Join(layout, "ingest"))
+	assert.Equal(t, true, os.IsNotExist(err))
+}
+
+func TestOCIBuilderCleanup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oci-test")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	os.Setenv("TMPDIR", dir)
+	defer os.Unsetenv("TMPDIR")
+	ioStreams := cmdutil.IOStreams{In: os.Stdin, Out: ioutil.Discard, ErrOut: ioutil.Discard}
+
+	// the temporary layout is removed if the image fails to be built
+	builder, err := NewBuilder(&Build{Backend: BackendOCI, OCI: OCI{Context: filepath.Join(dir, "not-exist")}})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, builder.Build(ioStreams, "localhost:5000/app:v1") != nil)
+	files, err := ioutil.ReadDir(dir)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(files))
+}
+
+func TestLookupCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oci-test")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	os.Setenv("DOCKER_CONFIG", dir)
+	defer os.Unsetenv("DOCKER_CONFIG")
+	// the credential helper only has the credentials of helper.io
+	helper := `#!/bin/sh
+read server
+if [ "$server" = "helper.io" ]; then
+  echo '{"ServerURL":"helper.io","Username":"user","Secret":"secret"}'
+else
+  echo "credentials not found in native keychain"
+  exit 1
+fi
+`
+	assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "docker-credential-test"), []byte(helper), 0700))
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+
+	writeConfig := func(config string) {
+		assert.Equal(t, nil, ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0600))
+	}
+	assertCredentials := func(host, username, secret string) {
+		u, s, err := lookupCredentials(host)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, username, u)
+		assert.Equal(t, secret, s)
+	}
+	writeConfig(`{"auths":{"auth.io":{"auth":"YTpi"},"helper.io":{}},"credHelpers":{"helper.io":"test","other.io":"test"}}`)
+	assertCredentials("auth.io", "a", "b")
+	assertCredentials("helper.io", "user", "secret")
+	assertCredentials("other.io", "", "")
+
+	writeConfig(`{"auths":{"helper.io":{}},"credsStore":"test"}`)
+	assertCredentials("helper.io", "user", "secret")
+	assertCredentials("none.io", "", "")
+}
+
+func TestParseReference(t *testing.T) {
+	digested := "gcr.io/distroless/static@sha256:" + strings.Repeat("1", 64)
+	cases := map[string]struct {
+		name        string
+		tagOrDigest string
+	}{
+		"nginx":              {name: "docker.io/library/nginx:latest", tagOrDigest: "latest"},
+		"oamdev/testapp:v1":  {name: "docker.io/oamdev/testapp:v1", tagOrDigest: "v1"},
+		"localhost:5000/app": {name: "localhost:5000/app:latest", tagOrDigest: "latest"},
+		digested:             {name: digested, tagOrDigest: "sha256:" + strings.Repeat("1", 64)},
+	}
+	for image, expected := range cases {
+		ref, err := parseReference(image)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, expected.name, ref.String())
+		assert.Equal(t, expected.tagOrDigest, tagOrDigest(ref))
+	}
+	_, err := parseReference("Invalid/Image")
+	assert.Equal(t, true, err != nil)
+}
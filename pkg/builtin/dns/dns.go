@@ -0,0 +1,113 @@
+package dns
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/oam-dev/kubevela/pkg/builtin/registry"
+)
+
+func init() {
+	registry.RegisterRunner("dns.label", newLabelCmd)
+	registry.RegisterRunner("dns.service", newServiceCmd)
+}
+
+// defaultClusterDomain is the default domain of the services in a cluster
+const defaultClusterDomain = "cluster.local"
+
+// invalidLabelChars matches the characters not allowed in a DNS-1123 label
+var invalidLabelChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// LabelCmd builds a valid DNS-1123 label from parts, e.g. the name of a resource derived from the application
+type LabelCmd struct{}
+
+func newLabelCmd(v cue.Value) (registry.Runner, error) {
+	return &LabelCmd{}, nil
+}
+
+// Run joins `parts` with "-", the characters not allowed in a DNS-1123 label are replaced with "-".
+// A label longer than 63 characters is truncated and suffixed with the hash of the whole label to keep it unique.
+func (c *LabelCmd) Run(meta *registry.Meta) (res interface{}, err error) {
+	partsVal := meta.Lookup("parts")
+	if meta.Err != nil {
+		return nil, errors.WithMessage(meta.Err, "parts is required")
+	}
+	iter, err := partsVal.List()
+	if err != nil {
+		return nil, errors.WithMessage(err, "parts must be a list of strings")
+	}
+	var parts []string
+	for iter.Next() {
+		part, err := iter.Value().String()
+		if err != nil {
+			return nil, errors.WithMessage(err, "parts must be a list of strings")
+		}
+		parts = append(parts, part)
+	}
+	return ToLabel(parts...)
+}
+
+// ToLabel converts the parts to a valid DNS-1123 label
+func ToLabel(parts ...string) (string, error) {
+	full := strings.ToLower(strings.Join(parts, "-"))
+	label := strings.Trim(invalidLabelChars.ReplaceAllString(full, "-"), "-")
+	if len(label) > validation.DNS1123LabelMaxLength {
+		hasher := fnv.New32a()
+		_, _ = hasher.Write([]byte(full))
+		suffix := fmt.Sprintf("-%08x", hasher.Sum32())
+		label = strings.TrimRight(label[:validation.DNS1123LabelMaxLength-len(suffix)], "-") + suffix
+	}
+	if errs := validation.IsDNS1123Label(label); len(errs) != 0 {
+		return "", errors.Errorf("cannot build a DNS label from %q: %s", parts, strings.Join(errs, "; "))
+	}
+	return label, nil
+}
+
+// ServiceCmd builds the fully qualified domain name of a service in the cluster
+type ServiceCmd struct{}
+
+func newServiceCmd(v cue.Value) (registry.Runner, error) {
+	return &ServiceCmd{}, nil
+}
+
+// Run returns `<service>.<namespace>.svc.<clusterDomain>`, the namespace is the one of the application by default
+// and the cluster domain is "cluster.local" by default
+func (c *ServiceCmd) Run(meta *registry.Meta) (res interface{}, err error) {
+	name := meta.String("service")
+	if meta.Err != nil {
+		return nil, errors.WithMessage(meta.Err, "service is required")
+	}
+	if errs := validation.IsDNS1123Label(name); len(errs) != 0 {
+		return nil, errors.Errorf("invalid service name %s: %s", name, strings.Join(errs, "; "))
+	}
+	namespace, err := optionalString(meta.Obj, "namespace", meta.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		return nil, errors.New("namespace is required")
+	}
+	clusterDomain, err := optionalString(meta.Obj, "clusterDomain", defaultClusterDomain)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Sprintf("%s.%s.svc.%s", name, namespace, clusterDomain), nil
+}
+
+func optionalString(v cue.Value, field, defaultValue string) (string, error) {
+	f := v.Lookup(field)
+	if !f.Exists() {
+		return defaultValue, nil
+	}
+	s, err := f.String()
+	if err != nil {
+		return "", errors.WithMessagef(err, "invalid %s", field)
+	}
+	return s, nil
+}
@@ -0,0 +1,113 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+
+	"cuelang.org/go/cue"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/kubevela/pkg/builtin/registry"
+)
+
+func TestLabelCmd_Run(t *testing.T) {
+	testCases := map[string]struct {
+		task      string
+		expect    string
+		expectErr bool
+	}{
+		"join parts": {
+			task:   `parts: ["myapp", "web"]`,
+			expect: "myapp-web",
+		},
+		"invalid characters replaced": {
+			task:   `parts: ["My_App", "web.v1", "--"]`,
+			expect: "my-app-web-v1",
+		},
+		"empty label": {
+			task:      `parts: ["_", "."]`,
+			expectErr: true,
+		},
+		"parts is required": {
+			task:      `name: "myapp"`,
+			expectErr: true,
+		},
+		"parts must be strings": {
+			task:      `parts: ["myapp", 1]`,
+			expectErr: true,
+		},
+	}
+
+	runner, err := registry.LookupRunner("dns.label")(cue.Value{})
+	assert.NoError(t, err)
+	for name, tc := range testCases {
+		r := cue.Runtime{}
+		inst, err := r.Compile("", tc.task)
+		assert.NoError(t, err, name)
+		got, err := runner.Run(&registry.Meta{Obj: inst.Value()})
+		if tc.expectErr {
+			assert.Error(t, err, name)
+			continue
+		}
+		assert.NoError(t, err, name)
+		assert.Equal(t, tc.expect, got, name)
+	}
+
+	// a too long label is truncated and suffixed with a hash to keep it unique
+	long, err := ToLabel(strings.Repeat("a", 60), "web")
+	assert.NoError(t, err)
+	assert.Equal(t, 63, len(long))
+	assert.True(t, strings.HasPrefix(long, strings.Repeat("a", 54)+"-"))
+	other, err := ToLabel(strings.Repeat("a", 60), "worker")
+	assert.NoError(t, err)
+	assert.NotEqual(t, long, other)
+}
+
+func TestServiceCmd_Run(t *testing.T) {
+	testCases := map[string]struct {
+		task      string
+		namespace string
+		expect    string
+		expectErr bool
+	}{
+		"service in the namespace of application": {
+			task:      `service: "mydb"`,
+			namespace: "default",
+			expect:    "mydb.default.svc.cluster.local",
+		},
+		"service in the specified namespace and cluster domain": {
+			task:      `{service: "mydb", namespace: "db", clusterDomain: "example.org"}`,
+			namespace: "default",
+			expect:    "mydb.db.svc.example.org",
+		},
+		"invalid service name": {
+			task:      `service: "My_DB"`,
+			namespace: "default",
+			expectErr: true,
+		},
+		"service is required": {
+			task:      `namespace: "db"`,
+			namespace: "default",
+			expectErr: true,
+		},
+		"namespace is required": {
+			task:      `service: "mydb"`,
+			expectErr: true,
+		},
+	}
+
+	runner, err := registry.LookupRunner("dns.service")(cue.Value{})
+	assert.NoError(t, err)
+	for name, tc := range testCases {
+		r := cue.Runtime{}
+		inst, err := r.Compile("", tc.task)
+		assert.NoError(t, err, name)
+		got, err := runner.Run(&registry.Meta{Obj: inst.Value(), Namespace: tc.namespace})
+		if tc.expectErr {
+			assert.Error(t, err, name)
+			continue
+		}
+		assert.NoError(t, err, name)
+		assert.Equal(t, tc.expect, got, name)
+	}
+}
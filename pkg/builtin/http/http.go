@@ -1,16 +1,58 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 
 	"cuelang.org/go/cue"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
 	"github.com/oam-dev/kubevela/pkg/builtin/registry"
 )
 
+const (
+	// DefaultTimeout is the timeout of a request if `timeout` is not specified
+	DefaultTimeout = 30 * time.Second
+	// DefaultBackoff is the time to wait before the first retry if `backoff` is not specified, it's doubled for each retry
+	DefaultBackoff = time.Second
+	// DefaultRetries is the times to retry an idempotent request if `retries` is not specified,
+	// the other requests are not retried by default since they may have been processed
+	DefaultRetries = 2
+	// MaxRetryDuration caps the total time of a request and its retries, no more retry is made after it
+	MaxRetryDuration = 5 * time.Minute
+	// MaxReconcileTimeout caps the timeout of a request sent in the reconcile of the controller,
+	// so that a slow service doesn't hold the workers of the controller
+	MaxReconcileTimeout = 5 * time.Second
+
+	// FormatJSON decodes the response body as JSON
+	FormatJSON = "json"
+	// FormatYAML decodes the response body as YAML
+	FormatYAML = "yaml"
+	// FormatText keeps the response body as string
+	FormatText = "text"
+)
+
+const (
+	// tlsCAKey is the key of the CA certificate in the secret referred by `tls.secret`
+	tlsCAKey = "ca.crt"
+	// tlsCertKey is the key of the client certificate in the secret referred by `tls.secret`
+	tlsCertKey = "tls.crt"
+	// tlsPrivateKeyKey is the key of the private key of the client certificate in the secret referred by `tls.secret`
+	tlsPrivateKeyKey = "tls.key"
+)
+
 func init() {
 	registry.RegisterRunner("http", newHTTPCmd)
 }
@@ -21,24 +63,67 @@ type HTTPCmd struct {
 }
 
 func newHTTPCmd(v cue.Value) (registry.Runner, error) {
-	client := http.DefaultClient
-	return &HTTPCmd{client}, nil
+	return &HTTPCmd{&http.Client{}}, nil
+}
+
+// RetryableError is returned instead of waiting to retry a request sent in the reconcile of the controller,
+// the reconcile is requeued after RetryAfter to send the request again
+type RetryableError struct {
+	RetryAfter time.Duration
+	Err        error
 }
 
-// Run exec the actual http logic, and res represent the result of http task
+func (e *RetryableError) Error() string {
+	return fmt.Sprintf("the request will be retried after %s: %v", e.RetryAfter, e.Err)
+}
+
+// Unwrap returns the error of the request
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// AsRetryableError returns the RetryableError in the chain of err if there is one
+func AsRetryableError(err error) (*RetryableError, bool) {
+	var re *RetryableError
+	if errors.As(err, &re) {
+		return re, true
+	}
+	return nil, false
+}
+
+// options are the options of sending the request and decoding the response
+type options struct {
+	timeout time.Duration
+	retries int64
+	backoff time.Duration
+	// format is the format to decode the response body in, the body isn't decoded if it's empty
+	format string
+	tls    *tls.Config
+}
+
+// Run exec the actual http logic, and res represent the result of http task.
+// The result contains the `statusCode`, `body`, `header` and `trailer` of the response, and the body decoded
+// in `response.format` is in `data` if the format is specified.
+// The request is retried `retries` times on connection errors and 5xx or 429 responses,
+// only idempotent requests are retried if `retries` is not specified.
+// In the reconcile of the controller, the timeout is capped by MaxReconcileTimeout and a RetryableError is returned
+// instead of waiting to retry, so the request is retried by requeueing the reconcile.
 func (c *HTTPCmd) Run(meta *registry.Meta) (res interface{}, err error) {
 	var header, trailer http.Header
 	var (
 		method = meta.String("method")
 		u      = meta.String("url")
 	)
-	var r io.Reader
+	var body []byte
 	if obj := meta.Obj.Lookup("request"); obj.Exists() {
 		if v := obj.Lookup("body"); v.Exists() {
-			r, err = v.Reader()
+			r, err := v.Reader()
 			if err != nil {
 				return nil, err
 			}
+			if body, err = ioutil.ReadAll(r); err != nil {
+				return nil, err
+			}
 		}
 		if header, err = parseHeaders(obj, "header"); err != nil {
 			return nil, err
@@ -48,32 +133,242 @@ func (c *HTTPCmd) Run(meta *registry.Meta) (res interface{}, err error) {
 		}
 	}
 	if header == nil {
+		header = http.Header{}
+	}
+	if header.Get("Content-Type") == "" {
 		header.Set("Content-Type", "application/json")
 	}
 	if meta.Err != nil {
 		return nil, meta.Err
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), method, u, r)
+	ctx := meta.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	opts, err := parseOptions(ctx, meta, method)
 	if err != nil {
 		return nil, err
 	}
-	req.Header = header
-	req.Trailer = trailer
+	cli := *c.Client
+	cli.Timeout = opts.timeout
+	if meta.InReconcile && cli.Timeout > MaxReconcileTimeout {
+		cli.Timeout = MaxReconcileTimeout
+	}
+	if opts.tls != nil {
+		cli.Transport = &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: opts.tls}
+	}
 
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, err
+	var resp *http.Response
+	backoff := opts.backoff
+	deadline := time.Now().Add(MaxRetryDuration)
+	for i := int64(0); ; i++ {
+		var r io.Reader
+		if body != nil {
+			r = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u, r)
+		if err != nil {
+			return nil, err
+		}
+		req.Header = header
+		req.Trailer = trailer
+
+		resp, err = cli.Do(req)
+		retryable := err != nil || shouldRetry(resp.StatusCode)
+		if meta.InReconcile && retryable && opts.retries > 0 {
+			if err == nil {
+				//nolint:errcheck
+				resp.Body.Close()
+				err = errors.Errorf("the response status code is %d", resp.StatusCode)
+			}
+			return nil, &RetryableError{RetryAfter: opts.backoff, Err: err}
+		}
+		if i >= opts.retries || !retryable || time.Now().Add(backoff).After(deadline) {
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+		if err == nil {
+			//nolint:errcheck
+			resp.Body.Close()
+		}
+		if err := wait(ctx, backoff); err != nil {
+			return nil, errors.Wrap(err, "wait to retry the request")
+		}
+		backoff *= 2
 	}
 	//nolint:errcheck
 	defer resp.Body.Close()
 	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
 	// parse response body and headers
-	return map[string]interface{}{
-		"body":    string(b),
-		"header":  resp.Header,
-		"trailer": resp.Trailer,
-	}, err
+	result := map[string]interface{}{
+		"statusCode": resp.StatusCode,
+		"body":       string(b),
+		"header":     resp.Header,
+		"trailer":    resp.Trailer,
+	}
+	if opts.format != "" {
+		data, err := DecodeBody(b, opts.format)
+		if err != nil && isSuccess(resp.StatusCode) {
+			return nil, err
+		}
+		if err == nil {
+			result["data"] = data
+		}
+	}
+	return result, nil
+}
+
+// DecodeBody decodes the response body in the format
+func DecodeBody(body []byte, format string) (interface{}, error) {
+	var data interface{}
+	switch format {
+	case FormatJSON:
+		if err := json.Unmarshal(body, &data); err != nil {
+			return nil, errors.Wrap(err, "decode response body as json")
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal(body, &data); err != nil {
+			return nil, errors.Wrap(err, "decode response body as yaml")
+		}
+	case FormatText:
+		data = string(body)
+	default:
+		return nil, errors.Errorf("unsupported response format %s, it must be one of %s, %s and %s",
+			format, FormatJSON, FormatYAML, FormatText)
+	}
+	return data, nil
+}
+
+// ResponseFormat returns `response.format` of the http task, or the default format if it's not specified
+func ResponseFormat(v cue.Value, defaultFormat string) (string, error) {
+	f := v.Lookup("response", "format")
+	if !f.Exists() {
+		return defaultFormat, nil
+	}
+	format, err := f.String()
+	if err != nil {
+		return "", errors.WithMessage(err, "invalid response.format")
+	}
+	return format, nil
+}
+
+// wait waits for the duration unless the context is done
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isIdempotent returns whether the request of the method can be sent multiple times with the same effect
+func isIdempotent(method string) bool {
+	switch strings.ToUpper(method) {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func isSuccess(statusCode int) bool {
+	return statusCode >= 200 && statusCode < 300
+}
+
+func shouldRetry(statusCode int) bool {
+	return statusCode >= 500 || statusCode == http.StatusTooManyRequests
+}
+
+func parseOptions(ctx context.Context, meta *registry.Meta, method string) (*options, error) {
+	opts := &options{}
+	if isIdempotent(method) {
+		opts.retries = DefaultRetries
+	}
+	var err error
+	if opts.timeout, err = lookupDuration(meta.Obj, "timeout", DefaultTimeout); err != nil {
+		return nil, err
+	}
+	if opts.backoff, err = lookupDuration(meta.Obj, "backoff", DefaultBackoff); err != nil {
+		return nil, err
+	}
+	if v := meta.Obj.Lookup("retries"); v.Exists() {
+		if opts.retries, err = v.Int64(); err != nil {
+			return nil, errors.WithMessage(err, "invalid retries")
+		}
+		if opts.retries < 0 {
+			return nil, errors.Errorf("invalid retries %d, it must not be negative", opts.retries)
+		}
+	}
+	if opts.format, err = ResponseFormat(meta.Obj, ""); err != nil {
+		return nil, err
+	}
+	if v := meta.Obj.Lookup("tls", "secret"); v.Exists() {
+		name, err := v.String()
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid tls.secret")
+		}
+		if opts.tls, err = loadTLSConfig(ctx, meta, name); err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+func lookupDuration(obj cue.Value, field string, defaultDuration time.Duration) (time.Duration, error) {
+	v := obj.Lookup(field)
+	if !v.Exists() {
+		return defaultDuration, nil
+	}
+	str, err := v.String()
+	if err != nil {
+		return 0, errors.WithMessagef(err, "invalid %s", field)
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "invalid %s", field)
+	}
+	return d, nil
+}
+
+// loadTLSConfig loads the CA certificate and the client certificate from the secret in the namespace of the application
+func loadTLSConfig(ctx context.Context, meta *registry.Meta, name string) (*tls.Config, error) {
+	if meta.Client == nil {
+		return nil, errors.Errorf("cannot get secret %s of tls without a cluster", name)
+	}
+	secret := &corev1.Secret{}
+	if err := meta.Client.Get(ctx, client.ObjectKey{Namespace: meta.Namespace, Name: name}, secret); err != nil {
+		return nil, errors.Wrapf(err, "get secret %s of tls in namespace %s", name, meta.Namespace)
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if ca, ok := secret.Data[tlsCAKey]; ok {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("invalid %s in secret %s", tlsCAKey, name)
+		}
+		config.RootCAs = pool
+	}
+	cert, hasCert := secret.Data[tlsCertKey]
+	key, hasKey := secret.Data[tlsPrivateKeyKey]
+	if hasCert != hasKey {
+		return nil, errors.Errorf("both %s and %s are required in secret %s for client certificate", tlsCertKey, tlsPrivateKeyKey, name)
+	}
+	if hasCert {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid client certificate in secret %s", name)
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+	return config, nil
 }
 
 func parseHeaders(obj cue.Value, label string) (http.Header, error) {
@@ -1,15 +1,24 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"cuelang.org/go/cue"
 	"github.com/bmizerany/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	//lint:ignore SA1019 We will use pkg/envtest before upgrading controller-runtime to v1.0.0
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/oam-dev/kubevela/pkg/builtin/registry"
 )
@@ -52,6 +61,205 @@ func TestHTTPCmd_Run(t *testing.T) {
 
 }
 
+func TestHTTPCmd_RunWithOptions(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/retry":
+			attempts++
+			if attempts < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("name: test"))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/header":
+			w.Write([]byte(r.Header.Get("Content-Type")))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}
+	}))
+	defer ts.Close()
+
+	run := func(task string) (map[string]interface{}, error) {
+		r := cue.Runtime{}
+		inst, err := r.Compile("", task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner, _ := newHTTPCmd(cue.Value{})
+		got, err := runner.Run(&registry.Meta{Obj: inst.Value()})
+		if err != nil {
+			return nil, err
+		}
+		return got.(map[string]interface{}), nil
+	}
+
+	got, err := run(fmt.Sprintf(`
+method: "GET"
+url: "%s/retry"
+retries: 3
+backoff: "10ms"
+response: format: "yaml"
+`, ts.URL))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, http.StatusOK, got["statusCode"])
+	assert.Equal(t, map[string]interface{}{"name": "test"}, got["data"])
+
+	got, err = run(fmt.Sprintf(`
+method: "GET"
+url: "%s/missing"
+response: format: "json"
+`, ts.URL))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, http.StatusNotFound, got["statusCode"])
+	assert.Equal(t, "not found", got["body"])
+	_, decoded := got["data"]
+	assert.Equal(t, false, decoded)
+
+	got, err = run(fmt.Sprintf(`
+method: "GET"
+url: "%s/header"
+response: format: "text"
+`, ts.URL))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "application/json", got["data"])
+
+	// an idempotent request is retried by default, while the others are only retried if retries are requested
+	attempts = 0
+	got, err = run(fmt.Sprintf(`
+method: "POST"
+url: "%s/retry"
+backoff: "10ms"
+`, ts.URL))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, got["statusCode"])
+	attempts = 0
+	got, err = run(fmt.Sprintf(`
+method: "PUT"
+url: "%s/retry"
+backoff: "10ms"
+`, ts.URL))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, http.StatusOK, got["statusCode"])
+
+	_, err = run(fmt.Sprintf(`
+method: "GET"
+url: "%s/slow"
+timeout: "50ms"
+retries: 0
+`, ts.URL))
+	assert.Equal(t, true, err != nil)
+
+	_, err = run(fmt.Sprintf(`
+method: "GET"
+url: "%s/header"
+response: format: "xml"
+`, ts.URL))
+	assert.Equal(t, true, err != nil)
+}
+
+func TestHTTPCmd_RunCanceled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	r := cue.Runtime{}
+	inst, err := r.Compile("", fmt.Sprintf(`
+method: "GET"
+url: "%s"
+retries: 3
+backoff: "10s"
+`, ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	runner, _ := newHTTPCmd(cue.Value{})
+	start := time.Now()
+	_, err = runner.Run(&registry.Meta{Context: ctx, Obj: inst.Value()})
+	assert.Equal(t, true, err != nil)
+	assert.Equal(t, true, time.Since(start) < 5*time.Second)
+}
+
+func TestHTTPCmd_RunInReconcile(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	run := func(task string) error {
+		r := cue.Runtime{}
+		inst, err := r.Compile("", task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner, _ := newHTTPCmd(cue.Value{})
+		_, err = runner.Run(&registry.Meta{Obj: inst.Value(), InReconcile: true})
+		return err
+	}
+
+	// the request is not retried in the reconcile, a retryable error is returned to requeue it
+	err := run(fmt.Sprintf(`
+method: "GET"
+url: "%s"
+retries: 3
+backoff: "10s"
+`, ts.URL))
+	re, ok := AsRetryableError(err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 10*time.Second, re.RetryAfter)
+	assert.Equal(t, 1, attempts)
+
+	// the request which shouldn't be retried returns the response as usual
+	attempts = 0
+	err = run(fmt.Sprintf(`
+method: "POST"
+url: "%s"
+`, ts.URL))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestHTTPCmd_RunWithTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secure"))
+	}))
+	defer ts.Close()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	cli := fake.NewFakeClientWithScheme(scheme.Scheme, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tls-secret", Namespace: "default"},
+		Data:       map[string][]byte{"ca.crt": ca},
+	})
+
+	run := func(secret string) (interface{}, error) {
+		r := cue.Runtime{}
+		inst, err := r.Compile("", fmt.Sprintf(`
+method: "GET"
+url: "%s"
+tls: secret: "%s"
+`, ts.URL, secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner, _ := newHTTPCmd(cue.Value{})
+		return runner.Run(&registry.Meta{Obj: inst.Value(), Client: cli, Namespace: "default"})
+	}
+	got, err := run("tls-secret")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "secure", got.(map[string]interface{})["body"])
+
+	_, err = run("not-exist")
+	assert.Equal(t, true, err != nil)
+}
+
 // NewMock mock the http server
 func NewMock() *httptest.Server {
 	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
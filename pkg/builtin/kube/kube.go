@@ -0,0 +1,69 @@
+package kube
+
+import (
+	"context"
+
+	"cuelang.org/go/cue"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/pkg/builtin/registry"
+)
+
+func init() {
+	registry.RegisterRunner("kube.get", newGetCmd)
+}
+
+// GetCmd reads an existing object in cluster, only the objects in the namespace of the application can be read
+type GetCmd struct{}
+
+func newGetCmd(v cue.Value) (registry.Runner, error) {
+	return &GetCmd{}, nil
+}
+
+// Run gets the object specified by `object.apiVersion`, `object.kind`, `object.metadata.name`
+// and the optional `object.metadata.namespace`, the object got is the result of the task
+func (c *GetCmd) Run(meta *registry.Meta) (res interface{}, err error) {
+	obj := meta.Lookup("object")
+	if meta.Err != nil {
+		return nil, errors.WithMessage(meta.Err, "object is required")
+	}
+	objMeta := &registry.Meta{Obj: obj}
+	var (
+		apiVersion = objMeta.String("apiVersion")
+		kind       = objMeta.String("kind")
+	)
+	if objMeta.Err != nil {
+		return nil, objMeta.Err
+	}
+	name, err := obj.Lookup("metadata", "name").String()
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid metadata.name")
+	}
+	if v := obj.Lookup("metadata", "namespace"); v.Exists() {
+		namespace, err := v.String()
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid metadata.namespace")
+		}
+		if namespace != meta.Namespace {
+			return nil, errors.Errorf("cannot get %s %s in namespace %s, only the objects in namespace %s can be read",
+				kind, name, namespace, meta.Namespace)
+		}
+	}
+	if meta.Client == nil {
+		return nil, errors.Errorf("cannot get %s %s without a cluster", kind, name)
+	}
+
+	ctx := meta.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	if err := meta.Client.Get(ctx, client.ObjectKey{Namespace: meta.Namespace, Name: name}, u); err != nil {
+		return nil, errors.Wrapf(err, "get %s %s in namespace %s", kind, name, meta.Namespace)
+	}
+	return u.Object, nil
+}
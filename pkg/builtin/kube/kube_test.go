@@ -0,0 +1,85 @@
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"cuelang.org/go/cue"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	//lint:ignore SA1019 We will use pkg/envtest before upgrading controller-runtime to v1.0.0
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/pkg/builtin/registry"
+)
+
+func TestGetCmd_Run(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, corev1.AddToScheme(scheme))
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-conn", Namespace: "default"},
+		Data:       map[string][]byte{"host": []byte("db.example.com")},
+	}
+	cli := fake.NewFakeClientWithScheme(scheme, secret)
+
+	testCases := map[string]struct {
+		task      string
+		namespace string
+		noClient  bool
+		expectErr bool
+	}{
+		"get object in the namespace of application": {
+			task:      `object: {apiVersion: "v1", kind: "Secret", metadata: name: "db-conn"}`,
+			namespace: "default",
+		},
+		"get object with the namespace of application specified": {
+			task:      `object: {apiVersion: "v1", kind: "Secret", metadata: {name: "db-conn", namespace: "default"}}`,
+			namespace: "default",
+		},
+		"object in other namespace is not allowed": {
+			task:      `object: {apiVersion: "v1", kind: "Secret", metadata: {name: "db-conn", namespace: "default"}}`,
+			namespace: "other",
+			expectErr: true,
+		},
+		"object not found": {
+			task:      `object: {apiVersion: "v1", kind: "Secret", metadata: name: "not-exist"}`,
+			namespace: "default",
+			expectErr: true,
+		},
+		"name is required": {
+			task:      `object: {apiVersion: "v1", kind: "Secret"}`,
+			namespace: "default",
+			expectErr: true,
+		},
+		"no cluster": {
+			task:      `object: {apiVersion: "v1", kind: "Secret", metadata: name: "db-conn"}`,
+			namespace: "default",
+			noClient:  true,
+			expectErr: true,
+		},
+	}
+
+	runner, err := registry.LookupRunner("kube.get")(cue.Value{})
+	assert.NoError(t, err)
+	for name, tc := range testCases {
+		r := cue.Runtime{}
+		inst, err := r.Compile("", tc.task)
+		assert.NoError(t, err, name)
+		meta := &registry.Meta{Context: context.Background(), Obj: inst.Value(), Namespace: tc.namespace}
+		if !tc.noClient {
+			meta.Client = cli
+		}
+		got, err := runner.Run(meta)
+		if tc.expectErr {
+			assert.Error(t, err, name)
+			continue
+		}
+		assert.NoError(t, err, name)
+		obj, ok := got.(map[string]interface{})
+		assert.True(t, ok, name)
+		assert.Equal(t, map[string]interface{}{"host": "ZGIuZXhhbXBsZS5jb20="}, obj["data"], name)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"cuelang.org/go/cue"
 	"cuelang.org/go/cue/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // Meta provides context for running a task.
@@ -18,6 +19,14 @@ type Meta struct {
 	Stderr  io.Writer
 	Obj     cue.Value
 	Err     error
+
+	// Client reads the resources in cluster, it's nil if the task is not run with a cluster
+	Client client.Reader
+	// Namespace is the namespace of the application which the task is run for
+	Namespace string
+	// InReconcile is set if the task is run in the reconcile of the controller, the task shouldn't block it for long,
+	// e.g. it fails with a retryable error rather than waiting to retry
+	InReconcile bool
 }
 
 // Lookup fetches the value of context by filed
@@ -63,6 +63,7 @@ func NewCommand() *cobra.Command {
 		// Apps
 		NewListCommand(commandArgs, ioStream),
 		NewDeleteCommand(commandArgs, ioStream),
+		NewRollbackCommand(commandArgs, ioStream),
 		NewAppStatusCommand(commandArgs, ioStream),
 		NewExecCommand(commandArgs, ioStream),
 		NewPortForwardCommand(commandArgs, ioStream),
@@ -75,6 +76,7 @@ func NewCommand() *cobra.Command {
 		NewTemplateCommand(ioStream),
 		NewTraitsCommand(commandArgs, ioStream),
 		NewWorkloadsCommand(commandArgs, ioStream),
+		NewDefinitionCommandGroup(ioStream),
 
 		// Helper
 		SystemCommandGroup(commandArgs, ioStream),
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	cmdutil "github.com/oam-dev/kubevela/pkg/commands/util"
+	"github.com/oam-dev/kubevela/pkg/dsl/deftest"
+)
+
+// NewDefinitionCommandGroup creates `def` command and its nested children command
+func NewDefinitionCommandGroup(ioStream cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "def",
+		DisableFlagsInUseLine: true,
+		Short:                 "Manage definitions",
+		Long:                  "Manage WorkloadDefinitions and TraitDefinitions",
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeCap,
+		},
+	}
+	cmd.SetOut(ioStream.Out)
+	cmd.AddCommand(NewDefinitionTestCommand(ioStream))
+	return cmd
+}
+
+// NewDefinitionTestCommand creates `def test` command
+func NewDefinitionTestCommand(ioStream cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "test DEFINITION_FILE TEST_FILE",
+		DisableFlagsInUseLine: true,
+		Short:                 "Test the template of a definition with test cases",
+		Long: "Test the template of a WorkloadDefinition or TraitDefinition with test cases without a cluster. " +
+			"Every test case renders the template with the parameter and context, then checks the rendered resources, " +
+			"health and status message against the expected ones",
+		Example: "vela def test webservice.yaml webservice_test.yaml",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDefinitionTest(ioStream, args[0], args[1])
+		},
+	}
+	cmd.SetOut(ioStream.Out)
+	return cmd
+}
+
+func runDefinitionTest(ioStream cmdutil.IOStreams, definitionFile, testFile string) error {
+	data, err := ioutil.ReadFile(filepath.Clean(definitionFile))
+	if err != nil {
+		return errors.Wrapf(err, "read definition file %s", definitionFile)
+	}
+	d, err := deftest.LoadDefinition(data)
+	if err != nil {
+		return err
+	}
+	data, err = ioutil.ReadFile(filepath.Clean(testFile))
+	if err != nil {
+		return errors.Wrapf(err, "read test file %s", testFile)
+	}
+	suite, err := deftest.LoadSuite(data)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, r := range d.Run(suite) {
+		if r.Passed() {
+			ioStream.Infof("PASS: %s\n", r.Name)
+			continue
+		}
+		failed++
+		ioStream.Infof("FAIL: %s\n", r.Name)
+		for _, f := range r.Failures {
+			ioStream.Infof("    %s\n", f)
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d test cases of %s failed", failed, len(suite.Cases), d.Name)
+	}
+	ioStream.Infof("all %d test cases of %s passed\n", len(suite.Cases), d.Name)
+	return nil
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 	corev1alpha2 "github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/appfile/dryrun"
 	cmdutil "github.com/oam-dev/kubevela/pkg/commands/util"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 )
@@ -20,8 +22,16 @@ import (
 type dryRunOptions struct {
 	cmdutil.IOStreams
 	applicationFile string
+	definitionDir   string
+	diff            bool
+	output          string
 }
 
+const (
+	dryRunOutputYAML = "yaml"
+	dryRunOutputJSON = "json"
+)
+
 // NewDryRunCommand creates `dry-run` command
 func NewDryRunCommand(c types.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
 	o := &dryRunOptions{IOStreams: ioStreams}
@@ -29,28 +39,53 @@ func NewDryRunCommand(c types.Args, ioStreams cmdutil.IOStreams) *cobra.Command
 		Use:                   "dry-run",
 		DisableFlagsInUseLine: true,
 		Short:                 "Dry Run an application, and output the conversion result to stdout",
-		Long:                  "Dry Run an application, and output the conversion result to stdout",
-		Example:               "vela dry-run",
+		Long:                  "Dry Run an application, and output the conversion result to stdout. With --diff, compare the result with the live resources in the cluster and output the changes of every resource. With --definitions, render the application with the definitions in local files without a cluster",
+		Example:               "vela system dry-run --diff -o json",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// rendering with local definitions doesn't need a cluster unless comparing with the live resources
+			if o.definitionDir != "" && !o.diff {
+				return nil
+			}
 			return c.SetConfig()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			newClient, err := client.New(c.Config, client.Options{Scheme: c.Schema})
-			if err != nil {
-				return err
-			}
-
-			dm, err := discoverymapper.New(c.Config)
-			if err != nil {
-				return err
+			if o.output != dryRunOutputYAML && o.output != dryRunOutputJSON {
+				return errors.Errorf("unsupported output format %s, support: [%s, %s]", o.output, dryRunOutputYAML, dryRunOutputJSON)
 			}
-
 			app, err := readApplicationFromFile(o.applicationFile)
 			if err != nil {
 				return errors.WithMessagef(err, "read application file: %s", o.applicationFile)
 			}
 
-			parser := appfile.NewApplicationParser(newClient, dm)
+			var newClient client.Client
+			if o.definitionDir == "" || o.diff {
+				newClient, err = client.New(c.Config, client.Options{Scheme: c.Schema})
+				if err != nil {
+					return err
+				}
+			}
+			if o.diff && app.Namespace == "" {
+				env, err := GetEnv(cmd)
+				if err != nil {
+					return err
+				}
+				app.Namespace = env.Namespace
+			}
+
+			var parser *appfile.Parser
+			if o.definitionDir != "" {
+				loader, err := dryrun.NewLocalDefinitionLoader(o.definitionDir)
+				if err != nil {
+					return errors.WithMessagef(err, "load definitions from %s", o.definitionDir)
+				}
+				parser = appfile.NewOfflineParser(loader)
+			} else {
+				dm, err := discoverymapper.New(c.Config)
+				if err != nil {
+					return err
+				}
+				parser = appfile.NewApplicationParser(newClient, dm)
+			}
 
 			appFile, err := parser.GenerateAppFile(app.Name, app)
 			if err != nil {
@@ -62,25 +97,59 @@ func NewDryRunCommand(c types.Args, ioStreams cmdutil.IOStreams) *cobra.Command
 				return errors.WithMessage(err, "generate OAM objects")
 			}
 
+			if o.diff {
+				diffs, err := dryrun.NewLiveDiffer(newClient).Diff(context.Background(), app, ac, comps)
+				if err != nil {
+					return errors.WithMessage(err, "diff with live resources")
+				}
+				return o.printDiffs(diffs)
+			}
+
 			var outs = []interface{}{ac}
 			for index := range comps {
 				outs = append(outs, comps[index])
 			}
-
-			result, err := yaml.Marshal(outs)
-			if err != nil {
-				return errors.WithMessage(err, "marshal result object in yaml format")
-			}
-			o.Info(string(result))
-			return nil
+			return o.print(outs)
 		},
 	}
 
 	cmd.Flags().StringVarP(&o.applicationFile, "file", "f", "./app.yaml", "application file name")
+	cmd.Flags().StringVarP(&o.definitionDir, "definitions", "d", "", "the directory of local definition files, render the application without a cluster")
+	cmd.Flags().BoolVar(&o.diff, "diff", false, "compare the rendered resources with the live ones in the cluster")
+	cmd.Flags().StringVarP(&o.output, "output", "o", dryRunOutputYAML, "output format, support: [yaml, json]")
 	cmd.SetOut(ioStreams.Out)
 	return cmd
 }
 
+func (o *dryRunOptions) print(v interface{}) error {
+	var result []byte
+	var err error
+	if o.output == dryRunOutputJSON {
+		result, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		result, err = yaml.Marshal(v)
+	}
+	if err != nil {
+		return errors.WithMessagef(err, "marshal result object in %s format", o.output)
+	}
+	o.Info(string(result))
+	return nil
+}
+
+// printDiffs prints the diffs in json for CI gating, or a readable summary of every changed resource
+func (o *dryRunOptions) printDiffs(diffs []dryrun.ResourceDiff) error {
+	if o.output == dryRunOutputJSON {
+		return o.print(diffs)
+	}
+	for _, d := range diffs {
+		o.Info(d.String())
+		if d.Diff != "" {
+			o.Info(d.Diff)
+		}
+	}
+	return nil
+}
+
 func readApplicationFromFile(filename string) (*corev1alpha2.Application, error) {
 
 	fileContent, err := ioutil.ReadFile(filepath.Clean(filename))
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	cmdutil "github.com/oam-dev/kubevela/pkg/commands/util"
+	"github.com/oam-dev/kubevela/pkg/serverlib"
+)
+
+// FlagRevision is the flag name of revision
+const FlagRevision = "revision"
+
+// NewRollbackCommand rollback an application to a former revision
+func NewRollbackCommand(c types.Args, ioStreams cmdutil.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "rollback APP_NAME",
+		DisableFlagsInUseLine: true,
+		Short:                 "Rollback an application to a former revision",
+		Long:                  "Rollback an application to a former revision recorded in ApplicationRevision",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.SetConfig()
+		},
+		Annotations: map[string]string{
+			types.TagCommandType: types.TypeApp,
+		},
+		Example: "vela rollback frontend --revision 1",
+	}
+	cmd.SetOut(ioStreams.Out)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("must specify name for the app")
+		}
+		appName := args[0]
+		revision, err := cmd.Flags().GetInt64(FlagRevision)
+		if err != nil {
+			return err
+		}
+		if revision <= 0 {
+			return errors.New("must specify a positive revision to rollback to")
+		}
+		env, err := GetEnv(cmd)
+		if err != nil {
+			return err
+		}
+		newClient, err := client.New(c.Config, client.Options{Scheme: c.Schema})
+		if err != nil {
+			return err
+		}
+		if err := serverlib.RollbackApplication(context.Background(), newClient, appName, env.Namespace, revision); err != nil {
+			return err
+		}
+		ioStreams.Infof("Application \"%s\" rolled back to revision %d\n", appName, revision)
+		return nil
+	}
+	cmd.Flags().Int64P(FlagRevision, "r", 0, "the revision of the application to rollback to")
+	return cmd
+}
@@ -12,11 +12,14 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/apis/types"
 	cmdutil "github.com/oam-dev/kubevela/pkg/commands/util"
 	"github.com/oam-dev/kubevela/pkg/plugins"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
 	"github.com/oam-dev/kubevela/pkg/utils/system"
 )
 
@@ -315,6 +318,15 @@ func getWorkloadsAndTraits(capabilities []types.Capability) ([]string, []string)
 }
 
 func showReferenceConsole(ctx context.Context, c types.Args, ioStreams cmdutil.IOStreams, capabilityName string) error {
+	// the schema stored by the controller is preferred, the local synced definition is used
+	// if it can't be read for whatever reason, e.g. no permission or an old controller
+	schema, err := getDefinitionSchema(ctx, c, capabilityName)
+	if err == nil && schema != nil {
+		ref := &plugins.ConsoleReference{}
+		printPropertyConsole(ioStreams, ref.GenerateSchemaProperties(schema))
+		return nil
+	}
+
 	home, err := system.GetVelaHomeDir()
 	if err != nil {
 		return err
@@ -337,10 +349,32 @@ func showReferenceConsole(ctx context.Context, c types.Args, ioStreams cmdutil.I
 	if err != nil {
 		return err
 	}
+	printPropertyConsole(ioStreams, propertyConsole)
+	return nil
+}
+
+// getDefinitionSchema gets the OpenAPI schema of the parameters of the capability stored by the controller
+func getDefinitionSchema(ctx context.Context, c types.Args, capabilityName string) (*openapi3.Schema, error) {
+	newClient, err := client.New(c.Config, client.Options{Scheme: c.Schema})
+	if err != nil {
+		return nil, err
+	}
+	data, err := common.GetDefinitionSchema(ctx, newClient, system.GetDefinitionSchemaNamespace(), capabilityName)
+	if err != nil {
+		return nil, err
+	}
+	schema := &openapi3.Schema{}
+	if err := schema.UnmarshalJSON(data); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI schema of %s: %w", capabilityName, err)
+	}
+	return schema, nil
+}
+
+// printPropertyConsole prints the tables of properties
+func printPropertyConsole(ioStreams cmdutil.IOStreams, propertyConsole []plugins.ConsoleReference) {
 	for _, p := range propertyConsole {
 		ioStreams.Info(p.TableName)
 		p.TableObject.Render()
 		ioStreams.Info("\n")
 	}
-	return nil
 }
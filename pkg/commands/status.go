@@ -17,6 +17,7 @@ import (
 	"github.com/oam-dev/kubevela/pkg/appfile"
 	"github.com/oam-dev/kubevela/pkg/appfile/api"
 	cmdutil "github.com/oam-dev/kubevela/pkg/commands/util"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
 )
 
@@ -140,6 +141,12 @@ func loopCheckStatus(ctx context.Context, c client.Client, ioStreams cmdutil.IOS
 	if err != nil {
 		return err
 	}
+	if remoteApp.Status.RenderError != nil {
+		// none of the services is deployed if the application fails to be rendered
+		ioStreams.Info(red.Sprintf("  %sApplication Failed to Render!", emojiFail))
+		ioStreams.Info(formatRenderError(remoteApp.Status.RenderError))
+		return nil
+	}
 	for _, comp := range remoteApp.Spec.Components {
 		compName := comp.Name
 
@@ -271,6 +278,42 @@ func TrackDeployStatus(ctx context.Context, c client.Client, compName, appName s
 	return compStatusDeploying, "", nil
 }
 
+// formatRenderError formats the render error with a hint of whether the parameters or the template is at fault
+func formatRenderError(re *v1alpha2.ApplicationRenderError) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "    Component: %s\n", re.Component)
+	if re.Trait != "" {
+		fmt.Fprintf(&b, "    Trait: %s\n", re.Trait)
+	}
+	fmt.Fprintf(&b, "    Definition: %s\n", re.Definition)
+	switch re.Reason {
+	case definition.ReasonInvalidParameter:
+		fmt.Fprintf(&b, "    Reason: %s (please check the parameters)\n", re.Reason)
+	case definition.ReasonInvalidTemplate:
+		fmt.Fprintf(&b, "    Reason: %s (please contact the platform team about the definition)\n", re.Reason)
+	default:
+		fmt.Fprintf(&b, "    Reason: %s\n", re.Reason)
+	}
+	fmt.Fprintf(&b, "    Message: %s\n", re.Message)
+	for _, d := range re.Details {
+		if d.Path != "" {
+			fmt.Fprintf(&b, "      - %s: %s\n", d.Path, d.Message)
+		} else {
+			fmt.Fprintf(&b, "      - %s\n", d.Message)
+		}
+		if d.ParameterPath != "" {
+			fmt.Fprintf(&b, "        Parameter: %s\n", d.ParameterPath)
+		}
+		if len(d.Values) > 0 {
+			fmt.Fprintf(&b, "        Conflicting values: %s\n", strings.Join(d.Values, ", "))
+		}
+		if len(d.Positions) > 0 {
+			fmt.Fprintf(&b, "        Positions: %s\n", strings.Join(d.Positions, ", "))
+		}
+	}
+	return b.String()
+}
+
 // trackHealthCheckingStatus will check health status from health scope
 func trackHealthCheckingStatus(ctx context.Context, c client.Client, compName, appName string, env *types.EnvMeta) (CompStatus, HealthStatus, string, error) {
 	app, err := loadRemoteApplication(c, env.Namespace, appName)
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+)
+
+func TestFormatRenderError(t *testing.T) {
+	msg := formatRenderError(&v1alpha2.ApplicationRenderError{
+		Component:  "express-server",
+		Trait:      "scaler",
+		Definition: "scaler",
+		Reason:     definition.ReasonInvalidParameter,
+		Message:    "invalid template of trait scaler after merge with parameter and context",
+		Details: []v1alpha2.RenderErrorDetail{{
+			Message:       "conflicting values int and \"two\" (mismatched types int and string)",
+			Path:          "parameter.replicas",
+			ParameterPath: "replicas",
+			Values:        []string{"int", "\"two\""},
+			Positions:     []string{"template:3:12"},
+		}},
+	})
+	assert.Contains(t, msg, "Trait: scaler\n")
+	assert.Contains(t, msg, "Reason: InvalidParameter (please check the parameters)\n")
+	assert.Contains(t, msg, "      - parameter.replicas: conflicting values")
+	assert.Contains(t, msg, "        Parameter: replicas\n")
+	assert.Contains(t, msg, "        Conflicting values: int, \"two\"\n")
+	assert.Contains(t, msg, "        Positions: template:3:12\n")
+
+	msg = formatRenderError(&v1alpha2.ApplicationRenderError{
+		Component:  "express-server",
+		Definition: "webservice",
+		Reason:     definition.ReasonInvalidTemplate,
+		Message:    "invalid cue template of workload express-server",
+	})
+	assert.NotContains(t, msg, "Trait:")
+	assert.Contains(t, msg, "Reason: InvalidTemplate (please contact the platform team about the definition)\n")
+}
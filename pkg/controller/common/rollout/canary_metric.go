@@ -0,0 +1,187 @@
+package rollout
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common/rollout/metrics"
+)
+
+// the keys in the ConfigMap referred by the templateRef of a canary metric
+const (
+	// MetricTemplateProviderKey is the key of the type of the metrics provider, default is prometheus
+	MetricTemplateProviderKey = "provider"
+	// MetricTemplateAddressKey is the key of the address of the metrics provider
+	MetricTemplateAddressKey = "address"
+	// MetricTemplateQueryKey is the key of the query of the metric, it's a go template which can refer to
+	// {{ .Name }} and {{ .Namespace }} of the target workload and the {{ .Interval }} of the canary metric
+	MetricTemplateQueryKey = "query"
+)
+
+// the default window size of a canary metric
+const defaultMetricInterval = "1m"
+
+// metricQueryArgs are the values that can be referred in the query of a canary metric
+type metricQueryArgs struct {
+	Name      string
+	Namespace string
+	Interval  string
+}
+
+// checkCanaryMetrics queries all the canary metrics and records their values in the status.
+// It returns true only if all of them are in range, the rollout fails if any of them is out of range
+// and it retries later if any of them can't be observed yet.
+// A metric is only queried after its interval has elapsed since the batch is available,
+// so that its window only covers the time when the pods of the batch are serving.
+func (r *Controller) checkCanaryMetrics(ctx context.Context, canaryMetrics []v1alpha1.CanaryMetric) bool {
+	if len(canaryMetrics) > 0 && r.rolloutStatus.BatchAvailableTime == nil {
+		// the time is not recorded by an earlier version, count from now on
+		r.rolloutStatus.BatchAvailableTime = &metav1.Time{Time: time.Now()}
+	}
+	for _, metric := range canaryMetrics {
+		metricStatus := v1alpha1.CanaryMetricStatus{Name: metric.Name, LastCheckTime: metav1.Now()}
+		interval, err := time.ParseDuration(metricInterval(metric))
+		if err != nil {
+			err = fmt.Errorf("invalid interval %s: %w", metric.Interval, err)
+			metricStatus.Message = err.Error()
+			r.setCanaryMetricStatus(metricStatus)
+			r.recorder.Event(r.parentController, event.Warning("Invalid canary metric", err))
+			r.rolloutStatus.RolloutFailed(fmt.Sprintf("invalid canary metric %s: %s", metric.Name, err))
+			return false
+		}
+		if elapsed := time.Since(r.rolloutStatus.BatchAvailableTime.Time); elapsed < interval {
+			metricStatus.Message = fmt.Sprintf("waiting for the interval %s to elapse since the batch is available", interval)
+			r.setCanaryMetricStatus(metricStatus)
+			klog.InfoS("the canary metric is waiting for its interval", "metric", metric.Name, "elapsed", elapsed)
+			return false
+		}
+		value, err := r.queryCanaryMetric(ctx, metric)
+		if err != nil {
+			klog.ErrorS(err, "failed to query the canary metric", "metric", metric.Name)
+			metricStatus.Message = err.Error()
+			r.setCanaryMetricStatus(metricStatus)
+			r.rolloutStatus.RolloutRetry(fmt.Sprintf("failed to query the canary metric %s: %s", metric.Name, err))
+			return false
+		}
+		metricStatus.Value = strconv.FormatFloat(value, 'f', -1, 64)
+		inRange, err := isMetricInRange(value, metric.MetricsRange)
+		if err != nil {
+			metricStatus.Message = err.Error()
+			r.setCanaryMetricStatus(metricStatus)
+			r.recorder.Event(r.parentController, event.Warning("Invalid canary metric", err))
+			r.rolloutStatus.RolloutFailed(fmt.Sprintf("invalid canary metric %s: %s", metric.Name, err))
+			return false
+		}
+		metricStatus.InRange = inRange
+		r.setCanaryMetricStatus(metricStatus)
+		if !inRange {
+			err = fmt.Errorf("the value of canary metric %s is out of range, value = %s", metric.Name, metricStatus.Value)
+			r.recorder.Event(r.parentController, event.Warning("Canary metric out of range", err))
+			r.rolloutStatus.RolloutFailed(err.Error())
+			return false
+		}
+		klog.InfoS("the canary metric is in range", "metric", metric.Name, "value", metricStatus.Value)
+	}
+	return true
+}
+
+// queryCanaryMetric renders the query in the metric template and runs it by the metrics provider
+func (r *Controller) queryCanaryMetric(ctx context.Context, metric v1alpha1.CanaryMetric) (float64, error) {
+	if metric.TemplateRef == nil {
+		return 0, fmt.Errorf("the template of the canary metric is not specified")
+	}
+	if metric.TemplateRef.Kind != "ConfigMap" {
+		return 0, fmt.Errorf("the template of the canary metric has to be a ConfigMap, kind = %s",
+			metric.TemplateRef.Kind)
+	}
+	cm := corev1.ConfigMap{}
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: r.parentController.GetNamespace(),
+		Name: metric.TemplateRef.Name}, &cm); err != nil {
+		return 0, err
+	}
+	provider, err := metrics.NewProvider(cm.Data[MetricTemplateProviderKey], cm.Data[MetricTemplateAddressKey])
+	if err != nil {
+		return 0, err
+	}
+	tmpl, err := template.New(metric.Name).Option("missingkey=error").Parse(cm.Data[MetricTemplateQueryKey])
+	if err != nil {
+		return 0, fmt.Errorf("invalid query of the canary metric: %w", err)
+	}
+	query := bytes.Buffer{}
+	if err := tmpl.Execute(&query, metricQueryArgs{
+		Name:      r.targetWorkload.GetName(),
+		Namespace: r.targetWorkload.GetNamespace(),
+		Interval:  metricInterval(metric),
+	}); err != nil {
+		return 0, fmt.Errorf("invalid query of the canary metric: %w", err)
+	}
+	return provider.RunQuery(ctx, query.String())
+}
+
+// metricInterval returns the window size of the canary metric
+func metricInterval(metric v1alpha1.CanaryMetric) string {
+	if metric.Interval == "" {
+		return defaultMetricInterval
+	}
+	return metric.Interval
+}
+
+// setCanaryMetricStatus records the observed value of a canary metric
+func (r *Controller) setCanaryMetricStatus(metricStatus v1alpha1.CanaryMetricStatus) {
+	for i, s := range r.rolloutStatus.CanaryMetricStatus {
+		if s.Name == metricStatus.Name {
+			r.rolloutStatus.CanaryMetricStatus[i] = metricStatus
+			return
+		}
+	}
+	r.rolloutStatus.CanaryMetricStatus = append(r.rolloutStatus.CanaryMetricStatus, metricStatus)
+}
+
+// isMetricInRange checks if the value is not less than the min and not greater than the max of the range
+func isMetricInRange(value float64, metricsRange *v1alpha1.MetricsExpectedRange) (bool, error) {
+	if metricsRange == nil {
+		return true, nil
+	}
+	if metricsRange.Min != nil {
+		min, err := parseMetricBound(metricsRange.Min)
+		if err != nil {
+			return false, err
+		}
+		if value < min {
+			return false, nil
+		}
+	}
+	if metricsRange.Max != nil {
+		max, err := parseMetricBound(metricsRange.Max)
+		if err != nil {
+			return false, err
+		}
+		if value > max {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func parseMetricBound(bound *intstr.IntOrString) (float64, error) {
+	if bound.Type == intstr.Int {
+		return float64(bound.IntVal), nil
+	}
+	value, err := strconv.ParseFloat(bound.StrVal, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bound %s of the metric range: %w", bound.StrVal, err)
+	}
+	return value, nil
+}
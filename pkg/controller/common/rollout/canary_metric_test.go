@@ -0,0 +1,123 @@
+package rollout
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	//lint:ignore SA1019 We will use pkg/envtest before upgrading controller-runtime to v1.0.0
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func TestCheckCanaryMetrics(t *testing.T) {
+	value := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `sum(rate(http_requests_total{namespace="default",name="app-v2"}[2m]))`,
+			r.URL.Query().Get("query"))
+		if value == "" {
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1614240000,"` +
+			value + `"]}]}}`))
+	}))
+	defer ts.Close()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "request-rate", Namespace: "default"},
+		Data: map[string]string{
+			MetricTemplateAddressKey: ts.URL,
+			MetricTemplateQueryKey:   `sum(rate(http_requests_total{namespace="{{ .Namespace }}",name="{{ .Name }}"}[{{ .Interval }}]))`,
+		},
+	}
+	cli := fake.NewFakeClientWithScheme(scheme.Scheme, cm)
+	target := &unstructured.Unstructured{}
+	target.SetName("app-v2")
+	target.SetNamespace("default")
+	max := intstr.FromString("100.5")
+	canaryMetrics := []v1alpha1.CanaryMetric{{
+		Name:         "request-rate",
+		Interval:     "2m",
+		MetricsRange: &v1alpha1.MetricsExpectedRange{Min: &intstr.IntOrString{IntVal: 10}, Max: &max},
+		TemplateRef:  &runtimev1alpha1.TypedReference{APIVersion: "v1", Kind: "ConfigMap", Name: "request-rate"},
+	}}
+	newController := func(available time.Time) *Controller {
+		return NewRolloutPlanController(cli, scheme.Scheme, &v1alpha2.ApplicationDeployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default"}},
+			event.NewNopRecorder(), &v1alpha1.RolloutPlan{}, v1alpha1.RolloutStatus{RollingState: v1alpha1.RollingInBatchesState,
+				BatchAvailableTime: &metav1.Time{Time: available}}, target, nil)
+	}
+	ctx := context.Background()
+
+	// the rollout waits for the interval of the metric to elapse since the batch is available
+	value = "55"
+	r := newController(time.Now().Add(-time.Minute))
+	assert.False(t, r.checkCanaryMetrics(ctx, canaryMetrics))
+	assert.Equal(t, v1alpha1.RollingInBatchesState, r.rolloutStatus.RollingState)
+	assert.Equal(t, 1, len(r.rolloutStatus.CanaryMetricStatus))
+	assert.Empty(t, r.rolloutStatus.CanaryMetricStatus[0].Value)
+	assert.Contains(t, r.rolloutStatus.CanaryMetricStatus[0].Message, "interval")
+
+	// the rollout waits for the metric to be observed
+	value = ""
+	r = newController(time.Now().Add(-3 * time.Minute))
+	assert.False(t, r.checkCanaryMetrics(ctx, canaryMetrics))
+	assert.Equal(t, v1alpha1.RollingInBatchesState, r.rolloutStatus.RollingState)
+	assert.Equal(t, 1, len(r.rolloutStatus.CanaryMetricStatus))
+	assert.NotEmpty(t, r.rolloutStatus.CanaryMetricStatus[0].Message)
+
+	value = "55"
+	assert.True(t, r.checkCanaryMetrics(ctx, canaryMetrics))
+	assert.Equal(t, 1, len(r.rolloutStatus.CanaryMetricStatus))
+	assert.Equal(t, "55", r.rolloutStatus.CanaryMetricStatus[0].Value)
+	assert.True(t, r.rolloutStatus.CanaryMetricStatus[0].InRange)
+	assert.Empty(t, r.rolloutStatus.CanaryMetricStatus[0].Message)
+
+	// the rollout fails if the metric is out of range
+	value = "100.6"
+	assert.False(t, r.checkCanaryMetrics(ctx, canaryMetrics))
+	assert.Equal(t, v1alpha1.RolloutFailedState, r.rolloutStatus.RollingState)
+	assert.False(t, r.rolloutStatus.CanaryMetricStatus[0].InRange)
+
+	// no metric is always passed
+	assert.True(t, newController(time.Now()).checkCanaryMetrics(ctx, nil))
+
+	// the rollout fails if the interval is invalid
+	r = newController(time.Now().Add(-3 * time.Minute))
+	assert.False(t, r.checkCanaryMetrics(ctx, []v1alpha1.CanaryMetric{{Name: "request-rate", Interval: "2 minutes"}}))
+	assert.Equal(t, v1alpha1.RolloutFailedState, r.rolloutStatus.RollingState)
+}
+
+func TestIsMetricInRange(t *testing.T) {
+	min, max, invalid := intstr.FromInt(1), intstr.FromString("2.5"), intstr.FromString("two")
+	cases := map[string]struct {
+		value         float64
+		metricsRange  *v1alpha1.MetricsExpectedRange
+		expectInRange bool
+		expectErr     bool
+	}{
+		"no range":      {value: 100, expectInRange: true},
+		"in range":      {value: 2.5, metricsRange: &v1alpha1.MetricsExpectedRange{Min: &min, Max: &max}, expectInRange: true},
+		"less than min": {value: 0.5, metricsRange: &v1alpha1.MetricsExpectedRange{Min: &min}},
+		"more than max": {value: 2.6, metricsRange: &v1alpha1.MetricsExpectedRange{Max: &max}},
+		"invalid bound": {value: 2, metricsRange: &v1alpha1.MetricsExpectedRange{Max: &invalid}, expectErr: true},
+	}
+	for name, c := range cases {
+		inRange, err := isMetricInRange(c.value, c.metricsRange)
+		assert.Equal(t, c.expectErr, err != nil, name)
+		assert.Equal(t, c.expectInRange, inRange, name)
+	}
+}
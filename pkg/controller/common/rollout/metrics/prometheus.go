@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+)
+
+// the timeout of a query to the Prometheus server
+const prometheusQueryTimeout = 10 * time.Second
+
+// PrometheusProvider queries the metrics by the instant query API of Prometheus
+type PrometheusProvider struct {
+	address url.URL
+	client  *http.Client
+}
+
+// prometheusResponse is the response of the instant query API
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// NewPrometheusProvider creates a provider which queries the Prometheus server at the address
+func NewPrometheusProvider(address string) (Provider, error) {
+	if address == "" {
+		return nil, fmt.Errorf("the address of prometheus is required")
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address of prometheus %s: %w", address, err)
+	}
+	return &PrometheusProvider{address: *u, client: &http.Client{Timeout: prometheusQueryTimeout}}, nil
+}
+
+// RunQuery executes the instant query, the result has to be a scalar or a vector with one sample
+func (p *PrometheusProvider) RunQuery(ctx context.Context, query string) (float64, error) {
+	u := p.address
+	u.Path = path.Join(u.Path, "/api/v1/query")
+	u.RawQuery = url.Values{"query": []string{query}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	r, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = r.Body.Close()
+	}()
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return 0, fmt.Errorf("error reading body: %w", err)
+	}
+
+	resp := prometheusResponse{}
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return 0, fmt.Errorf("invalid response of prometheus, status code = %d: %w", r.StatusCode, err)
+	}
+	if resp.Status != "success" {
+		return 0, fmt.Errorf("prometheus query failed, status code = %d: %s", r.StatusCode, resp.Error)
+	}
+
+	// a sample is a pair of the timestamp and the value in string
+	var sample []interface{}
+	switch resp.Data.ResultType {
+	case "scalar":
+		if err := json.Unmarshal(resp.Data.Result, &sample); err != nil {
+			return 0, err
+		}
+	case "vector":
+		var vector []struct {
+			Value []interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(resp.Data.Result, &vector); err != nil {
+			return 0, err
+		}
+		if len(vector) == 0 {
+			return 0, ErrNoValues
+		}
+		if len(vector) > 1 {
+			return 0, fmt.Errorf("the query returns %d series, it has to return only one", len(vector))
+		}
+		sample = vector[0].Value
+	default:
+		return 0, fmt.Errorf("unsupported result type %s of the query", resp.Data.ResultType)
+	}
+
+	if len(sample) != 2 {
+		return 0, fmt.Errorf("invalid sample %v in the result", sample)
+	}
+	str, ok := sample[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid sample %v in the result", sample)
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) {
+		return 0, ErrNoValues
+	}
+	return value, nil
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusProvider(t *testing.T) {
+	responses := map[string]string{
+		"scalar":   `{"status":"success","data":{"resultType":"scalar","result":[1614240000,"0.5"]}}`,
+		"vector":   `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1614240000,"99.5"]}]}}`,
+		"empty":    `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+		"nan":      `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1614240000,"NaN"]}]}}`,
+		"multiple": `{"status":"success","data":{"resultType":"vector","result":[{"value":[1,"1"]},{"value":[1,"2"]}]}}`,
+		"matrix":   `{"status":"success","data":{"resultType":"matrix","result":[]}}`,
+		"invalid":  `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/prometheus/api/v1/query", r.URL.Path)
+		resp, ok := responses[r.URL.Query().Get("query")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("query") == "invalid" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		_, _ = w.Write([]byte(resp))
+	}))
+	defer ts.Close()
+
+	_, err := NewProvider("datadog", ts.URL)
+	assert.Error(t, err)
+	_, err = NewProvider(ProviderTypePrometheus, "")
+	assert.Error(t, err)
+	provider, err := NewProvider("", ts.URL+"/prometheus")
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	value, err := provider.RunQuery(ctx, "scalar")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, value)
+	value, err = provider.RunQuery(ctx, "vector")
+	assert.NoError(t, err)
+	assert.Equal(t, 99.5, value)
+	_, err = provider.RunQuery(ctx, "empty")
+	assert.Equal(t, ErrNoValues, err)
+	_, err = provider.RunQuery(ctx, "nan")
+	assert.Equal(t, ErrNoValues, err)
+	for _, query := range []string{"multiple", "matrix", "invalid", "unknown"} {
+		_, err = provider.RunQuery(ctx, query)
+		assert.Error(t, err, query)
+	}
+}
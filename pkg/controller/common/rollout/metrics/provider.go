@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ProviderTypePrometheus queries the metrics by the HTTP API of Prometheus
+const ProviderTypePrometheus = "prometheus"
+
+// ErrNoValues is returned when the query result has no values, e.g. there is no traffic yet
+var ErrNoValues = errors.New("no values found")
+
+// Provider queries the value of a canary metric from a metrics server
+type Provider interface {
+	// RunQuery executes the query and returns its result as a single number
+	RunQuery(ctx context.Context, query string) (float64, error)
+}
+
+var providers = map[string]func(address string) (Provider, error){
+	ProviderTypePrometheus: NewPrometheusProvider,
+}
+
+// NewProvider creates the provider of the type which queries the metrics server at the address,
+// prometheus is used if the type is not specified
+func NewProvider(providerType, address string) (Provider, error) {
+	if providerType == "" {
+		providerType = ProviderTypePrometheus
+	}
+	newProvider, ok := providers[providerType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported metrics provider %s", providerType)
+	}
+	return newProvider(address)
+}
@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/event"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/klog/v2"
@@ -25,6 +27,7 @@ const rolloutReconcileRequeueTime = 5 * time.Second
 // Controller is the controller that controls the rollout plan resource
 type Controller struct {
 	client           client.Client
+	scheme           *runtime.Scheme
 	recorder         event.Recorder
 	parentController oam.Object
 
@@ -36,12 +39,13 @@ type Controller struct {
 }
 
 // NewRolloutPlanController creates a RolloutPlanController
-func NewRolloutPlanController(client client.Client, parentController oam.Object, recorder event.Recorder,
+func NewRolloutPlanController(client client.Client, scheme *runtime.Scheme, parentController oam.Object, recorder event.Recorder,
 	rolloutSpec *v1alpha1.RolloutPlan,
 	rolloutStatus v1alpha1.RolloutStatus, targetWorkload,
 	sourceWorkload *unstructured.Unstructured) *Controller {
 	return &Controller{
 		client:           client,
+		scheme:           scheme,
 		parentController: parentController,
 		recorder:         recorder,
 		rolloutSpec:      rolloutSpec,
@@ -92,14 +96,24 @@ func (r *Controller) Reconcile(ctx context.Context) (res reconcile.Result, statu
 		status = *workloadController.Verify(ctx)
 
 	case v1alpha1.InitializingState:
-		// TODO: call the pre-rollout webhooks
+		// the rollout can't start until all the initialize webhooks have succeeded
+		if !r.callWebhooks(v1alpha1.InitializeRolloutHook, r.rolloutSpec.RolloutWebhooks) {
+			status = r.rolloutStatus
+			break
+		}
 		status = *workloadController.Initialize(ctx)
 
 	case v1alpha1.RollingInBatchesState:
 		status = r.reconcileBatchInRolling(ctx, workloadController)
 
 	case v1alpha1.FinalisingState:
-		// TODO: call the post-rollout webhooks
+		// the rollout can't complete until all the canary metrics of the rollout plan are in range
+		// and all the finalize webhooks have succeeded
+		if !r.checkCanaryMetrics(ctx, r.rolloutSpec.CanaryMetric) ||
+			!r.callWebhooks(v1alpha1.FinalizeRolloutHook, r.rolloutSpec.RolloutWebhooks) {
+			status = r.rolloutStatus
+			break
+		}
 		status = *workloadController.Finalize(ctx)
 
 	case v1alpha1.RolloutSucceedState:
@@ -135,7 +149,12 @@ func (r *Controller) reconcileBatchInRolling(ctx context.Context, workloadContro
 
 	switch r.rolloutStatus.BatchRollingState {
 	case v1alpha1.BatchInitializingState:
-		// TODO:  call the pre-batch webhook
+		// the batch can't start until all the pre-batch webhooks have succeeded
+		currentBatch := r.rolloutSpec.RolloutBatches[r.rolloutStatus.CurrentBatch]
+		if r.callWebhooks(v1alpha1.PreBatchRolloutHook, currentBatch.BatchRolloutWebhooks) {
+			r.rolloutStatus.StateTransition(v1alpha1.InitializedOneBatchEvent)
+		}
+		status = r.rolloutStatus
 
 	case v1alpha1.BatchInRollingState:
 		//  still rolling the batch, the batch rolling is not completed yet
@@ -144,17 +163,29 @@ func (r *Controller) reconcileBatchInRolling(ctx context.Context, workloadContro
 	case v1alpha1.BatchVerifyingState:
 		// verifying if the application is ready to roll
 		// need to check if they meet the availability requirements in the rollout spec.
-		// TODO: evaluate any metrics/analysis
 		status = *workloadController.CheckOneBatchPods(ctx)
+		if status.BatchRollingState == v1alpha1.BatchFinalizingState {
+			// the canary metrics of the batch are observed from now on
+			r.rolloutStatus.BatchAvailableTime = &metav1.Time{Time: time.Now()}
+			status = r.rolloutStatus
+		}
 
 	case v1alpha1.BatchFinalizingState:
 		// all the pods in the are available
-		r.finalizeOneBatch()
+		// the batch can't finish until all the canary metrics of the batch are in range
+		// and all the post-batch webhooks have succeeded
+		currentBatch := r.rolloutSpec.RolloutBatches[r.rolloutStatus.CurrentBatch]
+		if r.checkCanaryMetrics(ctx, currentBatch.CanaryMetric) &&
+			r.callWebhooks(v1alpha1.PostBatchRolloutHook, currentBatch.BatchRolloutWebhooks) {
+			r.finalizeOneBatch()
+		}
+		status = r.rolloutStatus
 
 	case v1alpha1.BatchReadyState:
 		// all the pods in the are upgraded and their state are ready
 		// wait to move to the next batch if there are any
 		r.tryMovingToNextBatch()
+		status = r.rolloutStatus
 
 	default:
 		panic(fmt.Sprintf("illegal status %+v", r.rolloutStatus))
@@ -168,6 +199,9 @@ func (r *Controller) tryMovingToNextBatch() {
 	if r.rolloutSpec.BatchPartition == nil || *r.rolloutSpec.BatchPartition > r.rolloutStatus.CurrentBatch {
 		klog.InfoS("ready to rollout the next batch", "current batch", r.rolloutStatus.CurrentBatch)
 		r.rolloutStatus.CurrentBatch++
+		// the canary metrics are checked again for the next batch
+		r.rolloutStatus.CanaryMetricStatus = nil
+		r.rolloutStatus.BatchAvailableTime = nil
 		r.rolloutStatus.StateTransition(v1alpha1.BatchRolloutApprovedEvent)
 	} else {
 		klog.V(common.LogDebug).InfoS("the current batch is waiting to move on", "current batch",
@@ -176,10 +210,11 @@ func (r *Controller) tryMovingToNextBatch() {
 }
 
 func (r *Controller) finalizeOneBatch() {
-	// TODO: call the post-batch webhooks if there are any
 	currentBatch := int(r.rolloutStatus.CurrentBatch)
 	if currentBatch == len(r.rolloutSpec.RolloutBatches)-1 {
 		// this is the last batch, mark the rollout finalized
+		// the canary metrics of the rollout plan are observed since the last batch is available
+		r.rolloutStatus.CanaryMetricStatus = nil
 		r.rolloutStatus.StateTransition(v1alpha1.AllBatchFinishedEvent)
 		r.recorder.Event(r.parentController, event.Normal("all batches rolled out",
 			fmt.Sprintf("upgrade pod = %d, total ready pod = %d", r.rolloutStatus.UpgradedReplicas,
@@ -249,6 +284,22 @@ func (r *Controller) GetWorkloadController() (workloads.WorkloadController, erro
 		return workloads.NewCloneSetController(r.client, r.recorder, r.parentController,
 			r.rolloutSpec, &r.rolloutStatus, target), nil
 
+	case "Deployment":
+		// deployments have no partition, the pods are moved from the source deployment to the target one
+		var source types.NamespacedName
+		if r.sourceWorkload != nil {
+			source = types.NamespacedName{
+				Namespace: r.sourceWorkload.GetNamespace(),
+				Name:      r.sourceWorkload.GetName(),
+			}
+		}
+		return workloads.NewDeploymentController(r.client, r.recorder, r.parentController,
+			r.rolloutSpec, &r.rolloutStatus, source, target), nil
+
+	case "StatefulSet":
+		return workloads.NewStatefulSetController(r.client, r.recorder, r.parentController,
+			r.rolloutSpec, &r.rolloutStatus, target), nil
+
 	default:
 		return nil, fmt.Errorf("the workload kind `%s` is not supported", kind)
 	}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -12,9 +11,23 @@ import (
 	"strconv"
 	"time"
 
+	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
 )
 
+const (
+	// the timeout of a webhook if it's not specified
+	defaultWebhookTimeout = "10s"
+	// the number of times a failed webhook is retried if it's not specified
+	defaultWebhookRetries = 3
+)
+
 func callWebhook(webhook string, payload interface{}, timeout string) error {
 	payloadBin, err := json.Marshal(payload)
 	if err != nil {
@@ -34,7 +47,7 @@ func callWebhook(webhook string, payload interface{}, timeout string) error {
 	req.Header.Set("Content-Type", "application/json")
 
 	if timeout == "" {
-		timeout = "10s"
+		timeout = defaultWebhookTimeout
 	}
 
 	t, err := time.ParseDuration(timeout)
@@ -58,26 +71,30 @@ func callWebhook(webhook string, payload interface{}, timeout string) error {
 		return fmt.Errorf("error reading body: %w", err)
 	}
 
-	if r.StatusCode > 202 {
-		return errors.New(string(b))
+	if r.StatusCode < 200 || r.StatusCode >= 300 {
+		return fmt.Errorf("the webhook returns status code %d: %s", r.StatusCode, string(b))
 	}
 
 	return nil
 }
 
-// CallWebhook does a HTTP POST to an external service and
-// returns an error if the response status code is non-2xx
-func CallWebhook(name string, namespace string, phase v1alpha1.RollingState, w v1alpha1.RolloutWebhook) error {
-	payload := v1alpha1.RolloutWebhookPayload{}
+// CallWebhook does a HTTP POST to an external service with the references to the workload and the rollout,
+// and returns an error if the response status code is non-2xx. The batch is only sent to the batch webhooks.
+func CallWebhook(resourceRef, rolloutRef *runtimev1alpha1.TypedReference, w v1alpha1.RolloutWebhook,
+	batch int32) error {
+	payload := v1alpha1.RolloutWebhookPayload{
+		Type:        w.Type,
+		ResourceRef: resourceRef,
+		RolloutRef:  rolloutRef,
+	}
+	if w.Type == v1alpha1.PreBatchRolloutHook || w.Type == v1alpha1.PostBatchRolloutHook {
+		payload.Batch = &batch
+	}
 
 	if w.Metadata != nil {
 		payload.Metadata = *w.Metadata
 	}
 
-	if len(w.Timeout) < 2 {
-		w.Timeout = "10s"
-	}
-
 	return callWebhook(w.URL, payload, w.Timeout)
 }
 
@@ -95,3 +112,91 @@ func CallEventWebhook(r *v1alpha1.RolloutTrait, webhookURL, message, eventtype s
 
 	return callWebhook(webhookURL, payload, "5s")
 }
+
+// callWebhooks calls the webhooks of the hook type one by one and records their results in the status.
+// It returns true only if all of them have succeeded. A failed webhook blocks the rollout and is called again
+// in the next reconcile, the rollout fails after the webhook has been retried for `retries` times
+func (r *Controller) callWebhooks(hookType v1alpha1.HookType, webhooks []v1alpha1.RolloutWebhook) bool {
+	var batch int32
+	if hookType == v1alpha1.PreBatchRolloutHook || hookType == v1alpha1.PostBatchRolloutHook {
+		batch = r.rolloutStatus.CurrentBatch
+	}
+	for _, w := range webhooks {
+		if w.Type != hookType {
+			continue
+		}
+		hookStatus := r.getWebhookStatus(hookType, w.Name, batch)
+		if hookStatus.Succeeded {
+			// the webhooks are only called once at each hook point
+			continue
+		}
+		err := CallWebhook(r.getResourceRef(), r.getRolloutRef(), w, batch)
+		hookStatus.Attempts++
+		hookStatus.LastCallTime = metav1.Now()
+		if err == nil {
+			klog.InfoS("called the rollout webhook", "type", hookType, "name", w.Name, "batch", batch)
+			hookStatus.Succeeded = true
+			hookStatus.Message = ""
+			r.setWebhookStatus(hookStatus)
+			r.recorder.Event(r.parentController, event.Normal("Webhook succeeded",
+				fmt.Sprintf("the %s webhook %s succeeded", hookType, w.Name)))
+			continue
+		}
+		klog.ErrorS(err, "failed to call the rollout webhook", "type", hookType, "name", w.Name, "batch", batch,
+			"attempts", hookStatus.Attempts)
+		hookStatus.Message = err.Error()
+		r.setWebhookStatus(hookStatus)
+		retries := int32(defaultWebhookRetries)
+		if w.Retries != nil {
+			retries = *w.Retries
+		}
+		if hookStatus.Attempts > retries {
+			err = fmt.Errorf("the %s webhook %s failed after %d attempts: %w", hookType, w.Name,
+				hookStatus.Attempts, err)
+			r.recorder.Event(r.parentController, event.Warning("Webhook failed", err))
+			r.rolloutStatus.RolloutFailed(err.Error())
+			return false
+		}
+		r.rolloutStatus.RolloutRetry(fmt.Sprintf("the %s webhook %s failed: %s", hookType, w.Name, err))
+		return false
+	}
+	return true
+}
+
+// getWebhookStatus returns the result of the webhook recorded in the status, it's empty if the webhook isn't called
+func (r *Controller) getWebhookStatus(hookType v1alpha1.HookType, name string,
+	batch int32) v1alpha1.RolloutWebhookStatus {
+	for _, s := range r.rolloutStatus.RolloutWebhookStatus {
+		if s.Type == hookType && s.Name == name && s.Batch == batch {
+			return s
+		}
+	}
+	return v1alpha1.RolloutWebhookStatus{Type: hookType, Name: name, Batch: batch}
+}
+
+// setWebhookStatus records the result of the webhook in the status
+func (r *Controller) setWebhookStatus(hookStatus v1alpha1.RolloutWebhookStatus) {
+	for i, s := range r.rolloutStatus.RolloutWebhookStatus {
+		if s.Type == hookStatus.Type && s.Name == hookStatus.Name && s.Batch == hookStatus.Batch {
+			r.rolloutStatus.RolloutWebhookStatus[i] = hookStatus
+			return
+		}
+	}
+	r.rolloutStatus.RolloutWebhookStatus = append(r.rolloutStatus.RolloutWebhookStatus, hookStatus)
+}
+
+// getResourceRef returns the reference to the target workload
+func (r *Controller) getResourceRef() *runtimev1alpha1.TypedReference {
+	return meta.TypedReferenceTo(r.targetWorkload, r.targetWorkload.GroupVersionKind())
+}
+
+// getRolloutRef returns the reference to the resource that controls the rollout, its GVK is looked up in the scheme
+// as the TypeMeta of a typed object read by the client is empty
+func (r *Controller) getRolloutRef() *runtimev1alpha1.TypedReference {
+	gvk, err := apiutil.GVKForObject(r.parentController, r.scheme)
+	if err != nil {
+		klog.ErrorS(err, "cannot get the GVK of the rollout", "rollout", klog.KObj(r.parentController))
+		gvk = r.parentController.GetObjectKind().GroupVersionKind()
+	}
+	return meta.TypedReferenceTo(r.parentController, gvk)
+}
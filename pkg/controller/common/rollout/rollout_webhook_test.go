@@ -0,0 +1,105 @@
+package rollout
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+
+	core "github.com/oam-dev/kubevela/apis/core.oam.dev"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func TestCallWebhooks(t *testing.T) {
+	calls := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls[r.URL.Path]++
+		body, _ := ioutil.ReadAll(r.Body)
+		payload := v1alpha1.RolloutWebhookPayload{}
+		assert.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, "app-v2", payload.ResourceRef.Name)
+		assert.Equal(t, "CloneSet", payload.ResourceRef.Kind)
+		assert.Equal(t, "test-rollout", payload.RolloutRef.Name)
+		assert.Equal(t, "ApplicationDeployment", payload.RolloutRef.Kind)
+		assert.Equal(t, "core.oam.dev/v1alpha2", payload.RolloutRef.APIVersion)
+		switch r.URL.Path {
+		case "/approve":
+			assert.Equal(t, map[string]string{"env": "test"}, payload.Metadata)
+			assert.Equal(t, v1alpha1.PreBatchRolloutHook, payload.Type)
+			assert.Equal(t, pointer.Int32Ptr(1), payload.Batch)
+			w.WriteHeader(http.StatusOK)
+		case "/slow":
+			assert.Equal(t, v1alpha1.FinalizeRolloutHook, payload.Type)
+			assert.Nil(t, payload.Batch)
+			time.Sleep(500 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	target := &unstructured.Unstructured{}
+	target.SetAPIVersion("apps.kruise.io/v1alpha1")
+	target.SetKind("CloneSet")
+	target.SetName("app-v2")
+	// the TypeMeta of a typed object read by the client is empty, the GVK of the rollout is got from the scheme
+	parent := &v1alpha2.ApplicationDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-rollout"},
+	}
+	scheme := runtime.NewScheme()
+	assert.NoError(t, core.AddToScheme(scheme))
+	newController := func() *Controller {
+		return NewRolloutPlanController(nil, scheme, parent, event.NewNopRecorder(), &v1alpha1.RolloutPlan{},
+			v1alpha1.RolloutStatus{RollingState: v1alpha1.RollingInBatchesState, CurrentBatch: 1}, target, nil)
+	}
+	metadata := map[string]string{"env": "test"}
+	approve := v1alpha1.RolloutWebhook{Type: v1alpha1.PreBatchRolloutHook, Name: "approve", URL: ts.URL + "/approve",
+		Metadata: &metadata}
+
+	// the webhooks of the other types are ignored, and a webhook succeeded is not called again
+	r := newController()
+	webhooks := []v1alpha1.RolloutWebhook{approve,
+		{Type: v1alpha1.PostBatchRolloutHook, Name: "reject", URL: ts.URL + "/reject"}}
+	assert.True(t, r.callWebhooks(v1alpha1.PreBatchRolloutHook, webhooks))
+	assert.True(t, r.callWebhooks(v1alpha1.PreBatchRolloutHook, webhooks))
+	assert.Equal(t, 1, calls["/approve"])
+	assert.Equal(t, 0, calls["/reject"])
+	assert.Equal(t, 1, len(r.rolloutStatus.RolloutWebhookStatus))
+	hookStatus := r.rolloutStatus.RolloutWebhookStatus[0]
+	assert.Equal(t, v1alpha1.PreBatchRolloutHook, hookStatus.Type)
+	assert.Equal(t, int32(1), hookStatus.Batch)
+	assert.Equal(t, int32(1), hookStatus.Attempts)
+	assert.True(t, hookStatus.Succeeded)
+
+	// a failed webhook blocks the rollout, and fails it after the retries
+	r = newController()
+	webhooks = []v1alpha1.RolloutWebhook{{Type: v1alpha1.PostBatchRolloutHook, Name: "reject", URL: ts.URL + "/reject",
+		Retries: pointer.Int32Ptr(1)}, approve}
+	assert.False(t, r.callWebhooks(v1alpha1.PostBatchRolloutHook, webhooks))
+	assert.Equal(t, v1alpha1.RollingInBatchesState, r.rolloutStatus.RollingState)
+	assert.False(t, r.rolloutStatus.RolloutWebhookStatus[0].Succeeded)
+	assert.Contains(t, r.rolloutStatus.RolloutWebhookStatus[0].Message, "500")
+	assert.False(t, r.callWebhooks(v1alpha1.PostBatchRolloutHook, webhooks))
+	assert.Equal(t, v1alpha1.RolloutFailedState, r.rolloutStatus.RollingState)
+	assert.Equal(t, int32(2), r.rolloutStatus.RolloutWebhookStatus[0].Attempts)
+	assert.Equal(t, 2, calls["/reject"])
+	assert.Equal(t, 1, calls["/approve"])
+
+	// the webhook fails if it doesn't respond in time
+	r = newController()
+	webhooks = []v1alpha1.RolloutWebhook{{Type: v1alpha1.FinalizeRolloutHook, Name: "slow", URL: ts.URL + "/slow",
+		Timeout: "100ms"}}
+	assert.False(t, r.callWebhooks(v1alpha1.FinalizeRolloutHook, webhooks))
+	assert.Equal(t, int32(0), r.rolloutStatus.RolloutWebhookStatus[0].Batch)
+	assert.NotEmpty(t, r.rolloutStatus.RolloutWebhookStatus[0].Message)
+}
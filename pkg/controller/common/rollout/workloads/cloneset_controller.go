@@ -201,17 +201,7 @@ The functions below are helper functions
 --------------------- */
 // check if the replicas in all the rollout batches add up to the right number
 func (c *CloneSetController) verifyBatchSizes(totalReplicas int32) error {
-	// the target size has to be the same as the cloneset size
-	if c.rolloutSpec.TargetSize != nil && *c.rolloutSpec.TargetSize != totalReplicas {
-		return fmt.Errorf("the rollout plan is attempting to scale the cloneset, target = %d, cloneset size = %d",
-			*c.rolloutSpec.TargetSize, totalReplicas)
-	}
-	// use a common function to check if the sum of all the batches can match the cloneset size
-	err := VerifySumOfBatchSizes(c.rolloutSpec, totalReplicas)
-	if err != nil {
-		return err
-	}
-	return nil
+	return verifyBatchSizes(c.rolloutSpec, "cloneset", totalReplicas)
 }
 
 func (c *CloneSetController) fetchCloneSet(ctx context.Context) error {
@@ -230,24 +220,5 @@ func (c *CloneSetController) fetchCloneSet(ctx context.Context) error {
 }
 
 func (c *CloneSetController) calculateNewPodTarget(cloneSetSize int) int {
-	currentBatch := int(c.rolloutStatus.CurrentBatch)
-	newPodTarget := 0
-	if currentBatch == len(c.rolloutSpec.RolloutBatches)-1 {
-		// special handle the last batch, we ignore the rest of the batch in case there are rounding errors
-		klog.InfoS("use the cloneset size as the total pod target for the last rolling batch",
-			"current batch", currentBatch, "new version pod target", newPodTarget)
-		newPodTarget = cloneSetSize
-	} else {
-		for i, r := range c.rolloutSpec.RolloutBatches {
-			batchSize, _ := intstr.GetValueFromIntOrPercent(&r.Replicas, cloneSetSize, true)
-			if i <= currentBatch {
-				newPodTarget += batchSize
-			} else {
-				break
-			}
-		}
-		klog.InfoS("Calculated the number of new version pod", "current batch", currentBatch,
-			"new version pod target", newPodTarget)
-	}
-	return newPodTarget
+	return calculateNewPodTarget(c.rolloutSpec, int(c.rolloutStatus.CurrentBatch), cloneSetSize)
 }
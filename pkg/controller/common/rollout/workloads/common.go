@@ -1,9 +1,12 @@
 package workloads
 
 import (
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
 
 	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
 )
@@ -37,3 +40,61 @@ func VerifySumOfBatchSizes(rolloutSpec *v1alpha1.RolloutPlan, totalReplicas int3
 	}
 	return nil
 }
+
+// verifyBatchSizes checks that the rollout plan doesn't scale the workload and the replicas in all the rollout
+// batches add up to the right number
+func verifyBatchSizes(rolloutSpec *v1alpha1.RolloutPlan, kind string, totalReplicas int32) error {
+	// the target size has to be the same as the workload size
+	if rolloutSpec.TargetSize != nil && *rolloutSpec.TargetSize != totalReplicas {
+		return fmt.Errorf("the rollout plan is attempting to scale the %s, target = %d, %s size = %d",
+			kind, *rolloutSpec.TargetSize, kind, totalReplicas)
+	}
+	// use a common function to check if the sum of all the batches can match the workload size
+	return VerifySumOfBatchSizes(rolloutSpec, totalReplicas)
+}
+
+// calculateNewPodTarget calculates the number of pods in new version that should be upgraded
+// given the current batch in the status
+func calculateNewPodTarget(rolloutSpec *v1alpha1.RolloutPlan, currentBatch, workloadSize int) int {
+	newPodTarget := 0
+	if currentBatch == len(rolloutSpec.RolloutBatches)-1 {
+		// special handle the last batch, we ignore the rest of the batch in case there are rounding errors
+		klog.InfoS("use the workload size as the total pod target for the last rolling batch",
+			"current batch", currentBatch, "new version pod target", workloadSize)
+		newPodTarget = workloadSize
+	} else {
+		for i, r := range rolloutSpec.RolloutBatches {
+			batchSize, _ := intstr.GetValueFromIntOrPercent(&r.Replicas, workloadSize, true)
+			if i <= currentBatch {
+				newPodTarget += batchSize
+			} else {
+				break
+			}
+		}
+		klog.InfoS("Calculated the number of new version pod", "current batch", currentBatch,
+			"new version pod target", newPodTarget)
+	}
+	return newPodTarget
+}
+
+// updatedReadyReplicas returns the lower bound of the number of the updated pods which are ready,
+// by assuming all the pods not ready are updated ones
+func updatedReadyReplicas(replicas, updatedReplicas, readyReplicas int32) int32 {
+	updatedReady := updatedReplicas - (replicas - readyReplicas)
+	if updatedReady < 0 {
+		return 0
+	}
+	return updatedReady
+}
+
+// computePodTemplateHash computes the hash of the pod template, it identifies the pod template of the workloads
+// which don't record their revisions in status
+func computePodTemplateHash(template interface{}) (string, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+	hasher := fnv.New32a()
+	_, _ = hasher.Write(data)
+	return fmt.Sprintf("%x", hasher.Sum32()), nil
+}
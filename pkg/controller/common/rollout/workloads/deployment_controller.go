@@ -0,0 +1,301 @@
+package workloads
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// DeploymentController is responsible for handle Deployment type of workloads
+// Deployments have no partition, so the pods are upgraded by scaling the target Deployment up
+// and the source Deployment down batch by batch
+type DeploymentController struct {
+	client           client.Client
+	recorder         event.Recorder
+	parentController oam.Object
+
+	rolloutSpec          *v1alpha1.RolloutPlan
+	rolloutStatus        *v1alpha1.RolloutStatus
+	sourceNamespacedName types.NamespacedName
+	targetNamespacedName types.NamespacedName
+	sourceDeploy         *appsv1.Deployment
+	targetDeploy         *appsv1.Deployment
+}
+
+// NewDeploymentController creates a new Deployment controller, the source name is empty if there is no source Deployment
+func NewDeploymentController(client client.Client, recorder event.Recorder, parentController oam.Object,
+	rolloutSpec *v1alpha1.RolloutPlan, rolloutStatus *v1alpha1.RolloutStatus,
+	sourceName, targetName types.NamespacedName) *DeploymentController {
+	return &DeploymentController{
+		client:               client,
+		recorder:             recorder,
+		parentController:     parentController,
+		rolloutSpec:          rolloutSpec,
+		rolloutStatus:        rolloutStatus,
+		sourceNamespacedName: sourceName,
+		targetNamespacedName: targetName,
+	}
+}
+
+// Size returns the total number of pods of the source and target Deployments, it's the replicas of the source
+// Deployment (or the target Deployment if there is no source) before the rollout is verified
+func (c *DeploymentController) Size(ctx context.Context) (int32, error) {
+	if c.rolloutStatus.RolloutTargetSize > 0 {
+		return c.rolloutStatus.RolloutTargetSize, nil
+	}
+	if c.targetDeploy == nil {
+		if err := c.fetchDeployments(ctx); err != nil {
+			return 0, err
+		}
+	}
+	if c.sourceDeploy != nil {
+		return getDeploymentReplicas(c.sourceDeploy), nil
+	}
+	return getDeploymentReplicas(c.targetDeploy), nil
+}
+
+// Verify verifies that the source and target Deployments are consistent with the rollout spec
+func (c *DeploymentController) Verify(ctx context.Context) *v1alpha1.RolloutStatus {
+	var verifyErr error
+	defer func() {
+		if verifyErr != nil {
+			klog.Error(verifyErr)
+			c.recorder.Event(c.parentController, event.Warning("VerifyFailed", verifyErr))
+		}
+	}()
+
+	if verifyErr = c.fetchDeployments(ctx); verifyErr != nil {
+		return c.rolloutStatus
+	}
+
+	if c.sourceDeploy != nil && c.sourceNamespacedName == c.targetNamespacedName {
+		verifyErr = fmt.Errorf("the source and target deployment are the same, name = %s", c.targetNamespacedName)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return c.rolloutStatus
+	}
+
+	// make sure that there are changes in the pod template
+	targetHash, err := computePodTemplateHash(c.targetDeploy.Spec.Template)
+	if err != nil {
+		verifyErr = err
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return c.rolloutStatus
+	}
+	if targetHash == c.rolloutStatus.LastAppliedPodTemplateIdentifier {
+		verifyErr = fmt.Errorf("there is no difference between the source and target, hash = %s", targetHash)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return c.rolloutStatus
+	}
+	if c.sourceDeploy != nil {
+		sourceHash, _ := computePodTemplateHash(c.sourceDeploy.Spec.Template)
+		if sourceHash == targetHash {
+			verifyErr = fmt.Errorf("there is no difference between the source and target, hash = %s", targetHash)
+			c.rolloutStatus.RolloutFailed(verifyErr.Error())
+			return c.rolloutStatus
+		}
+	}
+	// record the new pod template hash
+	c.rolloutStatus.NewPodTemplateIdentifier = targetHash
+
+	// check if the rollout spec is compatible with the current state
+	totalReplicas, _ := c.Size(ctx)
+
+	// check if the target spec is the same as the total replicas
+	if verifyErr = verifyBatchSizes(c.rolloutSpec, "deployment", totalReplicas); verifyErr != nil {
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return c.rolloutStatus
+	}
+
+	// the rollout batch partition is either automatic or zero
+	if c.rolloutSpec.BatchPartition != nil && *c.rolloutSpec.BatchPartition != 0 {
+		verifyErr = fmt.Errorf("the rollout plan has to start from zero, partition= %d", *c.rolloutSpec.BatchPartition)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return c.rolloutStatus
+	}
+
+	// the source deployment can't be in the middle of another update
+	if c.sourceDeploy != nil && c.sourceDeploy.Status.UpdatedReplicas != getDeploymentReplicas(c.sourceDeploy) {
+		verifyErr = fmt.Errorf("the source deployment was still in the middle of updating, number of updated pods= %d",
+			c.sourceDeploy.Status.UpdatedReplicas)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return c.rolloutStatus
+	}
+
+	// record the size since the replicas of the deployments change during the rollout
+	c.rolloutStatus.RolloutTargetSize = totalReplicas
+
+	// mark the rollout verified
+	c.recorder.Event(c.parentController, event.Normal("Verified",
+		"Rollout spec and the Deployment resources are verified"))
+	c.rolloutStatus.StateTransition(v1alpha1.RollingSpecVerifiedEvent)
+	return c.rolloutStatus
+}
+
+// Initialize scales the target Deployment to zero so that the pods are added batch by batch
+func (c *DeploymentController) Initialize(ctx context.Context) *v1alpha1.RolloutStatus {
+	if c.fetchDeployments(ctx) != nil {
+		return c.rolloutStatus
+	}
+	if err := c.scaleDeployment(ctx, c.targetDeploy, 0); err != nil {
+		return c.rolloutStatus
+	}
+	// mark the rollout initialized
+	c.recorder.Event(c.parentController, event.Normal("Initialized", "Rollout resource are initialized"))
+	c.rolloutStatus.StateTransition(v1alpha1.RollingInitializedEvent)
+	return c.rolloutStatus
+}
+
+// RolloutOneBatchPods calculates the number of pods we can upgrade once according to the rollout spec
+// and then scales the target Deployment up accordingly
+func (c *DeploymentController) RolloutOneBatchPods(ctx context.Context) *v1alpha1.RolloutStatus {
+	if c.fetchDeployments(ctx) != nil {
+		return c.rolloutStatus
+	}
+	// calculate what's the total pods that should be upgraded given the currentBatch in the status
+	totalSize, _ := c.Size(ctx)
+	newPodTarget := calculateNewPodTarget(c.rolloutSpec, int(c.rolloutStatus.CurrentBatch), int(totalSize))
+	if err := c.scaleDeployment(ctx, c.targetDeploy, int32(newPodTarget)); err != nil {
+		return c.rolloutStatus
+	}
+	// record the upgrade
+	klog.InfoS("upgraded one batch", "current batch", c.rolloutStatus.CurrentBatch)
+	c.recorder.Event(c.parentController, event.Normal("Rollout",
+		fmt.Sprintf("upgraded the batch num = %d", c.rolloutStatus.CurrentBatch)))
+	c.rolloutStatus.StateTransition(v1alpha1.BatchRolloutVerifyingEvent)
+	c.rolloutStatus.UpgradedReplicas = int32(newPodTarget)
+	return c.rolloutStatus
+}
+
+// CheckOneBatchPods checks to see if the pods of the target Deployment are all available according to the rollout
+// plan, the source Deployment is scaled down once they are
+func (c *DeploymentController) CheckOneBatchPods(ctx context.Context) *v1alpha1.RolloutStatus {
+	if c.fetchDeployments(ctx) != nil {
+		return c.rolloutStatus
+	}
+	totalSize, _ := c.Size(ctx)
+	newPodTarget := calculateNewPodTarget(c.rolloutSpec, int(c.rolloutStatus.CurrentBatch), int(totalSize))
+	// get the number of ready pod from the target deployment
+	readyPodCount := int(updatedReadyReplicas(getDeploymentReplicas(c.targetDeploy),
+		c.targetDeploy.Status.UpdatedReplicas, c.targetDeploy.Status.ReadyReplicas))
+	currentBatch := c.rolloutSpec.RolloutBatches[c.rolloutStatus.CurrentBatch]
+	unavail := 0
+	if currentBatch.MaxUnavailable != nil {
+		unavail, _ = intstr.GetValueFromIntOrPercent(currentBatch.MaxUnavailable, int(totalSize), true)
+	}
+	klog.V(common.LogDebug).InfoS("checking the rolling out progress", "current batch", currentBatch,
+		"new pod count target", newPodTarget, "new ready pod count", readyPodCount,
+		"max unavailable pod allowed", unavail)
+	c.rolloutStatus.UpgradedReadyReplicas = int32(readyPodCount)
+	if unavail+readyPodCount < newPodTarget {
+		// continue to verify
+		klog.V(common.LogDebug).InfoS("the batch is not ready yet", "current batch", currentBatch)
+		c.rolloutStatus.StateTransition(v1alpha1.BatchRolloutVerifyingEvent)
+		return c.rolloutStatus
+	}
+	// remove the same number of pods from the source deployment
+	if c.sourceDeploy != nil {
+		if err := c.scaleDeployment(ctx, c.sourceDeploy, totalSize-int32(newPodTarget)); err != nil {
+			return c.rolloutStatus
+		}
+	}
+	// record the successful upgrade
+	klog.InfoS("pods are ready", "current batch", currentBatch)
+	c.recorder.Event(c.parentController, event.Normal("Batch Available",
+		fmt.Sprintf("the batch num = %d is available", c.rolloutStatus.CurrentBatch)))
+	c.rolloutStatus.StateTransition(v1alpha1.OneBatchAvailableEvent)
+	return c.rolloutStatus
+}
+
+// FinalizeOneBatch makes sure that the rollout status are updated correctly
+func (c *DeploymentController) FinalizeOneBatch(ctx context.Context) *v1alpha1.RolloutStatus {
+	// nothing to do for now
+	return c.rolloutStatus
+}
+
+// Finalize makes sure all the pods are moved from the source Deployment to the target Deployment
+func (c *DeploymentController) Finalize(ctx context.Context) *v1alpha1.RolloutStatus {
+	if c.fetchDeployments(ctx) != nil {
+		return c.rolloutStatus
+	}
+	totalSize, _ := c.Size(ctx)
+	if c.sourceDeploy != nil {
+		if err := c.scaleDeployment(ctx, c.sourceDeploy, 0); err != nil {
+			return c.rolloutStatus
+		}
+	}
+	if err := c.scaleDeployment(ctx, c.targetDeploy, totalSize); err != nil {
+		return c.rolloutStatus
+	}
+
+	c.rolloutStatus.StateTransition(v1alpha1.RollingFinalizedEvent)
+
+	return c.rolloutStatus
+}
+
+/* --------------------
+The functions below are helper functions
+--------------------- */
+// fetch the source deployment if there is one and the target deployment
+func (c *DeploymentController) fetchDeployments(ctx context.Context) error {
+	c.sourceDeploy = nil
+	if c.sourceNamespacedName.Name != "" {
+		source, err := c.fetchDeployment(ctx, c.sourceNamespacedName)
+		if err != nil {
+			return err
+		}
+		c.sourceDeploy = source
+	}
+	target, err := c.fetchDeployment(ctx, c.targetNamespacedName)
+	if err != nil {
+		return err
+	}
+	c.targetDeploy = target
+	return nil
+}
+
+func (c *DeploymentController) fetchDeployment(ctx context.Context, name types.NamespacedName) (*appsv1.Deployment, error) {
+	workload := appsv1.Deployment{}
+	if err := c.client.Get(ctx, name, &workload); err != nil {
+		if !apierrors.IsNotFound(err) {
+			c.recorder.Event(c.parentController, event.Warning("Failed to get the Deployment", err))
+		}
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return nil, err
+	}
+	return &workload, nil
+}
+
+// scaleDeployment patches the replicas of the deployment, it does nothing if the replicas are already right
+func (c *DeploymentController) scaleDeployment(ctx context.Context, deploy *appsv1.Deployment, replicas int32) error {
+	if deploy.Spec.Replicas != nil && *deploy.Spec.Replicas == replicas {
+		return nil
+	}
+	deployPatch := client.MergeFrom(deploy.DeepCopyObject())
+	deploy.Spec.Replicas = &replicas
+	if err := c.client.Patch(ctx, deploy, deployPatch, client.FieldOwner(c.parentController.GetUID())); err != nil {
+		c.recorder.Event(c.parentController, event.Warning("Failed to patch update the Deployment", err))
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return err
+	}
+	klog.InfoS("scaled the deployment", "deployment", deploy.GetName(), "replicas", replicas)
+	return nil
+}
+
+func getDeploymentReplicas(deploy *appsv1.Deployment) int32 {
+	// default is 1
+	if deploy.Spec.Replicas == nil {
+		return 1
+	}
+	return *deploy.Spec.Replicas
+}
@@ -0,0 +1,148 @@
+package workloads
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+
+	//lint:ignore SA1019 We will use pkg/envtest before upgrading controller-runtime to v1.0.0
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func newTestRolloutPlan() *v1alpha1.RolloutPlan {
+	return &v1alpha1.RolloutPlan{
+		RolloutBatches: []v1alpha1.RolloutBatch{
+			{Replicas: intstr.FromInt(1)},
+			{Replicas: intstr.FromInt(3)},
+		},
+	}
+}
+
+func newTestPodTemplate(image string) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+	}
+}
+
+func TestDeploymentController(t *testing.T) {
+	ctx := context.Background()
+	source := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-v1", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32Ptr(4), Template: newTestPodTemplate("app:v1")},
+		Status:     appsv1.DeploymentStatus{UpdatedReplicas: 4, ReadyReplicas: 4},
+	}
+	target := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-v2", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32Ptr(4), Template: newTestPodTemplate("app:v2")},
+	}
+	cli := fake.NewFakeClientWithScheme(scheme.Scheme, source, target)
+	sourceName := types.NamespacedName{Namespace: "default", Name: "app-v1"}
+	targetName := types.NamespacedName{Namespace: "default", Name: "app-v2"}
+	rolloutSpec := newTestRolloutPlan()
+	status := &v1alpha1.RolloutStatus{RollingState: v1alpha1.VerifyingState}
+	newController := func() *DeploymentController {
+		return NewDeploymentController(cli, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{},
+			rolloutSpec, status, sourceName, targetName)
+	}
+	getReplicas := func(name types.NamespacedName) int32 {
+		deploy := &appsv1.Deployment{}
+		assert.NoError(t, cli.Get(ctx, name, deploy))
+		return *deploy.Spec.Replicas
+	}
+	setReady := func(name types.NamespacedName, ready int32) {
+		deploy := &appsv1.Deployment{}
+		assert.NoError(t, cli.Get(ctx, name, deploy))
+		deploy.Status.UpdatedReplicas = *deploy.Spec.Replicas
+		deploy.Status.ReadyReplicas = ready
+		assert.NoError(t, cli.Update(ctx, deploy))
+	}
+
+	newController().Verify(ctx)
+	assert.Equal(t, v1alpha1.InitializingState, status.RollingState)
+	assert.Equal(t, int32(4), status.RolloutTargetSize)
+	assert.NotEmpty(t, status.NewPodTemplateIdentifier)
+
+	newController().Initialize(ctx)
+	assert.Equal(t, v1alpha1.RollingInBatchesState, status.RollingState)
+	assert.Equal(t, int32(0), getReplicas(targetName))
+	size, err := newController().Size(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), size)
+
+	for batch, expected := range []int32{1, 4} {
+		sourceReplicas := getReplicas(sourceName)
+		status.CurrentBatch = int32(batch)
+		status.BatchRollingState = v1alpha1.BatchInRollingState
+		newController().RolloutOneBatchPods(ctx)
+		assert.Equal(t, v1alpha1.BatchVerifyingState, status.BatchRollingState)
+		assert.Equal(t, expected, getReplicas(targetName))
+		assert.Equal(t, expected, status.UpgradedReplicas)
+
+		// the source is not scaled down until the new pods are ready
+		newController().CheckOneBatchPods(ctx)
+		assert.Equal(t, v1alpha1.BatchVerifyingState, status.BatchRollingState)
+		assert.Equal(t, sourceReplicas, getReplicas(sourceName))
+
+		setReady(targetName, expected)
+		newController().CheckOneBatchPods(ctx)
+		assert.Equal(t, v1alpha1.BatchFinalizingState, status.BatchRollingState)
+		assert.Equal(t, expected, status.UpgradedReadyReplicas)
+		assert.Equal(t, 4-expected, getReplicas(sourceName))
+	}
+
+	status.RollingState = v1alpha1.FinalisingState
+	newController().Finalize(ctx)
+	assert.Equal(t, v1alpha1.RolloutSucceedState, status.RollingState)
+	assert.Equal(t, int32(0), getReplicas(sourceName))
+	assert.Equal(t, int32(4), getReplicas(targetName))
+}
+
+func TestDeploymentControllerVerifyFailed(t *testing.T) {
+	ctx := context.Background()
+	source := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-v1", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32Ptr(4), Template: newTestPodTemplate("app:v1")},
+		Status:     appsv1.DeploymentStatus{UpdatedReplicas: 4},
+	}
+	cases := map[string]struct {
+		target      *appsv1.Deployment
+		rolloutSpec *v1alpha1.RolloutPlan
+	}{
+		"same pod template": {
+			target: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "app-v2", Namespace: "default"},
+				Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32Ptr(4), Template: newTestPodTemplate("app:v1")},
+			},
+			rolloutSpec: newTestRolloutPlan(),
+		},
+		"scale the deployments": {
+			target: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "app-v2", Namespace: "default"},
+				Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32Ptr(4), Template: newTestPodTemplate("app:v2")},
+			},
+			rolloutSpec: &v1alpha1.RolloutPlan{
+				TargetSize:     pointer.Int32Ptr(5),
+				RolloutBatches: newTestRolloutPlan().RolloutBatches,
+			},
+		},
+	}
+	for name, c := range cases {
+		cli := fake.NewFakeClientWithScheme(scheme.Scheme, source.DeepCopy(), c.target)
+		status := &v1alpha1.RolloutStatus{RollingState: v1alpha1.VerifyingState}
+		NewDeploymentController(cli, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{}, c.rolloutSpec, status,
+			types.NamespacedName{Namespace: "default", Name: "app-v1"}, types.NamespacedName{Namespace: "default", Name: "app-v2"}).Verify(ctx)
+		assert.Equal(t, v1alpha1.RolloutFailedState, status.RollingState, name)
+	}
+}
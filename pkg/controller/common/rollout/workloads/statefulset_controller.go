@@ -0,0 +1,263 @@
+package workloads
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+	"github.com/oam-dev/kubevela/pkg/controller/common"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+// StatefulSetController is responsible for handle StatefulSet type of workloads
+// the pods whose ordinals are not less than the partition of the rolling update are upgraded
+type StatefulSetController struct {
+	client           client.Client
+	recorder         event.Recorder
+	parentController oam.Object
+
+	rolloutSpec            *v1alpha1.RolloutPlan
+	rolloutStatus          *v1alpha1.RolloutStatus
+	workloadNamespacedName types.NamespacedName
+	statefulSet            *appsv1.StatefulSet
+}
+
+// NewStatefulSetController creates a new StatefulSet controller
+func NewStatefulSetController(client client.Client, recorder event.Recorder, parentController oam.Object,
+	rolloutSpec *v1alpha1.RolloutPlan, rolloutStatus *v1alpha1.RolloutStatus, workloadName types.NamespacedName) *StatefulSetController {
+	return &StatefulSetController{
+		client:                 client,
+		recorder:               recorder,
+		parentController:       parentController,
+		rolloutSpec:            rolloutSpec,
+		rolloutStatus:          rolloutStatus,
+		workloadNamespacedName: workloadName,
+	}
+}
+
+// Size fetches the StatefulSet and returns the replicas (not the actual number of pods)
+func (c *StatefulSetController) Size(ctx context.Context) (int32, error) {
+	if c.statefulSet == nil {
+		err := c.fetchStatefulSet(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+	// default is 1
+	if c.statefulSet.Spec.Replicas == nil {
+		return 1, nil
+	}
+	return *c.statefulSet.Spec.Replicas, nil
+}
+
+// Verify verifies that the target rollout resource is consistent with the rollout spec
+func (c *StatefulSetController) Verify(ctx context.Context) *v1alpha1.RolloutStatus {
+	var verifyErr error
+	defer func() {
+		if verifyErr != nil {
+			klog.Error(verifyErr)
+			c.recorder.Event(c.parentController, event.Warning("VerifyFailed", verifyErr))
+		}
+	}()
+
+	if verifyErr = c.fetchStatefulSet(ctx); verifyErr != nil {
+		return c.rolloutStatus
+	}
+
+	// the update revision is only calculated after the statefulSet controller observes the new spec
+	if c.statefulSet.Status.ObservedGeneration != c.statefulSet.Generation {
+		verifyErr = fmt.Errorf("the statefulset is not observed yet, generation = %d, observed generation = %d",
+			c.statefulSet.Generation, c.statefulSet.Status.ObservedGeneration)
+		c.rolloutStatus.RolloutRetry(verifyErr.Error())
+		return c.rolloutStatus
+	}
+
+	// make sure that there are changes in the pod template
+	targetHash := c.statefulSet.Status.UpdateRevision
+	if targetHash == c.rolloutStatus.LastAppliedPodTemplateIdentifier {
+		verifyErr = fmt.Errorf("there is no difference between the source and target, hash = %s", targetHash)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return c.rolloutStatus
+	}
+	// record the new pod template hash
+	c.rolloutStatus.NewPodTemplateIdentifier = targetHash
+
+	// check if the rollout spec is compatible with the current state
+	totalReplicas, _ := c.Size(ctx)
+
+	// check if the target spec is the same as the StatefulSet replicas
+	if verifyErr = verifyBatchSizes(c.rolloutSpec, "statefulset", totalReplicas); verifyErr != nil {
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return c.rolloutStatus
+	}
+
+	// the rollout batch partition is either automatic or zero
+	if c.rolloutSpec.BatchPartition != nil && *c.rolloutSpec.BatchPartition != 0 {
+		verifyErr = fmt.Errorf("the rollout plan has to start from zero, partition= %d", *c.rolloutSpec.BatchPartition)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return c.rolloutStatus
+	}
+
+	// only the rolling update strategy can be paused by partition
+	if c.statefulSet.Spec.UpdateStrategy.Type != "" &&
+		c.statefulSet.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		verifyErr = fmt.Errorf("the statefulset has to use the %s update strategy, update strategy = %s",
+			appsv1.RollingUpdateStatefulSetStrategyType, c.statefulSet.Spec.UpdateStrategy.Type)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return c.rolloutStatus
+	}
+
+	// the number of old version in the StatefulSet equals to the total number
+	if oldVersionPod := c.getPartition(); oldVersionPod < totalReplicas {
+		verifyErr = fmt.Errorf("the statefulset was still in the middle of updating, number of old pods= %d", oldVersionPod)
+		c.rolloutStatus.RolloutFailed(verifyErr.Error())
+		return c.rolloutStatus
+	}
+
+	// mark the rollout verified
+	c.recorder.Event(c.parentController, event.Normal("Verified",
+		"Rollout spec and the StatefulSet resource are verified"))
+	c.rolloutStatus.StateTransition(v1alpha1.RollingSpecVerifiedEvent)
+	return c.rolloutStatus
+}
+
+// Initialize makes sure that the StatefulSet exists
+func (c *StatefulSetController) Initialize(ctx context.Context) *v1alpha1.RolloutStatus {
+	if c.fetchStatefulSet(ctx) != nil {
+		return c.rolloutStatus
+	}
+
+	// mark the rollout initialized, there is nothing we need to do for StatefulSet for now
+	c.recorder.Event(c.parentController, event.Normal("Initialized", "Rollout resource are initialized"))
+	c.rolloutStatus.StateTransition(v1alpha1.RollingInitializedEvent)
+	return c.rolloutStatus
+}
+
+// RolloutOneBatchPods calculates the number of pods we can upgrade once according to the rollout spec
+// and then set the partition accordingly
+func (c *StatefulSetController) RolloutOneBatchPods(ctx context.Context) *v1alpha1.RolloutStatus {
+	// calculate what's the total pods that should be upgraded given the currentBatch in the status
+	statefulSetSize, err := c.Size(ctx)
+	if err != nil {
+		return c.rolloutStatus
+	}
+	newPodTarget := calculateNewPodTarget(c.rolloutSpec, int(c.rolloutStatus.CurrentBatch), int(statefulSetSize))
+	// set the Partition as the desired number of pods in old revisions.
+	if err = c.patchPartition(ctx, statefulSetSize-int32(newPodTarget)); err != nil {
+		return c.rolloutStatus
+	}
+	// record the upgrade
+	klog.InfoS("upgraded one batch", "current batch", c.rolloutStatus.CurrentBatch)
+	c.recorder.Event(c.parentController, event.Normal("Rollout",
+		fmt.Sprintf("upgraded the batch num = %d", c.rolloutStatus.CurrentBatch)))
+	c.rolloutStatus.StateTransition(v1alpha1.BatchRolloutVerifyingEvent)
+	c.rolloutStatus.UpgradedReplicas = int32(newPodTarget)
+	return c.rolloutStatus
+}
+
+// CheckOneBatchPods checks to see if the pods are all available according to the rollout plan
+func (c *StatefulSetController) CheckOneBatchPods(ctx context.Context) *v1alpha1.RolloutStatus {
+	statefulSetSize, err := c.Size(ctx)
+	if err != nil {
+		return c.rolloutStatus
+	}
+	newPodTarget := calculateNewPodTarget(c.rolloutSpec, int(c.rolloutStatus.CurrentBatch), int(statefulSetSize))
+	// the statefulSet doesn't report the number of updated pods which are ready, so count the pods not ready as updated
+	readyPodCount := int(updatedReadyReplicas(statefulSetSize, c.statefulSet.Status.UpdatedReplicas,
+		c.statefulSet.Status.ReadyReplicas))
+	currentBatch := c.rolloutSpec.RolloutBatches[c.rolloutStatus.CurrentBatch]
+	unavail := 0
+	if currentBatch.MaxUnavailable != nil {
+		unavail, _ = intstr.GetValueFromIntOrPercent(currentBatch.MaxUnavailable, int(statefulSetSize), true)
+	}
+	klog.V(common.LogDebug).InfoS("checking the rolling out progress", "current batch", currentBatch,
+		"new pod count target", newPodTarget, "new ready pod count", readyPodCount,
+		"max unavailable pod allowed", unavail)
+	c.rolloutStatus.UpgradedReadyReplicas = int32(readyPodCount)
+	if unavail+readyPodCount >= newPodTarget {
+		// record the successful upgrade
+		klog.InfoS("pods are ready", "current batch", currentBatch)
+		c.recorder.Event(c.parentController, event.Normal("Batch Available",
+			fmt.Sprintf("the batch num = %d is available", c.rolloutStatus.CurrentBatch)))
+		c.rolloutStatus.StateTransition(v1alpha1.OneBatchAvailableEvent)
+	} else {
+		// continue to verify
+		klog.V(common.LogDebug).InfoS("the batch is not ready yet", "current batch", currentBatch)
+		c.rolloutStatus.StateTransition(v1alpha1.BatchRolloutVerifyingEvent)
+	}
+	return c.rolloutStatus
+}
+
+// FinalizeOneBatch makes sure that the rollout status are updated correctly
+func (c *StatefulSetController) FinalizeOneBatch(ctx context.Context) *v1alpha1.RolloutStatus {
+	// nothing to do for now
+	return c.rolloutStatus
+}
+
+// Finalize makes sure the StatefulSet is all upgraded
+func (c *StatefulSetController) Finalize(ctx context.Context) *v1alpha1.RolloutStatus {
+	if c.fetchStatefulSet(ctx) != nil {
+		return c.rolloutStatus
+	}
+	// make sure all the pods are upgraded
+	if err := c.patchPartition(ctx, 0); err != nil {
+		return c.rolloutStatus
+	}
+
+	c.rolloutStatus.StateTransition(v1alpha1.RollingFinalizedEvent)
+
+	return c.rolloutStatus
+}
+
+/* --------------------
+The functions below are helper functions
+--------------------- */
+// fetch the statefulSet and retry the rollout if it fails
+func (c *StatefulSetController) fetchStatefulSet(ctx context.Context) error {
+	// get the statefulSet
+	workload := appsv1.StatefulSet{}
+	err := c.client.Get(ctx, c.workloadNamespacedName, &workload)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			c.recorder.Event(c.parentController, event.Warning("Failed to get the StatefulSet", err))
+		}
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return err
+	}
+	c.statefulSet = &workload
+	return nil
+}
+
+// getPartition returns the partition of the rolling update, it's zero if it's not set
+func (c *StatefulSetController) getPartition() int32 {
+	rollingUpdate := c.statefulSet.Spec.UpdateStrategy.RollingUpdate
+	if rollingUpdate == nil || rollingUpdate.Partition == nil {
+		return 0
+	}
+	return *rollingUpdate.Partition
+}
+
+func (c *StatefulSetController) patchPartition(ctx context.Context, partition int32) error {
+	if c.getPartition() == partition {
+		return nil
+	}
+	statefulSetPatch := client.MergeFrom(c.statefulSet.DeepCopyObject())
+	c.statefulSet.Spec.UpdateStrategy.Type = appsv1.RollingUpdateStatefulSetStrategyType
+	c.statefulSet.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition}
+	// patch the StatefulSet
+	if err := c.client.Patch(ctx, c.statefulSet, statefulSetPatch,
+		client.FieldOwner(c.parentController.GetUID())); err != nil {
+		c.recorder.Event(c.parentController, event.Warning("Failed to patch update the StatefulSet", err))
+		c.rolloutStatus.RolloutRetry(err.Error())
+		return err
+	}
+	return nil
+}
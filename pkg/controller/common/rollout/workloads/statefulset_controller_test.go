@@ -0,0 +1,95 @@
+package workloads
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	//lint:ignore SA1019 We will use pkg/envtest before upgrading controller-runtime to v1.0.0
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/standard.oam.dev/v1alpha1"
+)
+
+func TestStatefulSetController(t *testing.T) {
+	ctx := context.Background()
+	name := types.NamespacedName{Namespace: "default", Name: "app"}
+	newStatefulSet := func(partition int32) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: pointer.Int32Ptr(4),
+				Template: newTestPodTemplate("app:v2"),
+				UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+					Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+					RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: pointer.Int32Ptr(partition)},
+				},
+			},
+			Status: appsv1.StatefulSetStatus{ReadyReplicas: 4, CurrentRevision: "app-1", UpdateRevision: "app-2"},
+		}
+	}
+	getPartition := func(cli client.Client) int32 {
+		sts := &appsv1.StatefulSet{}
+		assert.NoError(t, cli.Get(ctx, name, sts))
+		return *sts.Spec.UpdateStrategy.RollingUpdate.Partition
+	}
+
+	// the statefulset has to be paused by the partition before the rollout
+	cli := fake.NewFakeClientWithScheme(scheme.Scheme, newStatefulSet(0))
+	status := &v1alpha1.RolloutStatus{RollingState: v1alpha1.VerifyingState}
+	NewStatefulSetController(cli, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{}, newTestRolloutPlan(),
+		status, name).Verify(ctx)
+	assert.Equal(t, v1alpha1.RolloutFailedState, status.RollingState)
+
+	cli = fake.NewFakeClientWithScheme(scheme.Scheme, newStatefulSet(4))
+	status = &v1alpha1.RolloutStatus{RollingState: v1alpha1.VerifyingState}
+	newController := func() *StatefulSetController {
+		return NewStatefulSetController(cli, event.NewNopRecorder(), &v1alpha2.ApplicationDeployment{},
+			newTestRolloutPlan(), status, name)
+	}
+	newController().Verify(ctx)
+	assert.Equal(t, v1alpha1.InitializingState, status.RollingState)
+	assert.Equal(t, "app-2", status.NewPodTemplateIdentifier)
+	newController().Initialize(ctx)
+	assert.Equal(t, v1alpha1.RollingInBatchesState, status.RollingState)
+
+	status.BatchRollingState = v1alpha1.BatchInRollingState
+	newController().RolloutOneBatchPods(ctx)
+	assert.Equal(t, v1alpha1.BatchVerifyingState, status.BatchRollingState)
+	assert.Equal(t, int32(3), getPartition(cli))
+
+	// the updated pod is not ready yet
+	sts := &appsv1.StatefulSet{}
+	assert.NoError(t, cli.Get(ctx, name, sts))
+	sts.Status.UpdatedReplicas = 1
+	sts.Status.ReadyReplicas = 3
+	assert.NoError(t, cli.Update(ctx, sts))
+	newController().CheckOneBatchPods(ctx)
+	assert.Equal(t, v1alpha1.BatchVerifyingState, status.BatchRollingState)
+
+	assert.NoError(t, cli.Get(ctx, name, sts))
+	sts.Status.ReadyReplicas = 4
+	assert.NoError(t, cli.Update(ctx, sts))
+	newController().CheckOneBatchPods(ctx)
+	assert.Equal(t, v1alpha1.BatchFinalizingState, status.BatchRollingState)
+	assert.Equal(t, int32(1), status.UpgradedReadyReplicas)
+
+	status.CurrentBatch = 1
+	status.BatchRollingState = v1alpha1.BatchInRollingState
+	newController().RolloutOneBatchPods(ctx)
+	assert.Equal(t, int32(0), getPartition(cli))
+	assert.Equal(t, int32(4), status.UpgradedReplicas)
+
+	status.RollingState = v1alpha1.FinalisingState
+	newController().Finalize(ctx)
+	assert.Equal(t, v1alpha1.RolloutSucceedState, status.RollingState)
+}
@@ -47,4 +47,12 @@ type Args struct {
 	// CustomRevisionHookURL is a webhook which will let oam-runtime to call with AC+Component info
 	// The webhook server will return a customized component revision for oam-runtime
 	CustomRevisionHookURL string
+
+	// ClusterSecretNamespace is the namespace of the secrets which hold the kubeconfig of the clusters
+	// that applications can be placed to
+	ClusterSecretNamespace string
+
+	// DefinitionSchemaNamespace is the namespace of the ConfigMaps which store the OpenAPI schema of the parameters
+	// of WorkloadDefinitions and TraitDefinitions
+	DefinitionSchemaNamespace string
 }
@@ -19,10 +19,12 @@ package application
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
@@ -42,16 +44,30 @@ import (
 // RolloutReconcileWaitTime is the time to wait before reconcile again an application still in rollout phase
 const RolloutReconcileWaitTime = time.Second * 3
 
+// ReferencePendingWaitTime is the time to wait before reconcile again an application whose components are pending
+// on the live resources they refer to
+const ReferencePendingWaitTime = time.Second * 3
+
+// appFinalizer is used to clean up the resources created by the application before it's deleted
+const appFinalizer = "finalizers.application.oam.dev"
+
 // Reconciler reconciles a Application object
 type Reconciler struct {
 	dm discoverymapper.DiscoveryMapper
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+
+	// clusterSecretNamespace is the namespace of the secrets of the clusters that applications can be placed to
+	clusterSecretNamespace string
+	// clusterClients caches the clients of the registered clusters by cluster name
+	clusterClients sync.Map
 }
 
 // +kubebuilder:rbac:groups=core.oam.dev,resources=applications,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core.oam.dev,resources=applications/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core.oam.dev,resources=applicationrevisions,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list
 
 // Reconcile process app event
 func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -68,9 +84,34 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
-	// TODO: check finalizer
+	handler := &appHandler{r: r, app: app, l: applog}
+
 	if app.DeletionTimestamp != nil {
-		return ctrl.Result{}, nil
+		if !meta.FinalizerExists(&app.ObjectMeta, appFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		applog.Info("finalize application")
+		if err := handler.finalize(ctx); err != nil {
+			handler.l.Error(err, "[Handle finalize]")
+			app.Status.SetConditions(errorCondition("Finalized", err))
+			return handler.Err(err)
+		}
+		// the Terraform state is kept by the CLI, the application is reconciled again once the CLI destroys the
+		// cloud resources and removes the annotation
+		if components := app.GetAnnotations()[oam.AnnotationTerraformComponents]; components != "" {
+			applog.Info("wait for the cloud resources to be destroyed", "components", components)
+			app.Status.SetConditions(cloudResourcesPendingCondition("Finalized", components))
+			return ctrl.Result{}, r.UpdateStatus(ctx, app)
+		}
+		meta.RemoveFinalizer(&app.ObjectMeta, appFinalizer)
+		return ctrl.Result{}, errors.Wrap(r.Update(ctx, app), "remove finalizer of application")
+	}
+
+	if !meta.FinalizerExists(&app.ObjectMeta, appFinalizer) {
+		meta.AddFinalizer(&app.ObjectMeta, appFinalizer)
+		if err := r.Update(ctx, app); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "register finalizer of application")
+		}
 	}
 
 	// Check if the oam rollout annotation exists
@@ -82,39 +123,84 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{RequeueAfter: RolloutReconcileWaitTime}, r.UpdateStatus(ctx, app)
 	}
 
+	if err := handler.loadRollbackRevision(ctx); err != nil {
+		handler.l.Error(err, "[Handle Rollback]")
+		app.Status.SetConditions(errorCondition("Rollback", err))
+		return handler.Err(err)
+	}
+
 	applog.Info("Start Rendering")
 
 	app.Status.Phase = v1alpha2.ApplicationRendering
-	handler := &appHandler{r, app, applog}
 
 	app.Status.Conditions = []v1alpha1.Condition{}
+	app.Status.RenderError = nil
 
 	applog.Info("parse template")
 	// parse template
-	appParser := appfile.NewApplicationParser(r.Client, r.dm)
+	appParser := appfile.NewApplicationParserWithLoader(r.Client,
+		appfile.NewDefinitionLoader(handler.definitionReader(), r.dm))
 
-	appfile, err := appParser.GenerateAppFile(app.Name, app)
+	af, err := appParser.GenerateAppFile(app.Name, app)
 	if err != nil {
 		handler.l.Error(err, "[Handle Parse]")
 		app.Status.SetConditions(errorCondition("Parsed", err))
 		return handler.Err(err)
 	}
+	af.InReconcile = true
 
 	app.Status.SetConditions(readyCondition("Parsed"))
 
+	appRev, revision, err := handler.prepareRevision(ctx, af)
+	if err != nil {
+		handler.l.Error(err, "[Handle PrepareRevision]")
+		app.Status.SetConditions(errorCondition("Revision", err))
+		return handler.Err(err)
+	}
+
 	applog.Info("build template")
 	// build template to applicationconfig & component
-	ac, comps, err := appParser.GenerateApplicationConfiguration(appfile, app.Namespace)
-	if err != nil {
+	ac, comps, err := appParser.GenerateApplicationConfiguration(af, app.Namespace)
+	// the components referring to the live resources which aren't ready yet are applied once they are ready,
+	// the others are applied in the meantime
+	pending := appfile.IsReferencePending(err)
+	if err != nil && !pending {
 		handler.l.Error(err, "[Handle GenerateApplicationConfiguration]")
 		app.Status.SetConditions(errorCondition("Built", err))
+		app.Status.RenderError = renderErrorStatus(err)
 		return handler.Err(err)
 	}
 
-	app.Status.SetConditions(readyCondition("Built"))
+	if pending {
+		applog.Info("some components are pending", "reason", err.Error())
+		app.Status.SetConditions(pendingCondition("Built", err))
+		// the revision is created once all the components are rendered
+		appRev = nil
+	} else {
+		app.Status.SetConditions(readyCondition("Built"))
+	}
+
+	if appRev != nil {
+		applog.Info("create application revision", "revision", appRev.Name)
+		if err := handler.createRevision(ctx, appRev, revision, ac, comps); err != nil {
+			handler.l.Error(err, "[Handle createRevision]")
+			app.Status.SetConditions(errorCondition("Revision", err))
+			return handler.Err(err)
+		}
+	}
+	app.Status.SetConditions(readyCondition("Revision"))
+
+	targets, err := handler.targets(ctx)
+	if err != nil {
+		handler.l.Error(err, "[Handle placement]")
+		app.Status.SetConditions(errorCondition("Placed", err))
+		return handler.Err(err)
+	}
+	app.Status.SetConditions(readyCondition("Placed"))
+
 	applog.Info("apply appConfig & component to the cluster")
 	// apply appConfig & component to the cluster
-	if err := handler.apply(ctx, ac, comps); err != nil {
+	if err := handler.apply(ctx, targets, ac, comps); err != nil {
 		handler.l.Error(err, "[Handle apply]")
 		app.Status.SetConditions(errorCondition("Applied", err))
 		return handler.Err(err)
@@ -122,10 +208,14 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	app.Status.SetConditions(readyCondition("Applied"))
 
+	if pending {
+		return ctrl.Result{RequeueAfter: ReferencePendingWaitTime}, r.UpdateStatus(ctx, app)
+	}
+
 	app.Status.Phase = v1alpha2.ApplicationHealthChecking
 	applog.Info("check application health status")
 	// check application health status
-	appCompStatus, healthy, err := handler.statusAggregate(appfile)
+	appCompStatus, healthy, err := handler.statusAggregate(af, targets)
 	if err != nil {
 		app.Status.SetConditions(errorCondition("HealthCheck", err))
 		return handler.Err(err)
@@ -140,17 +230,6 @@ func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	app.Status.Services = appCompStatus
 	app.Status.SetConditions(readyCondition("HealthCheck"))
 	app.Status.Phase = v1alpha2.ApplicationRunning
-	// Gather status of components
-	var refComps []v1alpha1.TypedReference
-	for _, comp := range comps {
-		refComps = append(refComps, v1alpha1.TypedReference{
-			APIVersion: comp.APIVersion,
-			Kind:       comp.Kind,
-			Name:       comp.Name,
-			UID:        app.UID,
-		})
-	}
-	app.Status.Components = refComps
 	return ctrl.Result{}, r.UpdateStatus(ctx, app)
 }
 
@@ -175,7 +254,7 @@ func (r *Reconciler) UpdateStatus(ctx context.Context, app *v1alpha2.Application
 }
 
 // Setup adds a controller that reconciles ApplicationDeployment.
-func Setup(mgr ctrl.Manager, _ core.Args, _ logging.Logger) error {
+func Setup(mgr ctrl.Manager, args core.Args, _ logging.Logger) error {
 	dm, err := discoverymapper.New(mgr.GetConfig())
 	if err != nil {
 		return fmt.Errorf("create discovery dm fail %w", err)
@@ -185,6 +264,8 @@ func Setup(mgr ctrl.Manager, _ core.Args, _ logging.Logger) error {
 		Log:    ctrl.Log.WithName("Application"),
 		Scheme: mgr.GetScheme(),
 		dm:     dm,
+
+		clusterSecretNamespace: args.ClusterSecretNamespace,
 	}
 	return reconciler.SetupWithManager(mgr)
 }
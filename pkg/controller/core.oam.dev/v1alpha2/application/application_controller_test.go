@@ -44,6 +44,7 @@ import (
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/serverlib"
 )
 
 var _ = Describe("Test Application Controller", func() {
@@ -641,6 +642,378 @@ var _ = Describe("Test Application Controller", func() {
 		Expect(k8sClient.Delete(ctx, app)).Should(BeNil())
 	})
 
+	It("app with finalizer will clean up the created resources when deleted", func() {
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "app-with-finalizer",
+			},
+		}
+		Expect(k8sClient.Create(ctx, ns)).Should(BeNil())
+		app := appwithNoTrait.DeepCopy()
+		app.SetName("app-with-finalizer")
+		app.SetNamespace(ns.Name)
+		Expect(k8sClient.Create(ctx, app)).Should(BeNil())
+
+		appKey := client.ObjectKey{
+			Name:      app.Name,
+			Namespace: app.Namespace,
+		}
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+
+		By("Check finalizer registered")
+		checkApp := &v1alpha2.Application{}
+		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(BeNil())
+		Expect(checkApp.Finalizers).Should(ContainElement(appFinalizer))
+		Expect(checkApp.Status.Components).Should(HaveLen(1))
+
+		By("Delete Application and reconcile")
+		Expect(k8sClient.Delete(ctx, checkApp)).Should(BeNil())
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+
+		By("Check resources cleaned up")
+		Expect(k8sClient.Get(ctx, client.ObjectKey{
+			Namespace: app.Namespace,
+			Name:      "myweb2",
+		}, &v1alpha2.Component{})).Should(&util.NotFoundMatcher{})
+		Expect(k8sClient.Get(ctx, appKey, &v1alpha2.ApplicationConfiguration{})).Should(&util.NotFoundMatcher{})
+		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(&util.NotFoundMatcher{})
+	})
+
+	It("app with cloud resources applied by Terraform is not finalized until they're destroyed", func() {
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "app-with-cloud-resources",
+			},
+		}
+		Expect(k8sClient.Create(ctx, ns)).Should(BeNil())
+		app := appwithNoTrait.DeepCopy()
+		app.SetName("app-with-cloud-resources")
+		app.SetNamespace(ns.Name)
+		app.SetAnnotations(map[string]string{oam.AnnotationTerraformComponents: "sample-db"})
+		Expect(k8sClient.Create(ctx, app)).Should(BeNil())
+
+		appKey := client.ObjectKey{
+			Name:      app.Name,
+			Namespace: app.Namespace,
+		}
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+
+		By("Delete Application and reconcile")
+		checkApp := &v1alpha2.Application{}
+		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(BeNil())
+		Expect(k8sClient.Delete(ctx, checkApp)).Should(BeNil())
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+
+		By("Check the finalizer is kept and the pending cloud resources are reported")
+		Expect(k8sClient.Get(ctx, appKey, &v1alpha2.ApplicationConfiguration{})).Should(&util.NotFoundMatcher{})
+		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(BeNil())
+		Expect(checkApp.Finalizers).Should(ContainElement(appFinalizer))
+		finalized := checkApp.Status.GetCondition("Finalized")
+		Expect(finalized.Status).Should(Equal(corev1.ConditionUnknown))
+		Expect(string(finalized.Reason)).Should(Equal("CloudResourcesPending"))
+		Expect(finalized.Message).Should(ContainSubstring("sample-db"))
+
+		By("Remove the annotation as the CLI does after destroying the cloud resources")
+		delete(checkApp.Annotations, oam.AnnotationTerraformComponents)
+		Expect(k8sClient.Update(ctx, checkApp)).Should(BeNil())
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(&util.NotFoundMatcher{})
+	})
+
+	It("app revisions are created on changes and the app can be rolled back to a former revision", func() {
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "app-with-revision",
+			},
+		}
+		Expect(k8sClient.Create(ctx, ns)).Should(BeNil())
+		versionedWd := &v1alpha2.WorkloadDefinition{}
+		versionedWdJson, _ := yaml.YAMLToJSON([]byte(fmt.Sprintf(versionedWorkerYaml, "v1")))
+		Expect(json.Unmarshal(versionedWdJson, versionedWd)).Should(BeNil())
+		Expect(k8sClient.Create(ctx, versionedWd.DeepCopy())).Should(BeNil())
+
+		app := &v1alpha2.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-with-revision", Namespace: ns.Name},
+			Spec: v1alpha2.ApplicationSpec{Components: []v1alpha2.ApplicationComponent{{
+				Name:         "myweb-revision",
+				WorkloadType: "versioned-worker",
+				Settings:     runtime.RawExtension{Raw: []byte(`{"image":"busybox"}`)},
+			}}},
+		}
+		Expect(k8sClient.Create(ctx, app)).Should(BeNil())
+		appKey := client.ObjectKey{Name: app.Name, Namespace: app.Namespace}
+		compKey := client.ObjectKey{Name: "myweb-revision", Namespace: app.Namespace}
+		checkVersion := func(expVersion string) {
+			component := &v1alpha2.Component{}
+			Expect(k8sClient.Get(ctx, compKey, component)).Should(BeNil())
+			gotCM := &corev1.ConfigMap{}
+			Expect(json.Unmarshal(component.Spec.Workload.Raw, gotCM)).Should(BeNil())
+			Expect(gotCM.Data).Should(Equal(map[string]string{"image": "busybox", "version": expVersion}))
+		}
+		checkLatestRevision := func(expRevision int64) {
+			checkApp := &v1alpha2.Application{}
+			Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(BeNil())
+			Expect(checkApp.Status.Phase).Should(Equal(v1alpha2.ApplicationRunning))
+			Expect(checkApp.Status.LatestRevision).ShouldNot(BeNil())
+			Expect(checkApp.Status.LatestRevision.Revision).Should(Equal(expRevision))
+			revs := &v1alpha2.ApplicationRevisionList{}
+			Expect(k8sClient.List(ctx, revs, client.InNamespace(app.Namespace),
+				client.MatchingLabels{oam.LabelAppName: app.Name})).Should(BeNil())
+			Expect(revs.Items).Should(HaveLen(int(expRevision)))
+		}
+
+		By("Check the first revision created with the snapshot of definitions")
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+		checkLatestRevision(1)
+		checkVersion("v1")
+		appRev := &v1alpha2.ApplicationRevision{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: app.Namespace,
+			Name: util.ConstructAppRevisionName(app.Name, 1)}, appRev)).Should(BeNil())
+		Expect(appRev.Labels[oam.LabelAppRevisionHash]).ShouldNot(BeEmpty())
+		Expect(appRev.Spec.WorkloadDefinitions).Should(HaveKey("versioned-worker"))
+		Expect(appRev.Spec.Components).Should(HaveLen(1))
+
+		By("Check no new revision created if nothing changed")
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+		checkLatestRevision(1)
+
+		By("Check a new revision created after the definition changed")
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: versionedWd.Name}, versionedWd)).Should(BeNil())
+		newWd := &v1alpha2.WorkloadDefinition{}
+		newWdJson, _ := yaml.YAMLToJSON([]byte(fmt.Sprintf(versionedWorkerYaml, "v2")))
+		Expect(json.Unmarshal(newWdJson, newWd)).Should(BeNil())
+		versionedWd.Spec = newWd.Spec
+		Expect(k8sClient.Update(ctx, versionedWd)).Should(BeNil())
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+		checkLatestRevision(2)
+		checkVersion("v2")
+
+		By("Rollback to the first revision and check the workload rendered by the snapshotted definition")
+		Expect(serverlib.RollbackApplication(ctx, k8sClient, app.Name, app.Namespace, 1)).Should(BeNil())
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+		checkLatestRevision(3)
+		checkVersion("v1")
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+		checkLatestRevision(3)
+		checkVersion("v1")
+
+		By("Check the live definition is used again once the app is changed after the rollback")
+		checkApp := &v1alpha2.Application{}
+		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(BeNil())
+		Expect(checkApp.Annotations).Should(HaveKeyWithValue(oam.AnnotationAppRollback,
+			util.ConstructAppRevisionName(app.Name, 1)))
+		checkApp.Spec.Components[0].Settings = runtime.RawExtension{Raw: []byte(`{"image":"nginx"}`)}
+		Expect(k8sClient.Update(ctx, checkApp)).Should(BeNil())
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+		checkLatestRevision(4)
+		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(BeNil())
+		Expect(checkApp.Annotations).ShouldNot(HaveKey(oam.AnnotationAppRollback))
+		component := &v1alpha2.Component{}
+		Expect(k8sClient.Get(ctx, compKey, component)).Should(BeNil())
+		gotCM := &corev1.ConfigMap{}
+		Expect(json.Unmarshal(component.Spec.Workload.Raw, gotCM)).Should(BeNil())
+		Expect(gotCM.Data).Should(Equal(map[string]string{"image": "nginx", "version": "v2"}))
+
+		Expect(k8sClient.Delete(ctx, checkApp)).Should(BeNil())
+		Expect(k8sClient.Delete(ctx, versionedWd)).Should(BeNil())
+	})
+
+	It("app revisions beyond the revision history limit are cleaned up", func() {
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "app-with-revision-limit",
+			},
+		}
+		Expect(k8sClient.Create(ctx, ns)).Should(BeNil())
+		app := appwithNoTrait.DeepCopy()
+		app.SetName("app-with-revision-limit")
+		app.SetNamespace(ns.Name)
+		app.Spec.RevisionHistoryLimit = pointer.Int32Ptr(2)
+		Expect(k8sClient.Create(ctx, app)).Should(BeNil())
+		appKey := client.ObjectKey{Name: app.Name, Namespace: app.Namespace}
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+
+		By("Change the application three times and check only the latest two revisions are kept")
+		for _, image := range []string{"nginx", "redis", "mysql"} {
+			checkApp := &v1alpha2.Application{}
+			Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(BeNil())
+			checkApp.Spec.Components[0].Settings = runtime.RawExtension{
+				Raw: []byte(fmt.Sprintf(`{"cmd":["sleep","1000"],"image":"%s"}`, image))}
+			Expect(k8sClient.Update(ctx, checkApp)).Should(BeNil())
+			reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+		}
+		revs := &v1alpha2.ApplicationRevisionList{}
+		Expect(k8sClient.List(ctx, revs, client.InNamespace(app.Namespace),
+			client.MatchingLabels{oam.LabelAppName: app.Name})).Should(BeNil())
+		var names []string
+		for _, rev := range revs.Items {
+			names = append(names, rev.Name)
+		}
+		Expect(names).Should(ConsistOf(util.ConstructAppRevisionName(app.Name, 3), util.ConstructAppRevisionName(app.Name, 4)))
+
+		By("Check the annotations set by kubectl don't make a new revision")
+		checkApp := &v1alpha2.Application{}
+		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(BeNil())
+		checkApp.SetAnnotations(map[string]string{corev1.LastAppliedConfigAnnotation: "{}"})
+		Expect(k8sClient.Update(ctx, checkApp)).Should(BeNil())
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(BeNil())
+		Expect(checkApp.Status.LatestRevision.Revision).Should(BeEquivalentTo(4))
+
+		Expect(k8sClient.Delete(ctx, checkApp)).Should(BeNil())
+	})
+
+	It("app with a component referring to the live resource of another one not created yet", func() {
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "app-with-pending-reference",
+			},
+		}
+		Expect(k8sClient.Create(ctx, ns)).Should(BeNil())
+		app := appwithNoTrait.DeepCopy()
+		app.SetName("app-with-pending-reference")
+		app.SetNamespace(ns.Name)
+		app.Spec.Components = append(app.Spec.Components, v1alpha2.ApplicationComponent{
+			Name:         "myconsumer",
+			WorkloadType: "worker",
+			Settings: runtime.RawExtension{
+				Raw: []byte(`{"cmd":["echo","$(components.myweb2.output.metadata.uid)"],"image":"busybox"}`)},
+		})
+		Expect(k8sClient.Create(ctx, app)).Should(BeNil())
+		appKey := client.ObjectKey{Name: app.Name, Namespace: app.Namespace}
+		result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: appKey})
+		Expect(err).Should(BeNil())
+		Expect(result.RequeueAfter).Should(Equal(ReferencePendingWaitTime))
+
+		By("Check the referenced component is applied and the other one is pending")
+		checkApp := &v1alpha2.Application{}
+		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(BeNil())
+		built := checkApp.Status.GetCondition("Built")
+		Expect(built.Status).Should(Equal(corev1.ConditionUnknown))
+		Expect(built.Message).Should(ContainSubstring("myconsumer"))
+		Expect(checkApp.Status.LatestRevision).Should(BeNil())
+		appConfig := &v1alpha2.ApplicationConfiguration{}
+		Expect(k8sClient.Get(ctx, appKey, appConfig)).Should(BeNil())
+		Expect(len(appConfig.Spec.Components)).Should(Equal(1))
+		Expect(appConfig.Spec.Components[0].ComponentName).Should(Equal("myweb2"))
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: "myconsumer"},
+			&v1alpha2.Component{})).Should(&util.NotFoundMatcher{})
+
+		By("Create the referenced workload as the ApplicationConfiguration controller does")
+		deploy := getExpDeployment("myweb2", app.Name)
+		deploy.SetName("myweb2")
+		deploy.SetNamespace(app.Namespace)
+		deploy.Labels[oam.LabelOAMResourceType] = oam.ResourceTypeWorkload
+		Expect(k8sClient.Create(ctx, deploy)).Should(BeNil())
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+
+		By("Check the pending component is applied with the reference resolved")
+		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(BeNil())
+		Expect(checkApp.Status.GetCondition("Built").Status).Should(Equal(corev1.ConditionTrue))
+		Expect(checkApp.Status.LatestRevision).ShouldNot(BeNil())
+		Expect(k8sClient.Get(ctx, appKey, appConfig)).Should(BeNil())
+		Expect(len(appConfig.Spec.Components)).Should(Equal(2))
+		component := &v1alpha2.Component{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: "myconsumer"}, component)).Should(BeNil())
+		gotD := &v1.Deployment{}
+		Expect(json.Unmarshal(component.Spec.Workload.Raw, gotD)).Should(BeNil())
+		Expect(gotD.Spec.Template.Spec.Containers[0].Command).Should(Equal([]string{"echo", string(deploy.UID)}))
+
+		Expect(k8sClient.Delete(ctx, checkApp)).Should(BeNil())
+	})
+
+	It("app with placement will be dispatched to the selected clusters", func() {
+		By("register member cluster")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "member",
+				Namespace: "default",
+				Labels:    map[string]string{oam.LabelClusterCredential: "true", "region": "hangzhou"},
+			},
+			Data: map[string][]byte{clusterKubeconfigKey: memberKubeconfig},
+		}
+		Expect(k8sClient.Create(ctx, secret)).Should(BeNil())
+
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "app-with-placement",
+			},
+		}
+		Expect(k8sClient.Create(ctx, ns.DeepCopy())).Should(BeNil())
+		app := appwithNoTrait.DeepCopy()
+		app.SetName("app-with-placement")
+		app.SetNamespace(ns.Name)
+		app.Spec.Placement = &v1alpha2.ApplicationPlacement{
+			ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "hangzhou"}},
+		}
+		Expect(k8sClient.Create(ctx, app)).Should(BeNil())
+
+		appKey := client.ObjectKey{
+			Name:      app.Name,
+			Namespace: app.Namespace,
+		}
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+
+		By("Check resources are dispatched to the member cluster")
+		checkApp := &v1alpha2.Application{}
+		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(BeNil())
+		Expect(checkApp.Status.Phase).Should(Equal(v1alpha2.ApplicationRunning))
+		Expect(checkApp.Status.Clusters).Should(Equal([]string{"member"}))
+		Expect(checkApp.Status.Services).Should(HaveLen(1))
+		Expect(checkApp.Status.Services[0].Cluster).Should(Equal("member"))
+		Expect(memberClient.Get(ctx, client.ObjectKey{Name: ns.Name}, &corev1.Namespace{})).Should(BeNil())
+		Expect(memberClient.Get(ctx, appKey, &v1alpha2.ApplicationConfiguration{})).Should(BeNil())
+		Expect(memberClient.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: "myweb2"}, &v1alpha2.Component{})).Should(BeNil())
+		Expect(k8sClient.Get(ctx, appKey, &v1alpha2.ApplicationConfiguration{})).Should(&util.NotFoundMatcher{})
+
+		By("Delete Application and check resources in the member cluster cleaned up")
+		Expect(k8sClient.Delete(ctx, checkApp)).Should(BeNil())
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+		Expect(memberClient.Get(ctx, appKey, &v1alpha2.ApplicationConfiguration{})).Should(&util.NotFoundMatcher{})
+		Expect(memberClient.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: "myweb2"}, &v1alpha2.Component{})).Should(&util.NotFoundMatcher{})
+		Expect(k8sClient.Delete(ctx, secret)).Should(BeNil())
+	})
+
+	It("app placed to a cluster whose secret is deleted can still be deleted", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "member-deleted",
+				Namespace: "default",
+				Labels:    map[string]string{oam.LabelClusterCredential: "true"},
+			},
+			Data: map[string][]byte{clusterKubeconfigKey: memberKubeconfig},
+		}
+		Expect(k8sClient.Create(ctx, secret)).Should(BeNil())
+
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "app-with-deleted-cluster",
+			},
+		}
+		Expect(k8sClient.Create(ctx, ns.DeepCopy())).Should(BeNil())
+		Expect(memberClient.Create(ctx, ns.DeepCopy())).Should(BeNil())
+		app := appwithNoTrait.DeepCopy()
+		app.SetName("app-with-deleted-cluster")
+		app.SetNamespace(ns.Name)
+		app.Spec.Placement = &v1alpha2.ApplicationPlacement{ClusterNames: []string{secret.Name}}
+		Expect(k8sClient.Create(ctx, app)).Should(BeNil())
+
+		appKey := client.ObjectKey{
+			Name:      app.Name,
+			Namespace: app.Namespace,
+		}
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+		checkApp := &v1alpha2.Application{}
+		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(BeNil())
+		Expect(checkApp.Status.Clusters).Should(Equal([]string{secret.Name}))
+
+		By("Delete the cluster secret then the Application")
+		Expect(k8sClient.Delete(ctx, secret)).Should(BeNil())
+		Expect(k8sClient.Delete(ctx, checkApp)).Should(BeNil())
+		reconcileRetry(reconciler, reconcile.Request{NamespacedName: appKey})
+		Expect(k8sClient.Get(ctx, appKey, checkApp)).Should(&util.NotFoundMatcher{})
+	})
+
 	It("app-with-trait will create workload and trait with http task", func() {
 		s := NewMock()
 		defer s.Close()
@@ -991,6 +1364,30 @@ func reconcileRetry(r reconcile.Reconciler, req reconcile.Request) {
 }
 
 const (
+	// versionedWorkerYaml is a workload definition whose template records the version of the definition
+	versionedWorkerYaml = `
+apiVersion: core.oam.dev/v1alpha2
+kind: WorkloadDefinition
+metadata:
+  name: versioned-worker
+spec:
+  definitionRef:
+    name: configmaps
+  extension:
+    template: |
+      output: {
+          apiVersion: "v1"
+          kind:       "ConfigMap"
+          data: {
+              image:   parameter.image
+              version: "%s"
+          }
+      }
+      parameter: {
+          image: string
+      }
+`
+
 	sDDefYaml = `apiVersion: core.oam.dev/v1alpha2
 kind: ScopeDefinition
 metadata:
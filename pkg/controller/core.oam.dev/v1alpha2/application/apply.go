@@ -2,6 +2,7 @@ package application
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	runtimev1alpha1 "github.com/crossplane/crossplane-runtime/apis/core/v1alpha1"
@@ -17,19 +18,34 @@ import (
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/pkg/appfile"
-	"github.com/oam-dev/kubevela/pkg/dsl/process"
+	httptask "github.com/oam-dev/kubevela/pkg/builtin/http"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/oam"
 )
 
 func errorCondition(tpy string, err error) runtimev1alpha1.Condition {
+	reason := runtimev1alpha1.ReasonReconcileError
+	// tell the users whether their parameters or the templates of definitions are at fault
+	if re, ok := definition.AsRenderError(err); ok {
+		reason = runtimev1alpha1.ConditionReason(re.Reason)
+	}
 	return runtimev1alpha1.Condition{
 		Type:               runtimev1alpha1.ConditionType(tpy),
 		Status:             v1.ConditionFalse,
 		LastTransitionTime: metav1.NewTime(time.Now()),
-		Reason:             runtimev1alpha1.ReasonReconcileError,
+		Reason:             reason,
 		Message:            err.Error(),
 	}
 }
 
+// renderErrorStatus returns the status of the RenderError in the chain of err, it's nil if err isn't a render error
+func renderErrorStatus(err error) *v1alpha2.ApplicationRenderError {
+	if re, ok := definition.AsRenderError(err); ok {
+		return re.ApplicationRenderError.DeepCopy()
+	}
+	return nil
+}
+
 func readyCondition(tpy string) runtimev1alpha1.Condition {
 	return runtimev1alpha1.Condition{
 		Type:               runtimev1alpha1.ConditionType(tpy),
@@ -39,10 +55,37 @@ func readyCondition(tpy string) runtimev1alpha1.Condition {
 	}
 }
 
+// pendingCondition tells that the components are waiting for the live resources they refer to, it's not a failure
+func pendingCondition(tpy string, err error) runtimev1alpha1.Condition {
+	return runtimev1alpha1.Condition{
+		Type:               runtimev1alpha1.ConditionType(tpy),
+		Status:             v1.ConditionUnknown,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Reason:             "ReferencePending",
+		Message:            err.Error(),
+	}
+}
+
+// cloudResourcesPendingCondition tells that the application is waiting for the cloud resources applied by Terraform
+// in the CLI to be destroyed by the CLI
+func cloudResourcesPendingCondition(tpy string, components string) runtimev1alpha1.Condition {
+	return runtimev1alpha1.Condition{
+		Type:               runtimev1alpha1.ConditionType(tpy),
+		Status:             v1.ConditionUnknown,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Reason:             "CloudResourcesPending",
+		Message: fmt.Sprintf("the cloud resources of components %s are applied by Terraform, run `vela delete` "+
+			"where they're applied to destroy them, or remove the annotation %s to leave them",
+			components, oam.AnnotationTerraformComponents),
+	}
+}
+
 type appHandler struct {
 	r   *Reconciler
 	app *v1alpha2.Application
 	l   logr.Logger
+	// rollbackRev is the revision the application is rolled back to, its definition snapshots are used to render
+	rollbackRev *v1alpha2.ApplicationRevision
 }
 
 func (ret *appHandler) Err(err error) (ctrl.Result, error) {
@@ -53,13 +96,20 @@ func (ret *appHandler) Err(err error) (ctrl.Result, error) {
 	if nerr != nil {
 		ret.l.Error(nerr, "[Update] application")
 	}
+	requeueAfter := time.Second * 10
+	// the request of a processing task is retried by requeueing rather than waiting in the reconcile
+	if re, ok := httptask.AsRetryableError(err); ok && re.RetryAfter > 0 {
+		requeueAfter = re.RetryAfter
+	}
 	return ctrl.Result{
-		RequeueAfter: time.Second * 10,
+		RequeueAfter: requeueAfter,
 	}, nil
 }
 
-// apply will set ownerReference for ApplicationConfiguration and Components created by Application
-func (ret *appHandler) apply(ctx context.Context, ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) error {
+// apply dispatches the ApplicationConfiguration and Components to the target clusters,
+// ownerReference to the Application is only set in the local cluster as the Application doesn't exist in the others,
+// and the namespace of the Application is created in the others if it doesn't exist.
+func (ret *appHandler) apply(ctx context.Context, targets []clusterTarget, ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) error {
 	owners := []metav1.OwnerReference{{
 		APIVersion: v1alpha2.SchemeGroupVersion.String(),
 		Kind:       v1alpha2.ApplicationKind,
@@ -67,43 +117,141 @@ func (ret *appHandler) apply(ctx context.Context, ac *v1alpha2.ApplicationConfig
 		UID:        ret.app.UID,
 		Controller: pointer.BoolPtr(true),
 	}}
-	ac.SetOwnerReferences(owners)
-	for _, c := range comps {
-		c.SetOwnerReferences(owners)
+	for _, target := range targets {
+		targetAC := ac.DeepCopy()
+		targetComps := make([]*v1alpha2.Component, len(comps))
+		for i, c := range comps {
+			targetComps[i] = c.DeepCopy()
+		}
+		if target.name == localCluster {
+			targetAC.SetOwnerReferences(owners)
+			for _, c := range targetComps {
+				c.SetOwnerReferences(owners)
+			}
+		} else if err := ret.ensureNamespace(ctx, target); err != nil {
+			return errors.WithMessagef(err, "apply to cluster %s", target.String())
+		}
+		if err := ret.Sync(ctx, target.client, targetAC, targetComps); err != nil {
+			return errors.WithMessagef(err, "apply to cluster %s", target.String())
+		}
+	}
+	if err := ret.cleanupUnplacedClusters(ctx, targets); err != nil {
+		return err
+	}
+	// record the applied components no matter the application is healthy or not,
+	// so that the orphaned components can always be garbage collected in the next round
+	var refComps []runtimev1alpha1.TypedReference
+	for _, comp := range comps {
+		refComps = append(refComps, runtimev1alpha1.TypedReference{
+			APIVersion: comp.APIVersion,
+			Kind:       comp.Kind,
+			Name:       comp.Name,
+			UID:        ret.app.UID,
+		})
 	}
-	return ret.Sync(ctx, ac, comps)
+	ret.app.Status.Components = refComps
+	return nil
+}
+
+// finalize cleans up the ApplicationConfiguration and Components created by the application in all the clusters
+// it's dispatched to, the ones in the local cluster are always cleaned up.
+// Cloud resources applied by Terraform are not cleaned up here since their state is only kept by the CLI,
+// they are destroyed by `vela delete`, and the finalizer is kept until then.
+func (ret *appHandler) finalize(ctx context.Context) error {
+	if err := ret.cleanup(ctx, ret.r); err != nil {
+		return err
+	}
+	clusters := ret.app.Status.Clusters
+	for i, name := range clusters {
+		target, err := ret.r.getClusterTarget(ctx, name)
+		switch {
+		case isClusterUnregistered(err):
+			// the cluster can't be accessed any more, there's nothing to clean up
+			ret.l.Info("the cluster is unregistered, skip cleaning it up", "cluster", name)
+			err = nil
+		case err == nil:
+			err = errors.WithMessagef(ret.cleanup(ctx, target.client), "clean up cluster %s", name)
+		}
+		if err != nil {
+			ret.app.Status.Clusters = clusters[i:]
+			return err
+		}
+	}
+	ret.app.Status.Clusters = nil
+	return nil
+}
+
+// cleanup deletes the ApplicationConfiguration and Components created by the application in the cluster,
+// traits and auxiliary resources are owned by the ApplicationConfiguration and will be deleted along with it.
+func (ret *appHandler) cleanup(ctx context.Context, c client.Client) error {
+	ac := &v1alpha2.ApplicationConfiguration{ObjectMeta: metav1.ObjectMeta{Name: ret.app.Name, Namespace: ret.app.Namespace}}
+	if err := c.Delete(ctx, ac); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "delete ApplicationConfiguration %s", ac.Name)
+	}
+	var compList v1alpha2.ComponentList
+	if err := c.List(ctx, &compList, client.InNamespace(ret.app.Namespace),
+		client.MatchingLabels{appfile.OAMApplicationLabel: ret.app.Name}); err != nil {
+		return errors.Wrap(err, "list components of application")
+	}
+	for i := range compList.Items {
+		if err := c.Delete(ctx, &compList.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "delete component %s", compList.Items[i].Name)
+		}
+	}
+	return nil
+}
+
+// statusAggregate checks the health of the components in every target cluster,
+// the statuses of the components in the clusters other than the local one are tagged with the cluster name.
+func (ret *appHandler) statusAggregate(appfile *appfile.Appfile, targets []clusterTarget) ([]v1alpha2.ApplicationComponentStatus, bool, error) {
+	var appStatus []v1alpha2.ApplicationComponentStatus
+	var healthy = true
+	for _, target := range targets {
+		status, targetHealthy, err := ret.clusterStatusAggregate(appfile, target)
+		if err != nil {
+			return nil, false, errors.WithMessagef(err, "cluster=%s", target.String())
+		}
+		appStatus = append(appStatus, status...)
+		healthy = healthy && targetHealthy
+	}
+	return appStatus, healthy, nil
 }
 
-func (ret *appHandler) statusAggregate(appfile *appfile.Appfile) ([]v1alpha2.ApplicationComponentStatus, bool, error) {
+func (ret *appHandler) clusterStatusAggregate(af *appfile.Appfile, target clusterTarget) ([]v1alpha2.ApplicationComponentStatus, bool, error) {
 	var appStatus []v1alpha2.ApplicationComponentStatus
 	var healthy = true
-	for _, wl := range appfile.Workloads {
+	for _, wl := range af.Workloads {
+		// evaluate the workload with the references resolved as it's rendered
+		wl = af.ResolvedWorkload(wl)
 		var status = v1alpha2.ApplicationComponentStatus{
 			Name:    wl.Name,
+			Cluster: target.name,
 			Healthy: true,
 		}
-		pCtx := process.NewContext(wl.Name, appfile.Name)
+		// the template is rendered against the local cluster as it's done when generating the application configuration
+		pCtx := appfile.NewProcessContext(wl, af, ret.app.Namespace)
+		pCtx.SetClient(ret.r)
 		if err := wl.EvalContext(pCtx); err != nil {
-			return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, evaluate context error", appfile.Name, wl.Name)
+			return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, evaluate context error", af.Name, wl.Name)
 		}
 		for _, tr := range wl.Traits {
 			if err := tr.EvalContext(pCtx); err != nil {
-				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, evaluate context error", appfile.Name, wl.Name, tr.Name)
+				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, evaluate context error", af.Name, wl.Name, tr.Name)
 			}
 		}
 
-		workloadHealth, err := wl.EvalHealth(pCtx, ret.r, ret.app.Namespace)
+		workloadHealth, err := wl.EvalHealth(pCtx, target.client, ret.app.Namespace)
 		if err != nil {
-			return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, check health error", appfile.Name, wl.Name)
+			return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, check health error", af.Name, wl.Name)
 		}
 		if !workloadHealth {
 			// TODO(wonderflow): we should add a custom way to let the template say why it's unhealthy, only a bool flag is not enough
 			status.Healthy = false
 			healthy = false
 		}
-		status.Message, err = wl.EvalStatus(pCtx, ret.r, ret.app.Namespace)
+		status.Message, err = wl.EvalStatus(pCtx, target.client, ret.app.Namespace)
 		if err != nil {
-			return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, evaluate workload status message error", appfile.Name, wl.Name)
+			return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, evaluate workload status message error", af.Name, wl.Name)
 		}
 		var traitStatusList []v1alpha2.ApplicationTraitStatus
 		for _, trait := range wl.Traits {
@@ -111,18 +259,18 @@ func (ret *appHandler) statusAggregate(appfile *appfile.Appfile) ([]v1alpha2.App
 				Type:    trait.Name,
 				Healthy: true,
 			}
-			traitHealth, err := trait.EvalHealth(pCtx, ret.r, ret.app.Namespace)
+			traitHealth, err := trait.EvalHealth(pCtx, target.client, ret.app.Namespace)
 			if err != nil {
-				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, check health error", appfile.Name, wl.Name, trait.Name)
+				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, check health error", af.Name, wl.Name, trait.Name)
 			}
 			if !traitHealth {
 				// TODO(wonderflow): we should add a custom way to let the template say why it's unhealthy, only a bool flag is not enough
 				traitStatus.Healthy = false
 				healthy = false
 			}
-			traitStatus.Message, err = trait.EvalStatus(pCtx, ret.r, ret.app.Namespace)
+			traitStatus.Message, err = trait.EvalStatus(pCtx, target.client, ret.app.Namespace)
 			if err != nil {
-				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, evaluate status message error", appfile.Name, wl.Name, trait.Name)
+				return nil, false, errors.WithMessagef(err, "app=%s, comp=%s, trait=%s, evaluate status message error", af.Name, wl.Name, trait.Name)
 			}
 			traitStatusList = append(traitStatusList, traitStatus)
 		}
@@ -164,15 +312,15 @@ func CreateOrUpdateAppConfig(ctx context.Context, client client.Client, appConfi
 	return client.Update(ctx, appConfig)
 }
 
-// Sync perform synchronization operations
-func (ret *appHandler) Sync(ctx context.Context, ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) error {
+// Sync perform synchronization operations in the cluster of the client
+func (ret *appHandler) Sync(ctx context.Context, c client.Client, ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) error {
 	for _, comp := range comps {
-		if err := CreateOrUpdateComponent(ctx, ret.r, comp.DeepCopy()); err != nil {
+		if err := CreateOrUpdateComponent(ctx, c, comp.DeepCopy()); err != nil {
 			return err
 		}
 	}
 
-	if err := CreateOrUpdateAppConfig(ctx, ret.r, ac); err != nil {
+	if err := CreateOrUpdateAppConfig(ctx, c, ac); err != nil {
 		return err
 	}
 
@@ -191,7 +339,7 @@ func (ret *appHandler) Sync(ctx context.Context, ac *v1alpha2.ApplicationConfigu
 		}
 		// Component not exits in current Application, should be deleted
 		var oldC = &v1alpha2.Component{ObjectMeta: metav1.ObjectMeta{Name: comp.Name, Namespace: ac.Namespace}}
-		if err := ret.r.Delete(ctx, oldC); err != nil {
+		if err := c.Delete(ctx, oldC); err != nil && !apierrors.IsNotFound(err) {
 			return err
 		}
 	}
@@ -0,0 +1,211 @@
+package application
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/oam"
+)
+
+const (
+	// localCluster is the name of the cluster the controller runs in
+	localCluster = ""
+
+	// clusterKubeconfigKey is the key of the kubeconfig in the data of a cluster secret
+	clusterKubeconfigKey = "kubeconfig"
+)
+
+// clusterTarget is a cluster the application is dispatched to
+type clusterTarget struct {
+	name   string
+	client client.Client
+}
+
+func (t clusterTarget) String() string {
+	if t.name == localCluster {
+		return "local"
+	}
+	return t.name
+}
+
+// cachedClusterClient is the client of a registered cluster built from the given version of its secret
+type cachedClusterClient struct {
+	resourceVersion string
+	client          client.Client
+}
+
+// targets returns the clusters selected by the placement of the application,
+// the application is dispatched to the local cluster only if no placement is specified.
+func (ret *appHandler) targets(ctx context.Context) ([]clusterTarget, error) {
+	placement := ret.app.Spec.Placement
+	if placement == nil {
+		return []clusterTarget{{name: localCluster, client: ret.r}}, nil
+	}
+	secrets, err := ret.r.selectClusterSecrets(ctx, placement)
+	if err != nil {
+		return nil, err
+	}
+	if len(secrets) == 0 {
+		return nil, errors.New("no cluster is selected by the placement")
+	}
+	targets := make([]clusterTarget, 0, len(secrets))
+	for i := range secrets {
+		c, err := ret.r.clusterClient(&secrets[i])
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, clusterTarget{name: secrets[i].Name, client: c})
+	}
+	return targets, nil
+}
+
+// ensureNamespace creates the namespace of the application in the cluster if it doesn't exist, the namespace is
+// left in the cluster when the application is deleted or moved away as other resources may be in it.
+func (ret *appHandler) ensureNamespace(ctx context.Context, target clusterTarget) error {
+	ns := &v1.Namespace{}
+	err := target.client.Get(ctx, client.ObjectKey{Name: ret.app.Namespace}, ns)
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "get namespace %s", ret.app.Namespace)
+	}
+	ns = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ret.app.Namespace}}
+	if err := target.client.Create(ctx, ns); err != nil && !apierrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "create namespace %s", ret.app.Namespace)
+	}
+	return nil
+}
+
+// cleanupUnplacedClusters cleans up the resources in the clusters which the application was dispatched to
+// but are not selected any more, then records the clusters the application is dispatched to now.
+func (ret *appHandler) cleanupUnplacedClusters(ctx context.Context, targets []clusterTarget) error {
+	placed := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		placed[t.name] = true
+	}
+	// the application is moved from the local cluster to the placed ones
+	if !placed[localCluster] && len(ret.app.Status.Clusters) == 0 && len(ret.app.Status.Components) != 0 {
+		if err := ret.cleanup(ctx, ret.r); err != nil {
+			return errors.WithMessage(err, "clean up local cluster")
+		}
+	}
+	for _, name := range ret.app.Status.Clusters {
+		if placed[name] {
+			continue
+		}
+		target, err := ret.r.getClusterTarget(ctx, name)
+		if isClusterUnregistered(err) {
+			// the cluster can't be accessed any more, there's nothing to clean up
+			ret.l.Info("the cluster is unregistered, skip cleaning it up", "cluster", name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := ret.cleanup(ctx, target.client); err != nil {
+			return errors.WithMessagef(err, "clean up cluster %s", name)
+		}
+	}
+
+	var clusters []string
+	for _, t := range targets {
+		if t.name != localCluster {
+			clusters = append(clusters, t.name)
+		}
+	}
+	ret.app.Status.Clusters = clusters
+	return nil
+}
+
+// selectClusterSecrets returns the secrets of the clusters selected by names or labels, sorted by name
+func (r *Reconciler) selectClusterSecrets(ctx context.Context, placement *v1alpha2.ApplicationPlacement) ([]v1.Secret, error) {
+	var secretList v1.SecretList
+	if err := r.List(ctx, &secretList, client.InNamespace(r.clusterSecretNamespace),
+		client.MatchingLabels{oam.LabelClusterCredential: "true"}); err != nil {
+		return nil, errors.Wrap(err, "list cluster secrets")
+	}
+	selected := map[string]v1.Secret{}
+	registered := make(map[string]v1.Secret, len(secretList.Items))
+	for _, s := range secretList.Items {
+		registered[s.Name] = s
+	}
+	for _, name := range placement.ClusterNames {
+		s, ok := registered[name]
+		if !ok {
+			return nil, errors.Errorf("cluster %s is not registered", name)
+		}
+		selected[name] = s
+	}
+	if placement.ClusterSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(placement.ClusterSelector)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid cluster selector")
+		}
+		for name, s := range registered {
+			if selector.Matches(labels.Set(s.Labels)) {
+				selected[name] = s
+			}
+		}
+	}
+
+	secrets := make([]v1.Secret, 0, len(selected))
+	for _, s := range selected {
+		secrets = append(secrets, s)
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].Name < secrets[j].Name
+	})
+	return secrets, nil
+}
+
+// getClusterTarget gets the registered cluster by name
+func (r *Reconciler) getClusterTarget(ctx context.Context, name string) (clusterTarget, error) {
+	secret := &v1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: r.clusterSecretNamespace, Name: name}, secret); err != nil {
+		return clusterTarget{}, errors.Wrapf(err, "get secret of cluster %s", name)
+	}
+	c, err := r.clusterClient(secret)
+	if err != nil {
+		return clusterTarget{}, err
+	}
+	return clusterTarget{name: name, client: c}, nil
+}
+
+// isClusterUnregistered checks if the error of getting a cluster target is caused by its secret being deleted
+func isClusterUnregistered(err error) bool {
+	return err != nil && apierrors.IsNotFound(errors.Cause(err))
+}
+
+// clusterClient builds the client of the cluster from the kubeconfig in its secret,
+// the client is cached until the secret is changed.
+func (r *Reconciler) clusterClient(secret *v1.Secret) (client.Client, error) {
+	if cached, ok := r.clusterClients.Load(secret.Name); ok {
+		if c := cached.(cachedClusterClient); c.resourceVersion == secret.ResourceVersion {
+			return c.client, nil
+		}
+	}
+	kubeconfig, ok := secret.Data[clusterKubeconfigKey]
+	if !ok {
+		return nil, errors.Errorf("no %s found in the secret of cluster %s", clusterKubeconfigKey, secret.Name)
+	}
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "load kubeconfig of cluster %s", secret.Name)
+	}
+	c, err := client.New(cfg, client.Options{Scheme: r.Scheme})
+	if err != nil {
+		return nil, errors.Wrapf(err, "create client of cluster %s", secret.Name)
+	}
+	r.clusterClients.Store(secret.Name, cachedClusterClient{resourceVersion: secret.ResourceVersion, client: c})
+	return c, nil
+}
@@ -0,0 +1,328 @@
+/*
+Copyright 2020 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+// defaultRevisionHistoryLimit is the number of ApplicationRevisions kept if the application doesn't specify it
+const defaultRevisionHistoryLimit = 10
+
+// volatileAnnotations are set by tools and the controllers rather than users, they don't make a new revision
+var volatileAnnotations = []string{
+	corev1.LastAppliedConfigAnnotation,
+	oam.AnnotationLastAppliedConfig,
+	oam.AnnotationAppRollback,
+	oam.AnnotationAppRollout,
+}
+
+// prepareRevision snapshots the application spec together with the definitions it refers to,
+// and decides whether a new ApplicationRevision is needed. The revision name is set into the Appfile
+// so that templates can refer to it by `context.appRevision`.
+// It returns a nil ApplicationRevision if the latest revision is still up to date.
+func (ret *appHandler) prepareRevision(ctx context.Context, af *appfile.Appfile) (*v1alpha2.ApplicationRevision, int64, error) {
+	appRev := &v1alpha2.ApplicationRevision{}
+	appRev.SetGroupVersionKind(v1alpha2.ApplicationRevisionGroupVersionKind)
+	appRev.Namespace = ret.app.Namespace
+	appRev.Spec.Application = *ret.app.Spec.DeepCopy()
+	appRev.Spec.WorkloadDefinitions = map[string]runtime.RawExtension{}
+	appRev.Spec.TraitDefinitions = map[string]runtime.RawExtension{}
+
+	wdSpecs := map[string]v1alpha2.WorkloadDefinitionSpec{}
+	tdSpecs := map[string]v1alpha2.TraitDefinitionSpec{}
+	// the parser has decided whether a missing definition is acceptable, a missing one is just not snapshotted
+	for _, wl := range af.Workloads {
+		if _, exist := wdSpecs[wl.Type]; !exist {
+			wd, err := util.GetWorkloadDefinition(ctx, ret.definitionReader(), wl.Type)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return nil, 0, errors.WithMessagef(err, "get workload definition %s", wl.Type)
+			}
+			if err == nil {
+				wdSpecs[wl.Type] = wd.Spec
+				appRev.Spec.WorkloadDefinitions[wl.Type] = runtime.RawExtension{Object: stripDefinitionMeta(wd)}
+			}
+		}
+		for _, tr := range wl.Traits {
+			if _, exist := tdSpecs[tr.Name]; exist {
+				continue
+			}
+			td, err := util.GetTraitDefinition(ctx, ret.definitionReader(), tr.Name)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return nil, 0, errors.WithMessagef(err, "get trait definition %s", tr.Name)
+			}
+			if err == nil {
+				tdSpecs[tr.Name] = td.Spec
+				appRev.Spec.TraitDefinitions[tr.Name] = runtime.RawExtension{Object: stripDefinitionMeta(td)}
+			}
+		}
+	}
+
+	// besides the spec and the definitions, the rendering also depends on the metadata of the application
+	// exposed by the context. The values read from the live resources are not part of a revision,
+	// they change with the status of the resources rather than the application.
+	spec := ret.app.Spec.DeepCopy()
+	spec.RevisionHistoryLimit = nil
+	annotations := make(map[string]string, len(af.Annotations))
+	for k, v := range af.Annotations {
+		annotations[k] = v
+	}
+	for _, k := range volatileAnnotations {
+		delete(annotations, k)
+	}
+	hasher := fnv.New32a()
+	util.DeepHashObject(hasher, struct {
+		App                 v1alpha2.ApplicationSpec
+		WorkloadDefinitions map[string]v1alpha2.WorkloadDefinitionSpec
+		TraitDefinitions    map[string]v1alpha2.TraitDefinitionSpec
+		Labels              map[string]string
+		Annotations         map[string]string
+		Env                 string
+	}{*spec, wdSpecs, tdSpecs, af.Labels, annotations, af.Env})
+	hash := rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+
+	var revision int64 = 1
+	if latest := ret.app.Status.LatestRevision; latest != nil {
+		latestRev := &v1alpha2.ApplicationRevision{}
+		err := ret.r.Get(ctx, client.ObjectKey{Namespace: ret.app.Namespace, Name: latest.Name}, latestRev)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, 0, errors.WithMessagef(err, "get latest application revision %s", latest.Name)
+		}
+		if err == nil && latestRev.Labels[oam.LabelAppRevisionHash] == hash {
+			af.RevisionName = latest.Name
+			return nil, latest.Revision, nil
+		}
+		revision = latest.Revision + 1
+	}
+
+	appRev.Name = util.ConstructAppRevisionName(ret.app.Name, revision)
+	appRev.Labels = map[string]string{
+		oam.LabelAppName:         ret.app.Name,
+		oam.LabelAppRevisionHash: hash,
+	}
+	appRev.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: v1alpha2.SchemeGroupVersion.String(),
+		Kind:       v1alpha2.ApplicationKind,
+		Name:       ret.app.Name,
+		UID:        ret.app.UID,
+		Controller: pointer.BoolPtr(true),
+	}})
+	af.RevisionName = appRev.Name
+	return appRev, revision, nil
+}
+
+// createRevision records the rendered ApplicationConfiguration and Components into the revision and creates it,
+// then points the latest revision of the application to it.
+func (ret *appHandler) createRevision(ctx context.Context, appRev *v1alpha2.ApplicationRevision, revision int64,
+	ac *v1alpha2.ApplicationConfiguration, comps []*v1alpha2.Component) error {
+	appRev.Spec.ApplicationConfiguration = runtime.RawExtension{Object: ac.DeepCopy()}
+	for _, comp := range comps {
+		appRev.Spec.Components = append(appRev.Spec.Components, runtime.RawExtension{Object: comp.DeepCopy()})
+	}
+	if err := ret.r.Create(ctx, appRev); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+		// the revision is created but the status of application failed to be updated last time
+		existRev := &v1alpha2.ApplicationRevision{}
+		if err := ret.r.Get(ctx, client.ObjectKey{Namespace: appRev.Namespace, Name: appRev.Name}, existRev); err != nil {
+			return err
+		}
+		if existRev.Labels[oam.LabelAppRevisionHash] != appRev.Labels[oam.LabelAppRevisionHash] {
+			return errors.Errorf("application revision %s already exists with a different spec", appRev.Name)
+		}
+	}
+	ret.app.Status.LatestRevision = &v1alpha2.Revision{
+		Name:     appRev.Name,
+		Revision: revision,
+	}
+	if err := ret.cleanupRevisions(ctx); err != nil {
+		ret.l.Error(err, "failed to clean up application revisions")
+	}
+	return nil
+}
+
+// cleanupRevisions deletes the oldest revisions of the application beyond its revision history limit,
+// the latest revision and the one the application is rolled back to are always kept.
+func (ret *appHandler) cleanupRevisions(ctx context.Context) error {
+	limit := defaultRevisionHistoryLimit
+	if ret.app.Spec.RevisionHistoryLimit != nil {
+		limit = int(*ret.app.Spec.RevisionHistoryLimit)
+	}
+	revs := &v1alpha2.ApplicationRevisionList{}
+	if err := ret.r.List(ctx, revs, client.InNamespace(ret.app.Namespace),
+		client.MatchingLabels{oam.LabelAppName: ret.app.Name}); err != nil {
+		return errors.WithMessage(err, "list application revisions")
+	}
+	if len(revs.Items) <= limit {
+		return nil
+	}
+	inUse := map[string]bool{ret.app.GetAnnotations()[oam.AnnotationAppRollback]: true}
+	if latest := ret.app.Status.LatestRevision; latest != nil {
+		inUse[latest.Name] = true
+	}
+	prefix := ret.app.Name + "-v"
+	revisionOf := func(rev *v1alpha2.ApplicationRevision) int64 {
+		n, _ := strconv.ParseInt(strings.TrimPrefix(rev.Name, prefix), 10, 64)
+		return n
+	}
+	sort.Slice(revs.Items, func(i, j int) bool {
+		return revisionOf(&revs.Items[i]) < revisionOf(&revs.Items[j])
+	})
+	toDelete := len(revs.Items) - limit
+	for i := range revs.Items {
+		if toDelete <= 0 {
+			break
+		}
+		rev := &revs.Items[i]
+		if inUse[rev.Name] {
+			continue
+		}
+		if err := ret.r.Delete(ctx, rev); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "delete application revision %s", rev.Name)
+		}
+		ret.l.Info("application revision deleted", "revision", rev.Name)
+		toDelete--
+	}
+	return nil
+}
+
+// loadRollbackRevision loads the revision which the application is rolled back to, so that the application is
+// rendered with the definitions snapshotted in it and a rollback undoes the changes of definitions as well.
+// The rollback annotation is removed by a patch once the spec of the application is changed since the rollback,
+// the live definitions are used from then on. It's called before rendering, so the application is rendered
+// with the metadata as it's patched.
+func (ret *appHandler) loadRollbackRevision(ctx context.Context) error {
+	revName, exist := ret.app.GetAnnotations()[oam.AnnotationAppRollback]
+	if !exist {
+		return nil
+	}
+	appRev := &v1alpha2.ApplicationRevision{}
+	err := ret.r.Get(ctx, client.ObjectKey{Namespace: ret.app.Namespace, Name: revName}, appRev)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.WithMessagef(err, "get application revision %s", revName)
+	}
+	if err == nil {
+		equal, err := isSpecEqual(appRev.Spec.Application, ret.app.Spec)
+		if err != nil {
+			return err
+		}
+		if equal {
+			ret.rollbackRev = appRev
+			return nil
+		}
+	}
+	ret.l.Info("the application is changed since it was rolled back, render it with the live definitions",
+		"revision", revName)
+	patch := client.MergeFrom(ret.app.DeepCopy())
+	delete(ret.app.Annotations, oam.AnnotationAppRollback)
+	return errors.Wrap(ret.r.Patch(ctx, ret.app, patch), "remove rollback annotation of application")
+}
+
+// definitionReader returns the reader to look up the definitions with
+func (ret *appHandler) definitionReader() client.Reader {
+	if ret.rollbackRev != nil {
+		return &revisionDefinitionReader{Reader: ret.r, appRev: ret.rollbackRev}
+	}
+	return ret.r
+}
+
+// revisionDefinitionReader reads the workload and trait definitions from the snapshots in an ApplicationRevision,
+// the definitions not snapshotted and the other objects are read by the underlying reader.
+type revisionDefinitionReader struct {
+	client.Reader
+	appRev *v1alpha2.ApplicationRevision
+}
+
+func (r *revisionDefinitionReader) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	var snapshot runtime.RawExtension
+	var exist bool
+	switch obj.(type) {
+	case *v1alpha2.WorkloadDefinition:
+		snapshot, exist = r.appRev.Spec.WorkloadDefinitions[key.Name]
+	case *v1alpha2.TraitDefinition:
+		snapshot, exist = r.appRev.Spec.TraitDefinitions[key.Name]
+	}
+	if !exist {
+		return r.Reader.Get(ctx, key, obj)
+	}
+	raw := snapshot.Raw
+	if raw == nil {
+		var err error
+		if raw, err = json.Marshal(snapshot.Object); err != nil {
+			return err
+		}
+	}
+	return errors.Wrapf(json.Unmarshal(raw, obj), "decode the definition %s snapshotted in revision %s",
+		key.Name, r.appRev.Name)
+}
+
+// isSpecEqual compares the application specs by their JSON, the raw settings of components are compared
+// regardless of the order of fields
+func isSpecEqual(a, b v1alpha2.ApplicationSpec) (bool, error) {
+	var objs [2]interface{}
+	for i, spec := range []v1alpha2.ApplicationSpec{a, b} {
+		data, err := json.Marshal(spec)
+		if err != nil {
+			return false, err
+		}
+		if err := json.Unmarshal(data, &objs[i]); err != nil {
+			return false, err
+		}
+	}
+	return reflect.DeepEqual(objs[0], objs[1]), nil
+}
+
+// stripDefinitionMeta only keeps the fields of a definition which are meaningful to a snapshot
+func stripDefinitionMeta(obj runtime.Object) runtime.Object {
+	switch def := obj.(type) {
+	case *v1alpha2.WorkloadDefinition:
+		snapshot := &v1alpha2.WorkloadDefinition{Spec: *def.Spec.DeepCopy()}
+		snapshot.SetGroupVersionKind(v1alpha2.WorkloadDefinitionGroupVersionKind)
+		snapshot.Name = def.Name
+		snapshot.Annotations = def.Annotations
+		return snapshot
+	case *v1alpha2.TraitDefinition:
+		snapshot := &v1alpha2.TraitDefinition{Spec: *def.Spec.DeepCopy()}
+		snapshot.SetGroupVersionKind(v1alpha2.TraitDefinitionGroupVersionKind)
+		snapshot.Name = def.Name
+		snapshot.Annotations = def.Annotations
+		return snapshot
+	}
+	return obj
+}
@@ -26,6 +26,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -46,6 +48,11 @@ var k8sClient client.Client
 var testEnv *envtest.Environment
 var testScheme = runtime.NewScheme()
 
+// memberEnv is another cluster which applications can be placed to
+var memberEnv *envtest.Environment
+var memberClient client.Client
+var memberKubeconfig []byte
+
 var reconciler *Reconciler
 
 func TestAPIs(t *testing.T) {
@@ -88,12 +95,39 @@ var _ = BeforeSuite(func(done Done) {
 		Log:    ctrl.Log.WithName("Application"),
 		Scheme: testScheme,
 		dm:     dm,
+
+		clusterSecretNamespace: "default",
 	}
+
+	By("bootstrapping member cluster")
+	memberEnv = &envtest.Environment{
+		UseExistingCluster: pointer.BoolPtr(false),
+		CRDDirectoryPaths:  []string{filepath.Join("../../../../..", "charts", "vela-core", "crds")},
+	}
+	memberCfg, err := memberEnv.Start()
+	Expect(err).ToNot(HaveOccurred())
+	memberClient, err = client.New(memberCfg, client.Options{Scheme: testScheme})
+	Expect(err).ToNot(HaveOccurred())
+	memberKubeconfig, err = clientcmd.Write(clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{"member": {
+			Server:                   memberCfg.Host,
+			CertificateAuthorityData: memberCfg.CAData,
+		}},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{"member": {
+			ClientCertificateData: memberCfg.CertData,
+			ClientKeyData:         memberCfg.KeyData,
+			Token:                 memberCfg.BearerToken,
+		}},
+		Contexts:       map[string]*clientcmdapi.Context{"member": {Cluster: "member", AuthInfo: "member"}},
+		CurrentContext: "member",
+	})
+	Expect(err).ToNot(HaveOccurred())
 	close(done)
-}, 60)
+}, 120)
 
 var _ = AfterSuite(func() {
 	By("tearing down the test environment")
 	err := testEnv.Stop()
 	Expect(err).ToNot(HaveOccurred())
+	Expect(memberEnv.Stop()).ToNot(HaveOccurred())
 })
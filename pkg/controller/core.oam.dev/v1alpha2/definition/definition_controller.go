@@ -0,0 +1,201 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package definition
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/types"
+	core "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+// Reconciler generates the OpenAPI schema of the parameters of a WorkloadDefinition or TraitDefinition
+// and stores it in a ConfigMap
+type Reconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// capType is the type of the definitions reconciled, workload or trait
+	capType types.CapType
+	// schemaNamespace is the namespace of the ConfigMaps which store the schema
+	schemaNamespace string
+}
+
+// +kubebuilder:rbac:groups=core.oam.dev,resources=workloaddefinitions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core.oam.dev,resources=traitdefinitions,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile generates the schema of the definition and creates or updates its ConfigMap
+func (r *Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues(string(r.capType)+"definition", req.Name)
+
+	def, tmpl, err := r.getDefinition(ctx, req.Name)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			// the ConfigMap is garbage collected by its owner reference
+			err = nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:      common.SchemaConfigMapName(r.capType, req.Name),
+		Namespace: r.schemaNamespace,
+	}}
+	schema, err := generateSchema(tmpl)
+	if err != nil {
+		// the definition has to be fixed by its author, it's no use to retry,
+		// but the schema of its former version is no longer authoritative
+		log.Error(err, "cannot generate OpenAPI schema of parameters")
+		return ctrl.Result{}, r.deleteSchema(ctx, cm)
+	}
+	if schema == nil {
+		// no schema can be generated from the template, e.g. helm
+		return ctrl.Result{}, r.deleteSchema(ctx, cm)
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		labels := cm.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[r.typeLabel()] = req.Name
+		cm.SetLabels(labels)
+		annotations := cm.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		// the schema is stale once the definition is updated until it's generated again
+		annotations[common.SchemaGenerationAnnotation] = strconv.FormatInt(def.GetGeneration(), 10)
+		cm.SetAnnotations(annotations)
+		cm.Data = map[string]string{common.OpenAPISchemaKey: string(schema)}
+		return ctrl.SetControllerReference(def, cm, r.Scheme)
+	}); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "store OpenAPI schema in ConfigMap")
+	}
+	log.Info("OpenAPI schema of parameters is stored", "configmap", cm.Name)
+	return ctrl.Result{}, nil
+}
+
+// deleteSchema deletes the ConfigMap of the schema if it exists
+func (r *Reconciler) deleteSchema(ctx context.Context, cm *corev1.ConfigMap) error {
+	if err := r.Delete(ctx, cm); err != nil && !kerrors.IsNotFound(err) {
+		return errors.Wrap(err, "delete ConfigMap of OpenAPI schema")
+	}
+	return nil
+}
+
+// getDefinition gets the definition and its template
+func (r *Reconciler) getDefinition(ctx context.Context, name string) (metav1.Object, *util.Template, error) {
+	var (
+		def          metav1.Object
+		template     string
+		templateType string
+		status       *v1alpha2.Status
+		extension    *runtime.RawExtension
+	)
+	switch r.capType {
+	case types.TypeTrait:
+		td := &v1alpha2.TraitDefinition{}
+		if err := r.Get(ctx, client.ObjectKey{Name: name}, td); err != nil {
+			return nil, nil, err
+		}
+		def, template, templateType, status, extension = td, td.Spec.Template, td.Spec.TemplateType, td.Spec.Status, td.Spec.Extension
+	default:
+		wd := &v1alpha2.WorkloadDefinition{}
+		if err := r.Get(ctx, client.ObjectKey{Name: name}, wd); err != nil {
+			return nil, nil, err
+		}
+		def, template, templateType, status, extension = wd, wd.Spec.Template, wd.Spec.TemplateType, wd.Spec.Status, wd.Spec.Extension
+	}
+	tmpl, err := util.NewTemplate(template, status, extension)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "parse template of definition")
+	}
+	tmpl.TemplateType = templateType
+	return def, tmpl, nil
+}
+
+// typeLabel returns the label which marks the ConfigMap with the name of the definition
+func (r *Reconciler) typeLabel() string {
+	if r.capType == types.TypeTrait {
+		return oam.TraitTypeLabel
+	}
+	return oam.WorkloadTypeLabel
+}
+
+// generateSchema generates the OpenAPI schema of the parameters according to the type of the template,
+// nil is returned if the template has no schema
+func generateSchema(tmpl *util.Template) ([]byte, error) {
+	if tmpl.TemplateStr == "" {
+		return nil, nil
+	}
+	switch tmpl.TemplateType {
+	case "", types.CUETemplateType:
+		return common.GenOpenAPISchemaFromTemplate(tmpl.TemplateStr)
+	case types.KubeTemplateType:
+		t, err := definition.ParseKubeTemplate(tmpl.TemplateStr)
+		if err != nil {
+			return nil, err
+		}
+		return t.OpenAPISchema().MarshalJSON()
+	default:
+		return nil, nil
+	}
+}
+
+// Setup adds the controllers which store the OpenAPI schema of definitions to the manager
+func Setup(mgr ctrl.Manager, args core.Args, _ logging.Logger) error {
+	for capType, def := range map[types.CapType]runtime.Object{
+		types.TypeWorkload: &v1alpha2.WorkloadDefinition{},
+		types.TypeTrait:    &v1alpha2.TraitDefinition{},
+	} {
+		r := &Reconciler{
+			Client:          mgr.GetClient(),
+			Log:             ctrl.Log.WithName(string(capType) + "definition"),
+			Scheme:          mgr.GetScheme(),
+			capType:         capType,
+			schemaNamespace: args.DefinitionSchemaNamespace,
+		}
+		if err := ctrl.NewControllerManagedBy(mgr).
+			Named(string(capType) + "definition").
+			For(def).
+			Owns(&corev1.ConfigMap{}).
+			Complete(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
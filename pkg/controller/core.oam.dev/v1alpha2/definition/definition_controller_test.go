@@ -0,0 +1,135 @@
+package definition
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	//lint:ignore SA1019 We will use pkg/envtest before upgrading controller-runtime to v1.0.0
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+func TestReconcile(t *testing.T) {
+	wd := &v1alpha2.WorkloadDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker", UID: "worker-uid", Generation: 2},
+		Spec: v1alpha2.WorkloadDefinitionSpec{
+			Reference: v1alpha2.DefinitionReference{Name: "deployments.apps"},
+			Template: `
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+}
+parameter: {
+	// +usage=Which image would you like to use for your service
+	image: string
+}
+`,
+		},
+	}
+	kube := &v1alpha2.WorkloadDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-worker", UID: "kube-worker-uid"},
+		Spec: v1alpha2.WorkloadDefinitionSpec{
+			Reference:    v1alpha2.DefinitionReference{Name: "deployments.apps"},
+			TemplateType: types.KubeTemplateType,
+			Template: `
+output:
+  apiVersion: apps/v1
+  kind: Deployment
+parameters:
+  - name: replicas
+    required: true
+    schema:
+      type: integer
+    fieldPaths:
+      - output.spec.replicas
+`,
+		},
+	}
+	td := &v1alpha2.TraitDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "scaler", UID: "scaler-uid"},
+		Spec: v1alpha2.TraitDefinitionSpec{
+			Template: `
+patch: spec: replicas: parameter.replicas
+parameter: {
+	replicas: *1 | int
+}
+`,
+		},
+	}
+	helm := &v1alpha2.WorkloadDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "helm-worker", UID: "helm-worker-uid"},
+		Spec: v1alpha2.WorkloadDefinitionSpec{
+			Reference:    v1alpha2.DefinitionReference{Name: "deployments.apps"},
+			TemplateType: types.HelmTemplateType,
+			Template:     `chart: podinfo`,
+		},
+	}
+	stale := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:      common.SchemaConfigMapName(types.TypeWorkload, "helm-worker"),
+		Namespace: types.DefaultKubeVelaNS,
+	}}
+	cli := fake.NewFakeClientWithScheme(common.Scheme, wd, kube, td, helm, stale)
+	newReconciler := func(capType types.CapType) *Reconciler {
+		return &Reconciler{Client: cli, Log: ctrl.Log, Scheme: common.Scheme, capType: capType, schemaNamespace: types.DefaultKubeVelaNS}
+	}
+	ctx := context.Background()
+	getSchema := func(capType types.CapType, name string) (*corev1.ConfigMap, *openapi3.Schema) {
+		cm := &corev1.ConfigMap{}
+		err := cli.Get(ctx, client.ObjectKey{Namespace: types.DefaultKubeVelaNS, Name: common.SchemaConfigMapName(capType, name)}, cm)
+		assert.NoError(t, err)
+		schema := &openapi3.Schema{}
+		assert.NoError(t, json.Unmarshal([]byte(cm.Data[common.OpenAPISchemaKey]), schema))
+		return cm, schema
+	}
+
+	_, err := newReconciler(types.TypeWorkload).Reconcile(ctrl.Request{NamespacedName: client.ObjectKey{Name: "worker"}})
+	assert.NoError(t, err)
+	cm, schema := getSchema(types.TypeWorkload, "worker")
+	assert.Equal(t, "worker", cm.Labels[oam.WorkloadTypeLabel])
+	assert.Equal(t, 1, len(cm.OwnerReferences))
+	assert.Equal(t, "WorkloadDefinition", cm.OwnerReferences[0].Kind)
+	assert.Equal(t, "2", cm.Annotations[common.SchemaGenerationAnnotation])
+	assert.Equal(t, "Which image would you like to use for your service", schema.Properties["image"].Value.Description)
+
+	_, err = newReconciler(types.TypeWorkload).Reconcile(ctrl.Request{NamespacedName: client.ObjectKey{Name: "kube-worker"}})
+	assert.NoError(t, err)
+	_, schema = getSchema(types.TypeWorkload, "kube-worker")
+	assert.Equal(t, []string{"replicas"}, schema.Required)
+
+	_, err = newReconciler(types.TypeTrait).Reconcile(ctrl.Request{NamespacedName: client.ObjectKey{Name: "scaler"}})
+	assert.NoError(t, err)
+	cm, schema = getSchema(types.TypeTrait, "scaler")
+	assert.Equal(t, "scaler", cm.Labels[oam.TraitTypeLabel])
+	assert.Equal(t, float64(1), schema.Properties["replicas"].Value.Default)
+
+	_, err = newReconciler(types.TypeWorkload).Reconcile(ctrl.Request{NamespacedName: client.ObjectKey{Name: "helm-worker"}})
+	assert.NoError(t, err)
+	err = cli.Get(ctx, client.ObjectKey{Namespace: types.DefaultKubeVelaNS, Name: stale.Name}, &corev1.ConfigMap{})
+	assert.True(t, kerrors.IsNotFound(err))
+
+	// the schema is deleted if the definition is updated to be invalid
+	assert.NoError(t, cli.Get(ctx, client.ObjectKey{Name: "worker"}, wd))
+	wd.Spec.Template = `output: {}`
+	assert.NoError(t, cli.Update(ctx, wd))
+	_, err = newReconciler(types.TypeWorkload).Reconcile(ctrl.Request{NamespacedName: client.ObjectKey{Name: "worker"}})
+	assert.NoError(t, err)
+	err = cli.Get(ctx, client.ObjectKey{Namespace: types.DefaultKubeVelaNS,
+		Name: common.SchemaConfigMapName(types.TypeWorkload, "worker")}, &corev1.ConfigMap{})
+	assert.True(t, kerrors.IsNotFound(err))
+
+	_, err = newReconciler(types.TypeWorkload).Reconcile(ctrl.Request{NamespacedName: client.ObjectKey{Name: "not-exist"}})
+	assert.NoError(t, err)
+}
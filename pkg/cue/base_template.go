@@ -4,7 +4,21 @@ package cue
 const BaseTemplate = `
 
 context: {
+  // name is the name of the component
   name: string
+  // appName is the name of the application
+  appName: string
+  // appRevision is the name of the application revision
+  appRevision: string
+  // namespace is the namespace of the application
+  namespace: string
+  // componentType is the workload type of the component
+  componentType: string
+  // appLabels and appAnnotations are the labels and annotations of the application
+  appLabels: [string]: string
+  appAnnotations: [string]: string
+  // env is the name of the environment which the application is deployed to
+  env: string
   config?: [...{
     name: string
     value: string
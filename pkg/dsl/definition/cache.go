@@ -0,0 +1,164 @@
+package definition
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/oam-dev/kubevela/pkg/dsl/model"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+)
+
+const (
+	// defaultRenderCacheSize is the max number of rendered results kept in the cache
+	defaultRenderCacheSize = 4096
+
+	workloadCacheKind = "workload"
+	traitCacheKind    = "trait"
+)
+
+var (
+	// renderCacheRequests counts the lookups of the render cache by result, the hit rate is hit/(hit+miss)
+	renderCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubevela_render_cache_requests_total",
+		Help: "Number of lookups of the definition template render cache, partitioned by kind and result(hit or miss).",
+	}, []string{"kind", "result"})
+	// renderCacheEntries is the number of rendered results in the render cache
+	renderCacheEntries = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "kubevela_render_cache_entries",
+		Help: "Number of rendered results kept in the definition template render cache.",
+	})
+
+	defaultRenderCache = newRenderCache(defaultRenderCacheSize)
+)
+
+func init() {
+	metrics.Registry.MustRegister(renderCacheRequests, renderCacheEntries)
+}
+
+// renderedAuxiliary is an auxiliary rendered by the template
+type renderedAuxiliary struct {
+	v         string
+	typ       string
+	name      string
+	isOutputs bool
+}
+
+// renderResult is the result of rendering a template, which is applied to the process context
+type renderResult struct {
+	// base is the main workload rendered, it's empty for trait
+	base        string
+	auxiliaries []renderedAuxiliary
+	// patch is the patch to the main workload rendered by trait
+	patch string
+	// cacheable is false if the result relies on the data out of the template and its context,
+	// e.g. the results of processing tasks
+	cacheable bool
+}
+
+// apply sets the rendered result into the process context, the instances are created for each time it's applied
+// as they can be changed by the traits afterwards
+func (r *renderResult) apply(ctx process.Context, name string) error {
+	if r.base != "" {
+		ctx.SetBase(model.NewInstanceFromString(r.base, true))
+	}
+	for _, aux := range r.auxiliaries {
+		ctx.AppendAuxiliaries(process.Auxiliary{
+			Ins:       model.NewInstanceFromString(aux.v, false),
+			Type:      aux.typ,
+			Name:      aux.name,
+			IsOutputs: aux.isOutputs,
+		})
+	}
+	if r.patch != "" {
+		base, _ := ctx.Output()
+		if base == nil {
+			return errors.Errorf("no workload for trait %s to patch", name)
+		}
+		if err := base.Unify(model.NewInstanceFromString(r.patch, false)); err != nil {
+			return errors.WithMessagef(err, "invalid patch trait %s into workload", name)
+		}
+	}
+	return nil
+}
+
+// renderCacheKey is the hash of everything the rendered result depends on, the template is included so that
+// the cached results are not used any more once the definition is updated
+func renderCacheKey(kind, name, template string, params interface{}, ctx process.Context) string {
+	h := sha256.New()
+	for _, s := range []string{kind, name, template, ctx.BaseContextFile()} {
+		_, _ = h.Write([]byte(s))
+		_, _ = h.Write([]byte{0})
+	}
+	// the keys of map are sorted by json, so the same params always have the same bytes
+	bt, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+	_, _ = h.Write(bt)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type renderCacheEntry struct {
+	key    string
+	result *renderResult
+}
+
+// renderCache is a LRU cache of rendered results
+type renderCache struct {
+	mu      sync.Mutex
+	size    int
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+func newRenderCache(size int) *renderCache {
+	return &renderCache{
+		size:    size,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *renderCache) get(kind, key string) (*renderResult, bool) {
+	if key == "" {
+		renderCacheRequests.WithLabelValues(kind, "miss").Inc()
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		renderCacheRequests.WithLabelValues(kind, "miss").Inc()
+		return nil, false
+	}
+	renderCacheRequests.WithLabelValues(kind, "hit").Inc()
+	c.ll.MoveToFront(e)
+	return e.Value.(*renderCacheEntry).result, true
+}
+
+func (c *renderCache) add(key string, result *renderResult) {
+	if key == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.ll.MoveToFront(e)
+		e.Value.(*renderCacheEntry).result = result
+		return
+	}
+	c.entries[key] = c.ll.PushFront(&renderCacheEntry{key: key, result: result})
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.entries, oldest.Value.(*renderCacheEntry).key)
+	}
+	renderCacheEntries.Set(float64(c.ll.Len()))
+}
@@ -0,0 +1,54 @@
+package definition
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+)
+
+func TestRenderCache(t *testing.T) {
+	workloadTemplate := `
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	metadata: name: context.name
+	spec: paused: parameter.paused
+}
+parameter: paused: *false | bool
+`
+	traitTemplate := `
+patch: spec: replicas: parameter.replicas
+parameter: replicas: int
+`
+	render := func(replicas int) map[string]interface{} {
+		ctx := process.NewContext("cache-test", "myapp", "myapp-v1")
+		assert.NoError(t, NewWorkloadAbstractEngine("cache-worker").Params(map[string]interface{}{}).Complete(ctx, workloadTemplate))
+		assert.NoError(t, NewTraitAbstractEngine("cache-scaler").Params(map[string]interface{}{"replicas": replicas}).Complete(ctx, traitTemplate))
+		base, _ := ctx.Output()
+		obj, err := base.Unstructured()
+		assert.NoError(t, err)
+		return obj.Object["spec"].(map[string]interface{})
+	}
+
+	hits := testutil.ToFloat64(renderCacheRequests.WithLabelValues(workloadCacheKind, "hit"))
+	assert.Equal(t, map[string]interface{}{"paused": false, "replicas": int64(2)}, render(2))
+	// the workload is rendered from the cache, and the patch of the former trait is not kept in it
+	assert.Equal(t, map[string]interface{}{"paused": false, "replicas": int64(3)}, render(3))
+	assert.Equal(t, hits+1, testutil.ToFloat64(renderCacheRequests.WithLabelValues(workloadCacheKind, "hit")))
+
+	traitHits := testutil.ToFloat64(renderCacheRequests.WithLabelValues(traitCacheKind, "hit"))
+	assert.Equal(t, map[string]interface{}{"paused": false, "replicas": int64(2)}, render(2))
+	assert.Equal(t, traitHits+1, testutil.ToFloat64(renderCacheRequests.WithLabelValues(traitCacheKind, "hit")))
+
+	c := newRenderCache(1)
+	c.add("a", &renderResult{base: "a"})
+	c.add("b", &renderResult{base: "b"})
+	_, ok := c.get(workloadCacheKind, "a")
+	assert.False(t, ok)
+	got, ok := c.get(workloadCacheKind, "b")
+	assert.True(t, ok)
+	assert.Equal(t, "b", got.base)
+}
@@ -0,0 +1,153 @@
+package definition
+
+import (
+	"fmt"
+	"strings"
+
+	cueerrors "cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/token"
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+)
+
+const (
+	// ReasonInvalidParameter means the parameters of the component or trait are at fault
+	ReasonInvalidParameter = "InvalidParameter"
+	// ReasonInvalidTemplate means the template of the definition is at fault
+	ReasonInvalidTemplate = "InvalidTemplate"
+)
+
+// the file names of the CUE sources, they appear in the positions of errors
+const (
+	templateFileName  = "template"
+	parameterFileName = "parameter"
+	contextFileName   = "context"
+)
+
+// conflictMessages are the formats of CUE errors whose first two arguments are the conflicting values
+var conflictMessages = []string{"conflicting values", "invalid value"}
+
+// RenderError is the error of rendering a component or trait by the template of its definition,
+// it tells whether the parameters or the template is at fault.
+type RenderError struct {
+	v1alpha2.ApplicationRenderError
+}
+
+// Error implements error
+func (e *RenderError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Message)
+	for i, d := range e.Details {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		if d.Path != "" {
+			b.WriteString(d.Path)
+			b.WriteString(": ")
+		}
+		b.WriteString(d.Message)
+		if len(d.Positions) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(d.Positions, ", "))
+		}
+	}
+	return b.String()
+}
+
+// newParameterError creates a RenderError of a parameter which is not valid against a template not in CUE
+func newParameterError(message, parameter, reason string) *RenderError {
+	return &RenderError{ApplicationRenderError: v1alpha2.ApplicationRenderError{
+		Reason:  ReasonInvalidParameter,
+		Message: message,
+		Details: []v1alpha2.RenderErrorDetail{{
+			Message:       reason,
+			Path:          ParameterFieldName + "." + parameter,
+			ParameterPath: parameter,
+		}},
+	}}
+}
+
+// newCUERenderError creates a RenderError from the errors of evaluating the CUE template with the parameters and context
+func newCUERenderError(message string, err error) *RenderError {
+	re := &RenderError{ApplicationRenderError: v1alpha2.ApplicationRenderError{
+		Reason:  ReasonInvalidTemplate,
+		Message: message,
+	}}
+	errs := cueerrors.Errors(err)
+	if len(errs) == 0 {
+		re.Details = append(re.Details, v1alpha2.RenderErrorDetail{Message: err.Error()})
+		return re
+	}
+	for _, e := range errs {
+		d, byParameter := newRenderErrorDetail(e)
+		if byParameter {
+			re.Reason = ReasonInvalidParameter
+		}
+		re.Details = append(re.Details, d)
+	}
+	return re
+}
+
+// newRenderErrorDetail converts a CUE error into the detail, and tells whether it's caused by the parameters
+func newRenderErrorDetail(e cueerrors.Error) (v1alpha2.RenderErrorDetail, bool) {
+	format, args := e.Msg()
+	d := v1alpha2.RenderErrorDetail{Message: fmt.Sprintf(format, args...)}
+	path := e.Path()
+	d.Path = strings.Join(path, ".")
+	byParameter := false
+	if len(path) > 0 && path[0] == ParameterFieldName {
+		byParameter = true
+		d.ParameterPath = strings.Join(path[1:], ".")
+	}
+	for _, prefix := range conflictMessages {
+		if strings.HasPrefix(format, prefix) && len(args) >= 2 {
+			d.Values = []string{fmt.Sprint(args[0]), fmt.Sprint(args[1])}
+			break
+		}
+	}
+	seen := map[string]bool{}
+	for _, pos := range append([]token.Pos{e.Position()}, e.InputPositions()...) {
+		if !pos.IsValid() {
+			continue
+		}
+		// the parameter file is generated from the JSON of parameters, its positions make no sense to users
+		if pos.Filename() == parameterFileName {
+			byParameter = true
+			continue
+		}
+		p := fmt.Sprintf("%s:%d:%d", pos.Filename(), pos.Line(), pos.Column())
+		if !seen[p] {
+			seen[p] = true
+			d.Positions = append(d.Positions, p)
+		}
+	}
+	return d, byParameter
+}
+
+// AsRenderError finds the first RenderError in the chain of err
+func AsRenderError(err error) (*RenderError, bool) {
+	var re *RenderError
+	if errors.As(err, &re) {
+		return re, true
+	}
+	return nil, false
+}
+
+// SetRenderErrorSource records the component, trait and definition into the RenderError in the chain of err
+func SetRenderErrorSource(err error, component, trait, definition string) {
+	re, ok := AsRenderError(err)
+	if !ok {
+		return
+	}
+	if component != "" {
+		re.Component = component
+	}
+	if trait != "" {
+		re.Trait = trait
+	}
+	if definition != "" {
+		re.Definition = definition
+	}
+}
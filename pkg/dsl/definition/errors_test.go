@@ -0,0 +1,63 @@
+package definition
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+)
+
+func TestRenderError(t *testing.T) {
+	workloadTemplate := `
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	spec: {
+		replicas: parameter.replicas
+		template: spec: containers: [{image: parameter.image}]
+	}
+}
+parameter: {
+	replicas: *1 | int & >0
+	image:    string
+}
+`
+	render := func(name, template string, params map[string]interface{}) *RenderError {
+		ctx := process.NewContext(name, "myapp", "myapp-v1")
+		err := NewWorkloadAbstractEngine(name).Params(params).Complete(ctx, template)
+		assert.Error(t, err)
+		re, ok := AsRenderError(err)
+		assert.True(t, ok)
+		return re
+	}
+
+	re := render("error-worker", workloadTemplate, map[string]interface{}{"image": "nginx", "replicas": "two"})
+	assert.Equal(t, ReasonInvalidParameter, re.Reason)
+	assert.True(t, strings.HasPrefix(re.Error(), "invalid cue template of workload error-worker after merge parameter and context: "))
+
+	re = render("error-worker", workloadTemplate, map[string]interface{}{"replicas": 2})
+	assert.Equal(t, ReasonInvalidParameter, re.Reason)
+	assert.Equal(t, "incomplete parameter of workload error-worker", re.Message)
+	assert.Equal(t, "image", re.Details[0].ParameterPath)
+
+	re = render("error-worker", workloadTemplate+"output: kind: \"StatefulSet\"\n", map[string]interface{}{"image": "nginx"})
+	assert.Equal(t, ReasonInvalidTemplate, re.Reason)
+	assert.Contains(t, re.Details[0].Message, "conflicting values")
+	assert.Equal(t, 2, len(re.Details[0].Values))
+	for _, pos := range re.Details[0].Positions {
+		assert.True(t, strings.HasPrefix(pos, templateFileName+":"), pos)
+	}
+
+	re = render("error-worker", `output: {`, nil)
+	assert.Equal(t, ReasonInvalidTemplate, re.Reason)
+	assert.Equal(t, "invalid cue template of workload error-worker", re.Message)
+
+	err := errors.WithMessage(re, "evaluate base template")
+	SetRenderErrorSource(err, "mycomp", "", "worker")
+	assert.Equal(t, "mycomp", re.Component)
+	assert.Equal(t, "worker", re.Definition)
+	SetRenderErrorSource(errors.New("not a render error"), "mycomp", "", "worker")
+}
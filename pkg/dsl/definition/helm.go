@@ -0,0 +1,242 @@
+package definition
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+	"github.com/oam-dev/kubevela/pkg/utils/helm"
+)
+
+// HelmTemplate is the template of definition in helm format, it renders a Helm chart with the parameters as values
+type HelmTemplate struct {
+	// Chart is the local path or the URL of the chart archive, the name of the chart in Repo,
+	// or the chart in OCI registry referred to by oci://host/repo/chart[:tag][@digest]
+	Chart string `json:"chart"`
+	// Repo is the URL of the chart repository
+	Repo    string `json:"repo,omitempty"`
+	Version string `json:"version,omitempty"`
+	// Values are the default values of the chart, they're overridden by the parameters
+	Values map[string]interface{} `json:"values,omitempty"`
+	// Workload selects the main workload among the resources rendered, it can be omitted if only one resource is rendered.
+	// The other resources are rendered as the outputs named by `<lower case kind>-<name>`.
+	Workload *ResourceSelector `json:"workload,omitempty"`
+}
+
+// ResourceSelector selects a resource by its apiVersion, kind and name, the empty ones match any resource
+type ResourceSelector struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Name       string `json:"name,omitempty"`
+}
+
+func (s *ResourceSelector) matches(obj *unstructured.Unstructured) bool {
+	return (s.APIVersion == "" || s.APIVersion == obj.GetAPIVersion()) &&
+		(s.Kind == "" || s.Kind == obj.GetKind()) &&
+		(s.Name == "" || s.Name == obj.GetName())
+}
+
+var (
+	// renderChart renders the manifests of a chart, it's replaced in tests
+	renderChart = helm.RenderChart
+	// resolveOCIChart resolves the digest of a chart in OCI registry, it's replaced in tests
+	resolveOCIChart = helm.ResolveOCIChart
+
+	manifestSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+)
+
+// ParseHelmTemplate parses the template in helm format
+func ParseHelmTemplate(template string) (*HelmTemplate, error) {
+	t := &HelmTemplate{}
+	if err := yaml.Unmarshal([]byte(template), t); err != nil {
+		return nil, errors.Wrap(err, "invalid helm template")
+	}
+	if t.Chart == "" {
+		return nil, errors.New("chart is required in helm template")
+	}
+	return t, nil
+}
+
+// renderHelmTemplate renders the chart with the parameters as values, the release is named after the component
+// and installed into the namespace of application.
+func renderHelmTemplate(ctx process.Context, name, template string, params interface{}) (*renderResult, error) {
+	t, err := ParseHelmTemplate(template)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid template of workload %s", name)
+	}
+	values := map[string]interface{}{}
+	if params != nil {
+		bt, err := json.Marshal(params)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "marshal parameter of workload %s", name)
+		}
+		if err := json.Unmarshal(bt, &values); err != nil {
+			return nil, errors.WithMessagef(err, "invalid parameter of workload %s", name)
+		}
+	}
+	// the parameters take precedence over the default values
+	values = chartutil.CoalesceTables(values, t.Values)
+
+	manifest, err := renderChart(t.Repo, t.Chart, t.Version, ctx.BaseContextLabels()["name"], ctx.Namespace(), values)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "render chart of workload %s", name)
+	}
+	objects, err := splitManifests(manifest)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid manifests rendered by chart of workload %s", name)
+	}
+	output, outputs, err := t.selectWorkload(objects)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "workload %s", name)
+	}
+	result, err := newRenderResult(output, outputs)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid manifests rendered by chart of workload %s", name)
+	}
+	// the cache key covers the content of the chart, see helmRenderCacheKey
+	result.cacheable = true
+	return result, nil
+}
+
+// helmRenderCacheKey extends the render cache key by the identity of the content of the chart, since the chart is
+// referred to by the template rather than a part of it. The result is not cached if the chart can't be identified.
+func helmRenderCacheKey(key, template string) string {
+	if key == "" {
+		return ""
+	}
+	t, err := ParseHelmTemplate(template)
+	if err != nil {
+		return ""
+	}
+	id, err := chartContentID(t)
+	if err != nil || id == "" {
+		return ""
+	}
+	h := sha256.Sum256([]byte(key + "\x00" + id))
+	return hex.EncodeToString(h[:])
+}
+
+// chartContentID identifies the content of the chart without downloading it, "" is returned if it can't be identified,
+// i.e. the latest version of a chart in a repository
+func chartContentID(t *HelmTemplate) (string, error) {
+	switch {
+	case t.Repo != "":
+		// a version of chart in a repository is immutable
+		if t.Version == "" {
+			return "", nil
+		}
+		return fmt.Sprintf("repo:%s/%s@%s", t.Repo, t.Chart, t.Version), nil
+	case helm.IsOCIChart(t.Chart):
+		// a tag can be moved to another chart, the digest of the manifest is resolved
+		d, err := resolveOCIChart(context.Background(), t.Chart, t.Version)
+		if err != nil {
+			return "", err
+		}
+		return "oci:" + d, nil
+	case strings.HasPrefix(t.Chart, "http://") || strings.HasPrefix(t.Chart, "https://"):
+		// the archive at a URL is taken as immutable, a chart repository publishes each version of a chart at its own URL
+		return fmt.Sprintf("url:%s@%s", t.Chart, t.Version), nil
+	default:
+		d, err := localChartDigest(t.Chart)
+		if err != nil {
+			return "", err
+		}
+		return "local:" + d, nil
+	}
+}
+
+// localChartDigest computes the digest of a local chart, which is an archive or a directory
+func localChartDigest(path string) (string, error) {
+	h := sha256.New()
+	err := filepath.Walk(path, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(path, name)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(filepath.Clean(name))
+		if err != nil {
+			return err
+		}
+		// the files are walked in lexical order, the names are hashed so that renaming a file changes the digest
+		for _, b := range [][]byte{[]byte(rel), data} {
+			_, _ = h.Write(b)
+			_, _ = h.Write([]byte{0})
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// selectWorkload picks the main workload from the objects, the others are the outputs
+func (t *HelmTemplate) selectWorkload(objects []*unstructured.Unstructured) (map[string]interface{}, map[string]map[string]interface{}, error) {
+	if len(objects) == 0 {
+		return nil, nil, errors.New("no resource is rendered by chart")
+	}
+	selected := -1
+	switch {
+	case t.Workload != nil:
+		for i, obj := range objects {
+			if t.Workload.matches(obj) {
+				selected = i
+				break
+			}
+		}
+		if selected < 0 {
+			return nil, nil, errors.Errorf("no resource rendered by chart matches the workload %+v", *t.Workload)
+		}
+	case len(objects) == 1:
+		selected = 0
+	default:
+		return nil, nil, errors.Errorf("workload is required in helm template to select the main workload among %d resources rendered", len(objects))
+	}
+	outputs := make(map[string]map[string]interface{}, len(objects)-1)
+	for i, obj := range objects {
+		if i == selected {
+			continue
+		}
+		name := fmt.Sprintf("%s-%s", strings.ToLower(obj.GetKind()), obj.GetName())
+		if _, exist := outputs[name]; exist {
+			return nil, nil, errors.Errorf("more than one %s named %s are rendered by chart", obj.GetKind(), obj.GetName())
+		}
+		outputs[name] = obj.Object
+	}
+	return objects[selected].Object, outputs, nil
+}
+
+// splitManifests splits the YAML documents of manifests into objects, the empty documents are skipped
+func splitManifests(manifest string) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	for _, doc := range manifestSeparator.Split(manifest, -1) {
+		obj := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return nil, err
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		objects = append(objects, &unstructured.Unstructured{Object: obj})
+	}
+	return objects, nil
+}
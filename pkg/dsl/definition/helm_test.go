@@ -0,0 +1,117 @@
+package definition
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+)
+
+func TestHelmTemplate(t *testing.T) {
+	render := func(template string, params map[string]interface{}) (process.Context, error) {
+		ctx := process.NewContext("web", "myapp", "myapp-v1")
+		ctx.SetNamespace("default")
+		return ctx, NewWorkloadAbstractEngine("helm-web").Params(params).TemplateType(types.HelmTemplateType).Complete(ctx, template)
+	}
+
+	ctx, err := render(`
+chart: testdata/web
+values:
+  replicas: 2
+  port: 8080
+workload:
+  kind: Deployment
+`, map[string]interface{}{"image": "nginx:1.19", "port": 9090})
+	assert.NoError(t, err)
+	base, assists := ctx.Output()
+	obj, err := base.Unstructured()
+	assert.NoError(t, err)
+	assert.Equal(t, "Deployment", obj.GetKind())
+	assert.Equal(t, "web", obj.GetName())
+	spec := obj.Object["spec"].(map[string]interface{})
+	assert.Equal(t, int64(2), spec["replicas"])
+	container := spec["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "nginx:1.19", container["image"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"containerPort": int64(9090)}}, container["ports"])
+	assert.Equal(t, 1, len(assists))
+	assert.Equal(t, "service-web", assists[0].Name)
+
+	_, err = render(`chart: testdata/web`, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "workload is required in helm template")
+	_, err = render(`chart: oci://registry.example.com/charts/web`, nil)
+	assert.Error(t, err)
+}
+
+func TestSplitManifests(t *testing.T) {
+	objects, err := splitManifests(`
+---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+# Source: web/templates/empty.yaml
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(objects))
+	assert.Equal(t, "Service", objects[0].GetKind())
+	assert.Equal(t, "Deployment", objects[1].GetKind())
+}
+
+func TestHelmRenderCacheKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helm-cache-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "templates"), 0750))
+	chartFile := filepath.Join(dir, "Chart.yaml")
+	assert.NoError(t, ioutil.WriteFile(chartFile, []byte("name: web\nversion: 0.1.0\n"), 0600))
+
+	// a local chart is identified by its content
+	local := "chart: " + dir
+	key := helmRenderCacheKey("key", local)
+	assert.NotEmpty(t, key)
+	assert.Equal(t, key, helmRenderCacheKey("key", local))
+	assert.NotEqual(t, key, helmRenderCacheKey("another-key", local))
+	assert.NoError(t, ioutil.WriteFile(chartFile, []byte("name: web\nversion: 0.2.0\n"), 0600))
+	assert.NotEqual(t, key, helmRenderCacheKey("key", local))
+	assert.Empty(t, helmRenderCacheKey("key", "chart: "+filepath.Join(dir, "not-exist")))
+
+	// a chart in repository is identified by its version
+	assert.NotEmpty(t, helmRenderCacheKey("key", "chart: web\nrepo: https://charts.example.com\nversion: 0.1.0"))
+	assert.Empty(t, helmRenderCacheKey("key", "chart: web\nrepo: https://charts.example.com"))
+
+	// the archive at a URL is identified by the URL
+	assert.NotEmpty(t, helmRenderCacheKey("key", "chart: https://charts.example.com/web-0.1.0.tgz"))
+
+	// a chart in OCI registry is identified by its digest
+	defer func(resolve func(context.Context, string, string) (string, error)) { resolveOCIChart = resolve }(resolveOCIChart)
+	digests := map[string]string{"0.1.0": "sha256:a", "latest": "sha256:a"}
+	resolveOCIChart = func(_ context.Context, name, version string) (string, error) {
+		if d, ok := digests[version]; ok {
+			return d, nil
+		}
+		return "", errors.New("not found")
+	}
+	oci := func(version string) string {
+		return helmRenderCacheKey("key", "chart: oci://registry.example.com/charts/web\nversion: "+version)
+	}
+	assert.NotEmpty(t, oci("0.1.0"))
+	assert.Equal(t, oci("0.1.0"), oci("latest"))
+	digests["latest"] = "sha256:b"
+	assert.NotEqual(t, oci("0.1.0"), oci("latest"))
+	assert.Empty(t, oci("0.2.0"))
+}
@@ -0,0 +1,210 @@
+package definition
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"cuelang.org/go/cue"
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/pkg/dsl/model"
+)
+
+// KubeTemplate is the template of definition in kube format, it's made up of plain Kubernetes manifests
+// and a list of parameters which are patched into the manifests by field paths.
+type KubeTemplate struct {
+	// Output is the main workload
+	Output map[string]interface{} `json:"output"`
+	// Outputs are the auxiliary resources of the workload by name
+	Outputs    map[string]map[string]interface{} `json:"outputs,omitempty"`
+	Parameters []KubeParameter                   `json:"parameters,omitempty"`
+}
+
+// KubeParameter is a parameter of the kube template
+type KubeParameter struct {
+	Name        string      `json:"name"`
+	Required    bool        `json:"required,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	// Schema is the JSON schema of the parameter
+	Schema *openapi3.Schema `json:"schema,omitempty"`
+	// FieldPaths are the fields which the parameter is patched into, they are rooted at `output` or `outputs.<name>`,
+	// e.g. output.spec.template.spec.containers[0].image
+	FieldPaths []string `json:"fieldPaths"`
+}
+
+// ParseKubeTemplate parses the template in kube format
+func ParseKubeTemplate(template string) (*KubeTemplate, error) {
+	t := &KubeTemplate{}
+	if err := yaml.Unmarshal([]byte(template), t); err != nil {
+		return nil, errors.Wrap(err, "invalid kube template")
+	}
+	if len(t.Output) == 0 {
+		return nil, errors.New("output is required in kube template")
+	}
+	names := make(map[string]bool, len(t.Parameters))
+	for _, p := range t.Parameters {
+		if p.Name == "" {
+			return nil, errors.New("name of parameter is required in kube template")
+		}
+		if names[p.Name] {
+			return nil, errors.Errorf("parameter %s is defined more than once in kube template", p.Name)
+		}
+		names[p.Name] = true
+		if len(p.FieldPaths) == 0 {
+			return nil, errors.Errorf("fieldPaths of parameter %s is required in kube template", p.Name)
+		}
+		for _, fp := range p.FieldPaths {
+			if err := t.checkFieldPath(fp); err != nil {
+				return nil, errors.WithMessagef(err, "invalid fieldPaths of parameter %s", p.Name)
+			}
+		}
+	}
+	return t, nil
+}
+
+// checkFieldPath checks the field path is rooted at the output or one of the outputs
+func (t *KubeTemplate) checkFieldPath(fp string) error {
+	segments, err := fieldpath.Parse(fp)
+	if err != nil {
+		return err
+	}
+	if len(segments) >= 2 && segments[0].Field == OutputFieldName {
+		return nil
+	}
+	if len(segments) >= 3 && segments[0].Field == OutputsFieldName {
+		if _, ok := t.Outputs[segments[1].Field]; ok {
+			return nil
+		}
+	}
+	return errors.Errorf("%s is neither a field of %s nor a field of an existing resource of %s", fp, OutputFieldName, OutputsFieldName)
+}
+
+// Validate checks the value of the parameter against its schema
+func (p KubeParameter) Validate(value interface{}) error {
+	if p.Schema == nil {
+		return nil
+	}
+	return p.Schema.VisitJSON(value)
+}
+
+// OpenAPISchema generates the OpenAPI schema of the parameters of the kube template
+func (t *KubeTemplate) OpenAPISchema() *openapi3.Schema {
+	schema := openapi3.NewObjectSchema()
+	for _, p := range t.Parameters {
+		ps := &openapi3.Schema{}
+		if p.Schema != nil {
+			s := *p.Schema
+			ps = &s
+		}
+		ps.Title = p.Name
+		if p.Description != "" {
+			ps.Description = p.Description
+		}
+		if p.Default != nil {
+			ps.Default = p.Default
+		}
+		schema.WithProperty(p.Name, ps)
+		if p.Required && p.Default == nil {
+			schema.Required = append(schema.Required, p.Name)
+		}
+	}
+	return schema
+}
+
+// renderKubeTemplate patches the parameters into the manifests of the kube template
+func renderKubeTemplate(name, template string, params interface{}) (*renderResult, error) {
+	t, err := ParseKubeTemplate(template)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid template of workload %s", name)
+	}
+	values := map[string]interface{}{}
+	if params != nil {
+		// normalize the parameters as JSON values to be validated by the schema
+		bt, err := json.Marshal(params)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "marshal parameter of workload %s", name)
+		}
+		if err := json.Unmarshal(bt, &values); err != nil {
+			return nil, errors.WithMessagef(err, "invalid parameter of workload %s", name)
+		}
+	}
+	outputs := make(map[string]interface{}, len(t.Outputs))
+	for k, v := range t.Outputs {
+		outputs[k] = v
+	}
+	root := fieldpath.Pave(map[string]interface{}{
+		OutputFieldName:  t.Output,
+		OutputsFieldName: outputs,
+	})
+	for _, p := range t.Parameters {
+		v, ok := values[p.Name]
+		if !ok {
+			if p.Default == nil {
+				if p.Required {
+					return nil, newParameterError(fmt.Sprintf("invalid parameter of workload %s", name), p.Name, "required but not provided")
+				}
+				continue
+			}
+			v = p.Default
+		}
+		if err := p.Validate(v); err != nil {
+			return nil, newParameterError(fmt.Sprintf("invalid parameter of workload %s", name), p.Name, err.Error())
+		}
+		for _, fp := range p.FieldPaths {
+			if err := root.SetValue(fp, v); err != nil {
+				return nil, errors.WithMessagef(err, "patch parameter %s of workload %s into %s", p.Name, name, fp)
+			}
+		}
+	}
+	result, err := newRenderResult(t.Output, t.Outputs)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid template of workload %s", name)
+	}
+	return result, nil
+}
+
+// newRenderResult creates the render result of workload from the resources rendered in other formats than CUE
+func newRenderResult(output map[string]interface{}, outputs map[string]map[string]interface{}) (*renderResult, error) {
+	base, err := newInstanceFromObject(output, model.NewBase)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid output")
+	}
+	result := &renderResult{base: base, cacheable: true}
+	names := make([]string, 0, len(outputs))
+	for k := range outputs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		other, err := newInstanceFromObject(outputs[k], model.NewOther)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid outputs(%s)", k)
+		}
+		result.auxiliaries = append(result.auxiliaries, renderedAuxiliary{
+			v: other, typ: AuxiliaryWorkload, name: k, isOutputs: true})
+	}
+	return result, nil
+}
+
+// newInstanceFromObject converts the object into the CUE format string of an instance
+func newInstanceFromObject(obj map[string]interface{}, newInstance func(v cue.Value) (model.Instance, error)) (string, error) {
+	bt, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	var r cue.Runtime
+	inst, err := r.Compile("-", string(bt))
+	if err != nil {
+		return "", err
+	}
+	ins, err := newInstance(inst.Value())
+	if err != nil {
+		return "", err
+	}
+	return ins.String(), nil
+}
@@ -0,0 +1,95 @@
+package definition
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+)
+
+func TestKubeTemplate(t *testing.T) {
+	template := `
+output:
+  apiVersion: apps/v1
+  kind: Deployment
+  spec:
+    template:
+      spec:
+        containers:
+          - name: main
+            ports:
+              - containerPort: 80
+outputs:
+  service:
+    apiVersion: v1
+    kind: Service
+    spec:
+      ports:
+        - port: 80
+parameters:
+  - name: image
+    required: true
+    schema:
+      type: string
+    fieldPaths:
+      - output.spec.template.spec.containers[0].image
+  - name: port
+    default: 8080
+    schema:
+      type: integer
+      minimum: 1
+    fieldPaths:
+      - output.spec.template.spec.containers[0].ports[0].containerPort
+      - outputs.service.spec.ports[0].targetPort
+`
+	render := func(params map[string]interface{}) (process.Context, error) {
+		ctx := process.NewContext("kube-test", "myapp", "myapp-v1")
+		return ctx, NewWorkloadAbstractEngine("kube-worker").Params(params).TemplateType(types.KubeTemplateType).Complete(ctx, template)
+	}
+
+	ctx, err := render(map[string]interface{}{"image": "nginx"})
+	assert.NoError(t, err)
+	base, assists := ctx.Output()
+	obj, err := base.Unstructured()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":  "main",
+		"image": "nginx",
+		"ports": []interface{}{map[string]interface{}{"containerPort": int64(8080)}},
+	}, obj.Object["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0])
+	assert.Equal(t, 1, len(assists))
+	assert.Equal(t, "service", assists[0].Name)
+	assert.Equal(t, AuxiliaryWorkload, assists[0].Type)
+	svc, err := assists[0].Ins.Unstructured()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{map[string]interface{}{"port": int64(80), "targetPort": int64(8080)}},
+		svc.Object["spec"].(map[string]interface{})["ports"])
+
+	_, err = render(map[string]interface{}{})
+	assert.EqualError(t, err, "invalid parameter of workload kube-worker: parameter.image: required but not provided")
+	_, err = render(map[string]interface{}{"image": "nginx", "port": 0})
+	re, ok := AsRenderError(err)
+	assert.True(t, ok)
+	assert.Equal(t, ReasonInvalidParameter, re.Reason)
+	assert.Equal(t, "port", re.Details[0].ParameterPath)
+
+	kt, err := ParseKubeTemplate(template)
+	assert.NoError(t, err)
+	schema := kt.OpenAPISchema()
+	assert.Equal(t, []string{"image"}, schema.Required)
+	assert.Equal(t, "string", schema.Properties["image"].Value.Type)
+	assert.Equal(t, float64(8080), schema.Properties["port"].Value.Default)
+	assert.Equal(t, "port", schema.Properties["port"].Value.Title)
+
+	_, err = ParseKubeTemplate(`
+output:
+  kind: Deployment
+parameters:
+  - name: image
+    fieldPaths:
+      - outputs.service.spec.image
+`)
+	assert.Error(t, err)
+}
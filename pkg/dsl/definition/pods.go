@@ -0,0 +1,201 @@
+package definition
+
+import (
+	"context"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// PodsFieldName is the name of the pods selected by the workload in the context of health and status policy
+	PodsFieldName = "pods"
+	// EventsFieldName is the name of the recent Warning events of the workload and its pods in the context of health and status policy
+	EventsFieldName = "events"
+	// maxEvents limits the events in the context to the most recent ones
+	maxEvents = 10
+)
+
+// contextFieldPatterns match the references to the fields of context in a policy template
+var contextFieldPatterns = map[string]*regexp.Regexp{
+	PodsFieldName:   contextFieldPattern(PodsFieldName),
+	EventsFieldName: contextFieldPattern(EventsFieldName),
+}
+
+func contextFieldPattern(field string) *regexp.Regexp {
+	return regexp.MustCompile(`context\s*(\.\s*` + field + `\b|\[\s*"` + field + `"\s*\])`)
+}
+
+// usesContextField returns whether the policy template references the field of context,
+// so that the pods and events are only fetched for the templates using them.
+func usesContextField(policyTemplate, field string) bool {
+	return contextFieldPatterns[field].MatchString(policyTemplate)
+}
+
+// addPodsAndEvents adds the pods selected by the workload and the recent Warning events of the workload and its pods
+// into the template context if the policy template uses them.
+func addPodsAndEvents(templateContext map[string]interface{}, cli client.Reader, ns string, policyTemplate string) error {
+	withPods := usesContextField(policyTemplate, PodsFieldName)
+	withEvents := usesContextField(policyTemplate, EventsFieldName)
+	if !withPods && !withEvents {
+		return nil
+	}
+	workload, _ := templateContext[OutputFieldName].(map[string]interface{})
+	pods, err := getSelectedPods(cli, ns, workload)
+	if err != nil {
+		return errors.WithMessage(err, "get pods of workload")
+	}
+	if withPods {
+		podList := make([]interface{}, 0, len(pods))
+		for i := range pods {
+			pod, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&pods[i])
+			if err != nil {
+				return err
+			}
+			podList = append(podList, pod)
+		}
+		templateContext[PodsFieldName] = podList
+	}
+	if withEvents {
+		events, err := getWarningEvents(cli, ns, workload, pods)
+		if err != nil {
+			return errors.WithMessage(err, "get events of workload")
+		}
+		templateContext[EventsFieldName] = events
+	}
+	return nil
+}
+
+// getSelectedPods lists the pods selected by `spec.selector` of the workload,
+// there is no pod for the workload without a selector.
+func getSelectedPods(cli client.Reader, ns string, workload map[string]interface{}) ([]corev1.Pod, error) {
+	selector, found, err := unstructured.NestedMap(workload, "spec", "selector")
+	if err != nil || !found {
+		return nil, err
+	}
+	labelSelector := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(selector, labelSelector); err != nil {
+		return nil, errors.Wrap(err, "invalid selector")
+	}
+	sel, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid selector")
+	}
+	if sel.Empty() {
+		// an empty selector matches all the pods in the namespace
+		return nil, nil
+	}
+	// an unstructured list is read from the API server, a typed one would start an informer of all the pods
+	// in the cluster for the cache of the manager
+	list := &unstructured.UnstructuredList{}
+	list.SetAPIVersion("v1")
+	list.SetKind("PodList")
+	if err := cli.List(context.Background(), list, &client.ListOptions{Namespace: ns, LabelSelector: sel}); err != nil {
+		return nil, err
+	}
+	pods := make([]corev1.Pod, 0, len(list.Items))
+	for i := range list.Items {
+		pod := corev1.Pod{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(list.Items[i].Object, &pod); err != nil {
+			return nil, err
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+// getWarningEvents returns the most recent Warning events of the workload and the pods.
+// The events are listed by field selectors directly from the API server instead of being cached,
+// one list for the workload and one for all the pods.
+func getWarningEvents(cli client.Reader, ns string, workload map[string]interface{}, pods []corev1.Pod) ([]interface{}, error) {
+	var warnings []corev1.Event
+	if workload != nil {
+		u := &unstructured.Unstructured{Object: workload}
+		events, err := listWarningEvents(cli, ns, u.GetKind(), u.GetName())
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, events...)
+	}
+	if len(pods) > 0 {
+		events, err := listWarningEvents(cli, ns, "Pod", "")
+		if err != nil {
+			return nil, err
+		}
+		selected := map[string]bool{}
+		for _, pod := range pods {
+			selected[pod.Name] = true
+		}
+		for _, ev := range events {
+			if selected[ev.InvolvedObject.Name] {
+				warnings = append(warnings, ev)
+			}
+		}
+	}
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return eventTime(warnings[j]).Time.Before(eventTime(warnings[i]).Time)
+	})
+	if len(warnings) > maxEvents {
+		warnings = warnings[:maxEvents]
+	}
+	events := make([]interface{}, 0, len(warnings))
+	for i := range warnings {
+		ev, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&warnings[i])
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// listWarningEvents lists the Warning events of the objects of the kind, or only the named one if name is set
+func listWarningEvents(cli client.Reader, ns, kind, name string) ([]corev1.Event, error) {
+	set := fields.Set{
+		"involvedObject.kind": kind,
+		"type":                corev1.EventTypeWarning,
+	}
+	if name != "" {
+		set["involvedObject.name"] = name
+	}
+	// an unstructured list is read from the API server rather than the cache of the manager
+	list := &unstructured.UnstructuredList{}
+	list.SetAPIVersion("v1")
+	list.SetKind("EventList")
+	if err := cli.List(context.Background(), list, &client.ListOptions{Namespace: ns,
+		FieldSelector: fields.SelectorFromSet(set)}); err != nil {
+		return nil, err
+	}
+	var events []corev1.Event
+	for i := range list.Items {
+		ev := corev1.Event{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(list.Items[i].Object, &ev); err != nil {
+			return nil, err
+		}
+		// the field selectors may not be respected by all the clients, e.g. the fake client in tests
+		if ev.Type != corev1.EventTypeWarning || ev.InvolvedObject.Kind != kind ||
+			(name != "" && ev.InvolvedObject.Name != name) {
+			continue
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// eventTime returns the time when the event last occurred
+func eventTime(ev corev1.Event) metav1.Time {
+	if !ev.LastTimestamp.IsZero() {
+		return ev.LastTimestamp
+	}
+	if !ev.EventTime.IsZero() {
+		return metav1.Time{Time: ev.EventTime.Time}
+	}
+	return ev.CreationTimestamp
+}
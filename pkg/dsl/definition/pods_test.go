@@ -0,0 +1,138 @@
+package definition
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	//lint:ignore SA1019 We will use pkg/envtest before upgrading controller-runtime to v1.0.0
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestUsesContextField(t *testing.T) {
+	assert.True(t, usesContextField(`isHealth: len(context.pods) > 0`, PodsFieldName))
+	assert.True(t, usesContextField(`message: context["events"][0].message`, EventsFieldName))
+	assert.False(t, usesContextField(`isHealth: context.output.status.readyReplicas > 0`, PodsFieldName))
+	assert.False(t, usesContextField(`isHealth: context.podsReady`, PodsFieldName))
+}
+
+func TestAddPodsAndEvents(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	pod := func(name string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels}}
+	}
+	event := func(name, typ, kind, object string, lastTime time.Time) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Type:           typ,
+			Reason:         name,
+			InvolvedObject: corev1.ObjectReference{Kind: kind, Name: object},
+			LastTimestamp:  metav1.NewTime(lastTime),
+		}
+	}
+	now := time.Now()
+	cli := fake.NewFakeClientWithScheme(scheme,
+		pod("web-1", map[string]string{"app": "web"}),
+		pod("db-1", map[string]string{"app": "db"}),
+		event("BackOff", corev1.EventTypeWarning, "Pod", "web-1", now),
+		event("FailedCreate", corev1.EventTypeWarning, "Deployment", "web", now.Add(-time.Minute)),
+		event("Pulled", corev1.EventTypeNormal, "Pod", "web-1", now),
+		event("Unhealthy", corev1.EventTypeWarning, "Pod", "db-1", now),
+	)
+	workload := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web"},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}},
+		},
+	}
+
+	templateContext := map[string]interface{}{OutputFieldName: workload}
+	assert.NoError(t, addPodsAndEvents(templateContext, cli, "default", `isHealth: true`))
+	assert.Equal(t, 1, len(templateContext))
+
+	assert.NoError(t, addPodsAndEvents(templateContext, cli, "default", `
+isHealth: len(context.pods) == 1
+message: context.events[0].reason
+`))
+	pods := templateContext[PodsFieldName].([]interface{})
+	assert.Equal(t, 1, len(pods))
+	assert.Equal(t, "web-1", pods[0].(map[string]interface{})["metadata"].(map[string]interface{})["name"])
+	var reasons []interface{}
+	for _, ev := range templateContext[EventsFieldName].([]interface{}) {
+		reasons = append(reasons, ev.(map[string]interface{})["reason"])
+	}
+	assert.Equal(t, []interface{}{"BackOff", "FailedCreate"}, reasons)
+
+	healthy, err := checkHealth(templateContext, `isHealth: len(context.pods) == 1`)
+	assert.NoError(t, err)
+	assert.True(t, healthy)
+	message, err := getStatusMessage(templateContext, `message: "\(context.events[0].reason): \(context.events[0].involvedObject.name)"`)
+	assert.NoError(t, err)
+	assert.Equal(t, "BackOff: web-1", message)
+
+	// no pod is selected by the workload without a selector
+	templateContext = map[string]interface{}{OutputFieldName: map[string]interface{}{"kind": "Deployment"}}
+	assert.NoError(t, addPodsAndEvents(templateContext, cli, "default", `isHealth: len(context.pods) > 0`))
+	assert.Equal(t, []interface{}{}, templateContext[PodsFieldName])
+}
+
+func TestListWarningEvents(t *testing.T) {
+	var selectors []string
+	cli := &test.MockClient{
+		MockList: func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+			listOpts := &client.ListOptions{}
+			listOpts.ApplyOptions(opts)
+			assert.Equal(t, "default", listOpts.Namespace)
+			selectors = append(selectors, listOpts.FieldSelector.String())
+			assert.Equal(t, "EventList", list.(*unstructured.UnstructuredList).GetKind())
+			return nil
+		},
+	}
+	workload := map[string]interface{}{"kind": "Deployment", "metadata": map[string]interface{}{"name": "web"}}
+	_, err := getWarningEvents(cli, "default", workload, []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "web-1"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"involvedObject.kind=Deployment,involvedObject.name=web,type=Warning",
+		"involvedObject.kind=Pod,type=Warning",
+	}, selectors)
+}
+
+func TestGetSelectedPods(t *testing.T) {
+	cli := &test.MockClient{
+		MockList: func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+			listOpts := &client.ListOptions{}
+			listOpts.ApplyOptions(opts)
+			assert.Equal(t, "app=web", listOpts.LabelSelector.String())
+			ul, ok := list.(*unstructured.UnstructuredList)
+			assert.True(t, ok)
+			assert.Equal(t, "PodList", ul.GetKind())
+			ul.Items = []unstructured.Unstructured{{Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Pod",
+				"metadata":   map[string]interface{}{"name": "web-1", "namespace": "default"},
+			}}}
+			return nil
+		},
+	}
+	workload := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}},
+		},
+	}
+	pods, err := getSelectedPods(cli, "default", workload)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(pods))
+	assert.Equal(t, "web-1", pods[0].Name)
+}
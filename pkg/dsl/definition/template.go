@@ -11,6 +11,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/dsl/model"
 	"github.com/oam-dev/kubevela/pkg/dsl/process"
 	"github.com/oam-dev/kubevela/pkg/dsl/task"
@@ -25,6 +26,8 @@ const (
 	OutputsFieldName = "outputs"
 	// PatchFieldName is the name of the struct contains the patch of CR data
 	PatchFieldName = "patch"
+	// ParameterFieldName is the name of the struct contains the parameters of definition
+	ParameterFieldName = "parameter"
 	// CustomMessage defines the custom message in definition template
 	CustomMessage = "message"
 	// HealthCheckPolicy defines the health check policy in definition template
@@ -40,6 +43,7 @@ const (
 // AbstractEngine defines Definition's Render interface
 type AbstractEngine interface {
 	Params(params interface{}) AbstractEngine
+	TemplateType(templateType string) AbstractEngine
 	Complete(ctx process.Context, abstractTemplate string) error
 	HealthCheck(ctx process.Context, cli client.Client, ns string, healthPolicyTemplate string) (bool, error)
 	Status(ctx process.Context, cli client.Client, ns string, customStatusTemplate string) (string, error)
@@ -48,6 +52,8 @@ type AbstractEngine interface {
 type def struct {
 	name   string
 	params interface{}
+	// templateType is the data format of the template, it's CUE if empty
+	templateType string
 }
 
 type workloadDef struct {
@@ -70,36 +76,85 @@ func (wd *workloadDef) Params(params interface{}) AbstractEngine {
 	return wd
 }
 
+// TemplateType set the data format of definition's template
+func (wd *workloadDef) TemplateType(templateType string) AbstractEngine {
+	wd.templateType = templateType
+	return wd
+}
+
 // Complete do workload definition's rendering
 func (wd *workloadDef) Complete(ctx process.Context, abstractTemplate string) error {
+	key := renderCacheKey(workloadCacheKind, wd.name, abstractTemplate, wd.params, ctx)
+	if wd.templateType == types.HelmTemplateType {
+		key = helmRenderCacheKey(key, abstractTemplate)
+	}
+	result, ok := defaultRenderCache.get(workloadCacheKind, key)
+	if !ok {
+		var err error
+		if result, err = wd.render(ctx, abstractTemplate); err != nil {
+			return err
+		}
+		if result.cacheable {
+			defaultRenderCache.add(key, result)
+		}
+	}
+	return result.apply(ctx, wd.name)
+}
+
+// render evaluates the template of workload with parameter and context
+func (wd *workloadDef) render(ctx process.Context, abstractTemplate string) (*renderResult, error) {
+	switch wd.templateType {
+	case "", types.CUETemplateType:
+	case types.KubeTemplateType:
+		return renderKubeTemplate(wd.name, abstractTemplate, wd.params)
+	case types.HelmTemplateType:
+		return renderHelmTemplate(ctx, wd.name, abstractTemplate, wd.params)
+	default:
+		return nil, errors.Errorf("templateType %s of workload %s is not supported", wd.templateType, wd.name)
+	}
 	bi := build.NewContext().NewInstance("", nil)
-	if err := bi.AddFile("-", abstractTemplate); err != nil {
-		return errors.WithMessagef(err, "invalid cue template of workload %s", wd.name)
+	if err := bi.AddFile(templateFileName, abstractTemplate); err != nil {
+		return nil, newCUERenderError(fmt.Sprintf("invalid cue template of workload %s", wd.name), err)
 	}
 	if wd.params != nil {
 		bt, err := json.Marshal(wd.params)
 		if err != nil {
-			return errors.WithMessagef(err, "marshal parameter of workload %s", wd.name)
+			return nil, errors.WithMessagef(err, "marshal parameter of workload %s", wd.name)
 		}
-		if err := bi.AddFile("parameter", fmt.Sprintf("parameter: %s", string(bt))); err != nil {
-			return errors.WithMessagef(err, "invalid parameter of workload %s", wd.name)
+		if err := bi.AddFile(parameterFileName, fmt.Sprintf("%s: %s", ParameterFieldName, string(bt))); err != nil {
+			return nil, errors.WithMessagef(err, "invalid parameter of workload %s", wd.name)
 		}
 	}
 
-	if err := bi.AddFile("-", ctx.BaseContextFile()); err != nil {
-		return err
+	if err := bi.AddFile(contextFileName, ctx.BaseContextFile()); err != nil {
+		return nil, err
 	}
+	result := &renderResult{cacheable: true}
 	instances := cue.Build([]*build.Instance{bi})
 	for _, inst := range instances {
 		if err := inst.Value().Err(); err != nil {
-			return errors.WithMessagef(err, "invalid cue template of workload %s after merge parameter and context", wd.name)
+			return nil, newCUERenderError(fmt.Sprintf("invalid cue template of workload %s after merge parameter and context", wd.name), err)
+		}
+		if inst.Lookup(task.ProcessingFieldName).Exists() {
+			// the results of processing tasks rely on the data out of the template
+			result.cacheable = false
+			var err error
+			if inst, err = task.Process(inst, ctx.Client(), ctx.Namespace(), ctx.InReconcile()); err != nil {
+				return nil, errors.WithMessagef(err, "invalid process of workload %s", wd.name)
+			}
+		}
+		if incomplete, err := validateFields(inst, OutputFieldName, OutputsFieldName); err != nil {
+			if incomplete {
+				return nil, newCUERenderError(fmt.Sprintf("incomplete parameter of workload %s", wd.name), err)
+			}
+			return nil, newCUERenderError(fmt.Sprintf("invalid cue template of workload %s after merge parameter and context", wd.name), err)
 		}
 		output := inst.Lookup(OutputFieldName)
 		base, err := model.NewBase(output)
 		if err != nil {
-			return errors.WithMessagef(err, "invalid output of workload %s", wd.name)
+			return nil, errors.WithMessagef(err, "invalid output of workload %s", wd.name)
 		}
-		ctx.SetBase(base)
+		result.base = base.String()
 
 		// we will support outputs for workload composition, and it will become trait in AppConfig.
 		outputs := inst.Lookup(OutputsFieldName)
@@ -108,7 +163,7 @@ func (wd *workloadDef) Complete(ctx process.Context, abstractTemplate string) er
 		}
 		st, err := outputs.Struct()
 		if err != nil {
-			return errors.WithMessagef(err, "invalid outputs of workload %s", wd.name)
+			return nil, errors.WithMessagef(err, "invalid outputs of workload %s", wd.name)
 		}
 		for i := 0; i < st.Len(); i++ {
 			fieldInfo := st.Field(i)
@@ -117,12 +172,38 @@ func (wd *workloadDef) Complete(ctx process.Context, abstractTemplate string) er
 			}
 			other, err := model.NewOther(fieldInfo.Value)
 			if err != nil {
-				return errors.WithMessagef(err, "invalid outputs(%s) of workload %s", fieldInfo.Name, wd.name)
+				return nil, errors.WithMessagef(err, "invalid outputs(%s) of workload %s", fieldInfo.Name, wd.name)
 			}
-			ctx.AppendAuxiliaries(process.Auxiliary{Ins: other, Type: AuxiliaryWorkload, Name: fieldInfo.Name, IsOutputs: true})
+			result.auxiliaries = append(result.auxiliaries, renderedAuxiliary{
+				v: other.String(), typ: AuxiliaryWorkload, name: fieldInfo.Name, isOutputs: true})
 		}
 	}
-	return nil
+	return result, nil
+}
+
+// validateFields validates the rendered fields, the conflicts in them are returned as they are, and if any of them
+// is incomplete, the incomplete parameters which mostly cause it are returned with incomplete as true.
+func validateFields(inst *cue.Instance, fields ...string) (incomplete bool, err error) {
+	for _, f := range fields {
+		v := inst.Lookup(f)
+		if !v.Exists() {
+			continue
+		}
+		if err := v.Validate(); err != nil {
+			return false, err
+		}
+		if v.Validate(cue.Concrete(true)) != nil {
+			incomplete = true
+		}
+	}
+	parameter := inst.Lookup(ParameterFieldName)
+	if !incomplete || !parameter.Exists() {
+		return false, nil
+	}
+	if err := parameter.Validate(cue.Concrete(true)); err != nil {
+		return true, err
+	}
+	return false, nil
 }
 
 func (wd *workloadDef) getTemplateContext(ctx process.Context, cli client.Reader, ns string) (map[string]interface{}, error) {
@@ -188,6 +269,9 @@ func (wd *workloadDef) HealthCheck(ctx process.Context, cli client.Client, ns st
 	if err != nil {
 		return false, errors.WithMessage(err, "get template context")
 	}
+	if err := addPodsAndEvents(templateContext, cli, ns, healthPolicyTemplate); err != nil {
+		return false, err
+	}
 	return checkHealth(templateContext, healthPolicyTemplate)
 }
 
@@ -219,6 +303,9 @@ func (wd *workloadDef) Status(ctx process.Context, cli client.Client, ns string,
 	if err != nil {
 		return "", errors.WithMessage(err, "get template context")
 	}
+	if err := addPodsAndEvents(templateContext, cli, ns, customStatusTemplate); err != nil {
+		return "", err
+	}
 	return getStatusMessage(templateContext, customStatusTemplate)
 }
 
@@ -259,51 +346,84 @@ func (td *traitDef) Params(params interface{}) AbstractEngine {
 	return td
 }
 
+// TemplateType set the data format of definition's template
+func (td *traitDef) TemplateType(templateType string) AbstractEngine {
+	td.templateType = templateType
+	return td
+}
+
 // Complete do trait definition's rendering
 func (td *traitDef) Complete(ctx process.Context, abstractTemplate string) error {
+	key := renderCacheKey(traitCacheKind, td.name, abstractTemplate, td.params, ctx)
+	result, ok := defaultRenderCache.get(traitCacheKind, key)
+	if !ok {
+		var err error
+		if result, err = td.render(ctx, abstractTemplate); err != nil {
+			return err
+		}
+		if result.cacheable {
+			defaultRenderCache.add(key, result)
+		}
+	}
+	return result.apply(ctx, td.name)
+}
+
+// render evaluates the template of trait with parameter and context
+func (td *traitDef) render(ctx process.Context, abstractTemplate string) (*renderResult, error) {
+	if td.templateType != "" && td.templateType != types.CUETemplateType {
+		return nil, errors.Errorf("templateType %s of trait %s is not supported", td.templateType, td.name)
+	}
 	bi := build.NewContext().NewInstance("", nil)
-	if err := bi.AddFile("-", abstractTemplate); err != nil {
-		return errors.WithMessagef(err, "invalid template of trait %s", td.name)
+	if err := bi.AddFile(templateFileName, abstractTemplate); err != nil {
+		return nil, newCUERenderError(fmt.Sprintf("invalid template of trait %s", td.name), err)
 	}
 	if td.params != nil {
 		bt, err := json.Marshal(td.params)
 		if err != nil {
-			return errors.WithMessagef(err, "marshal parameter of trait %s", td.name)
+			return nil, errors.WithMessagef(err, "marshal parameter of trait %s", td.name)
 		}
-		if err := bi.AddFile("parameter", fmt.Sprintf("parameter: %s", string(bt))); err != nil {
-			return errors.WithMessagef(err, "invalid parameter of trait %s", td.name)
+		if err := bi.AddFile(parameterFileName, fmt.Sprintf("%s: %s", ParameterFieldName, string(bt))); err != nil {
+			return nil, errors.WithMessagef(err, "invalid parameter of trait %s", td.name)
 		}
 	}
 
-	if err := bi.AddFile("context", ctx.BaseContextFile()); err != nil {
-		return errors.WithMessagef(err, "invalid context of trait %s", td.name)
+	if err := bi.AddFile(contextFileName, ctx.BaseContextFile()); err != nil {
+		return nil, errors.WithMessagef(err, "invalid context of trait %s", td.name)
 	}
+	result := &renderResult{cacheable: true}
 	instances := cue.Build([]*build.Instance{bi})
 	for _, inst := range instances {
 		if err := inst.Value().Err(); err != nil {
-			return errors.WithMessagef(err, "invalid template of trait %s after merge with parameter and context", td.name)
+			return nil, newCUERenderError(fmt.Sprintf("invalid template of trait %s after merge with parameter and context", td.name), err)
 		}
-		processing := inst.Lookup("processing")
-		var err error
-		if processing.Exists() {
-			if inst, err = task.Process(inst); err != nil {
-				return errors.WithMessagef(err, "invalid process of trait %s", td.name)
+		if inst.Lookup(task.ProcessingFieldName).Exists() {
+			// the results of processing tasks rely on the data out of the template
+			result.cacheable = false
+			var err error
+			if inst, err = task.Process(inst, ctx.Client(), ctx.Namespace(), ctx.InReconcile()); err != nil {
+				return nil, errors.WithMessagef(err, "invalid process of trait %s", td.name)
 			}
 		}
+		if incomplete, err := validateFields(inst, OutputFieldName, OutputsFieldName, PatchFieldName); err != nil {
+			if incomplete {
+				return nil, newCUERenderError(fmt.Sprintf("incomplete parameter of trait %s", td.name), err)
+			}
+			return nil, newCUERenderError(fmt.Sprintf("invalid template of trait %s after merge with parameter and context", td.name), err)
+		}
 
 		output := inst.Lookup(OutputFieldName)
 		if output.Exists() {
 			other, err := model.NewOther(output)
 			if err != nil {
-				return errors.WithMessagef(err, "invalid output of trait %s", td.name)
+				return nil, errors.WithMessagef(err, "invalid output of trait %s", td.name)
 			}
-			ctx.AppendAuxiliaries(process.Auxiliary{Ins: other, Type: td.name, IsOutputs: false})
+			result.auxiliaries = append(result.auxiliaries, renderedAuxiliary{v: other.String(), typ: td.name})
 		}
 		outputs := inst.Lookup(OutputsFieldName)
 		if outputs.Exists() {
 			st, err := outputs.Struct()
 			if err != nil {
-				return errors.WithMessagef(err, "invalid outputs of trait %s", td.name)
+				return nil, errors.WithMessagef(err, "invalid outputs of trait %s", td.name)
 			}
 			for i := 0; i < st.Len(); i++ {
 				fieldInfo := st.Field(i)
@@ -312,25 +432,23 @@ func (td *traitDef) Complete(ctx process.Context, abstractTemplate string) error
 				}
 				other, err := model.NewOther(fieldInfo.Value)
 				if err != nil {
-					return errors.WithMessagef(err, "invalid outputs(resource=%s) of trait %s", fieldInfo.Name, td.name)
+					return nil, errors.WithMessagef(err, "invalid outputs(resource=%s) of trait %s", fieldInfo.Name, td.name)
 				}
-				ctx.AppendAuxiliaries(process.Auxiliary{Ins: other, Type: td.name, Name: fieldInfo.Name, IsOutputs: true})
+				result.auxiliaries = append(result.auxiliaries, renderedAuxiliary{
+					v: other.String(), typ: td.name, name: fieldInfo.Name, isOutputs: true})
 			}
 		}
 
 		patcher := inst.Lookup(PatchFieldName)
 		if patcher.Exists() {
-			base, _ := ctx.Output()
 			p, err := model.NewOther(patcher)
 			if err != nil {
-				return errors.WithMessagef(err, "invalid patch of trait %s", td.name)
-			}
-			if err := base.Unify(p); err != nil {
-				return errors.WithMessagef(err, "invalid patch trait %s into workload", td.name)
+				return nil, errors.WithMessagef(err, "invalid patch of trait %s", td.name)
 			}
+			result.patch = p.String()
 		}
 	}
-	return nil
+	return result, nil
 }
 
 func (td *traitDef) getTemplateContext(ctx process.Context, cli client.Reader, ns string) (map[string]interface{}, error) {
@@ -373,7 +491,8 @@ func (td *traitDef) getTemplateContext(ctx process.Context, cli client.Reader, n
 	return root, nil
 }
 
-// Status get trait status by customStatusTemplate
+// Status get trait status by customStatusTemplate,
+// the pods and events are not added into the context of a trait since its resources don't select pods
 func (td *traitDef) Status(ctx process.Context, cli client.Client, ns string, customStatusTemplate string) (string, error) {
 	if customStatusTemplate == "" {
 		return "", nil
@@ -385,7 +504,7 @@ func (td *traitDef) Status(ctx process.Context, cli client.Client, ns string, cu
 	return getStatusMessage(templateContext, customStatusTemplate)
 }
 
-// HealthCheck address health check for trait
+// HealthCheck address health check for trait, the pods and events are not available as the status of trait
 func (td *traitDef) HealthCheck(ctx process.Context, cli client.Client, ns string, healthPolicyTemplate string) (bool, error) {
 	if healthPolicyTemplate == "" {
 		return true, nil
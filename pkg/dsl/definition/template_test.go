@@ -82,7 +82,7 @@ parameter: {
 	}
 
 	for _, v := range testCases {
-		ctx := process.NewContext("test", "myapp")
+		ctx := process.NewContext("test", "myapp", "myapp-v1")
 		wt := NewWorkloadAbstractEngine("testworkload")
 		assert.NoError(t, wt.Params(v.params).Complete(ctx, v.workloadTemplate))
 		base, assists := ctx.Output()
@@ -230,7 +230,7 @@ parameter: {
 	replicas: *1 | int
 }
 `
-		ctx := process.NewContext("test", "myapp")
+		ctx := process.NewContext("test", "myapp", "myapp-v1")
 		wt := NewWorkloadAbstractEngine("-")
 		if err := wt.Params(map[string]interface{}{
 			"replicas": 2,
@@ -0,0 +1,364 @@
+package deftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	//lint:ignore SA1019 We will use pkg/envtest before upgrading controller-runtime to v1.0.0
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	core "github.com/oam-dev/kubevela/apis/core.oam.dev"
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/dsl/model"
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
+)
+
+const (
+	defaultComponentName = "mycomp"
+	defaultAppName       = "myapp"
+	defaultNamespace     = "default"
+)
+
+// Definition is the WorkloadDefinition or TraitDefinition under test
+type Definition struct {
+	Name     string
+	Type     types.CapType
+	Template *util.Template
+}
+
+// Suite is the test cases of a definition
+type Suite struct {
+	Cases []Case `json:"cases"`
+}
+
+// Case is a test case which renders the template of definition with the parameter and context,
+// then checks the rendered resources, health and status message against the expected ones.
+type Case struct {
+	Name string `json:"name"`
+	// Context is the context to render the template with, the default ones are used if not set
+	Context Context `json:"context,omitempty"`
+	// Parameter is the parameter to render the template with
+	Parameter map[string]interface{} `json:"parameter,omitempty"`
+	// Workload is the workload which the trait is rendered for and patches, only for TraitDefinition
+	Workload map[string]interface{} `json:"workload,omitempty"`
+	// Objects are the objects in the fake cluster to evaluate the health and status message with,
+	// an object is matched with a rendered resource by name, or by the labels set by KubeVela if no name is rendered
+	Objects  []map[string]interface{} `json:"objects,omitempty"`
+	Expected Expected                 `json:"expected"`
+}
+
+// Context is the context of application to render the template with
+type Context struct {
+	Name        string `json:"name,omitempty"`
+	AppName     string `json:"appName,omitempty"`
+	AppRevision string `json:"appRevision,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	// ComponentType defaults to the name of the definition for WorkloadDefinition
+	ComponentType  string            `json:"componentType,omitempty"`
+	AppLabels      map[string]string `json:"appLabels,omitempty"`
+	AppAnnotations map[string]string `json:"appAnnotations,omitempty"`
+	// Env defaults to the namespace
+	Env string `json:"env,omitempty"`
+}
+
+// Expected is the expected result of a test case, only the specified ones are checked
+type Expected struct {
+	// Output is the resource rendered in `output`
+	Output map[string]interface{} `json:"output,omitempty"`
+	// Outputs are the resources rendered in `outputs` by name
+	Outputs map[string]map[string]interface{} `json:"outputs,omitempty"`
+	// Workload is the workload patched by the trait, only for TraitDefinition
+	Workload map[string]interface{} `json:"workload,omitempty"`
+	// Healthy is the result of `isHealth` in the health policy
+	Healthy *bool `json:"healthy,omitempty"`
+	// Message is the `message` in the custom status
+	Message *string `json:"message,omitempty"`
+	// Error is a substring of the error expected when rendering
+	Error string `json:"error,omitempty"`
+}
+
+// Result is the result of a test case, the case passes if there is no failure
+type Result struct {
+	Name     string
+	Failures []string
+}
+
+// Passed returns whether the test case passes
+func (r Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// LoadDefinition loads a WorkloadDefinition or TraitDefinition from YAML or JSON
+func LoadDefinition(data []byte) (*Definition, error) {
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(data, &obj.Object); err != nil {
+		return nil, errors.Wrap(err, "invalid definition")
+	}
+	d := &Definition{Name: obj.GetName()}
+	var err error
+	switch obj.GetKind() {
+	case v1alpha2.WorkloadDefinitionKind:
+		wd := &v1alpha2.WorkloadDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, wd); err != nil {
+			return nil, errors.Wrap(err, "invalid WorkloadDefinition")
+		}
+		d.Type = types.TypeWorkload
+		if d.Template, err = util.NewTemplate(wd.Spec.Template, wd.Spec.Status, wd.Spec.Extension); err == nil {
+			d.Template.TemplateType = wd.Spec.TemplateType
+		}
+	case v1alpha2.TraitDefinitionKind:
+		td := &v1alpha2.TraitDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, td); err != nil {
+			return nil, errors.Wrap(err, "invalid TraitDefinition")
+		}
+		d.Type = types.TypeTrait
+		if d.Template, err = util.NewTemplate(td.Spec.Template, td.Spec.Status, td.Spec.Extension); err == nil {
+			d.Template.TemplateType = td.Spec.TemplateType
+		}
+	default:
+		return nil, errors.Errorf("kind %s is not supported, only %s and %s can be tested",
+			obj.GetKind(), v1alpha2.WorkloadDefinitionKind, v1alpha2.TraitDefinitionKind)
+	}
+	if err != nil {
+		return nil, errors.WithMessagef(err, "load template of %s", d.Name)
+	}
+	if d.Template.TemplateStr == "" {
+		return nil, errors.Errorf("no template found in definition %s", d.Name)
+	}
+	return d, nil
+}
+
+// LoadSuite loads the test cases from YAML or JSON
+func LoadSuite(data []byte) (*Suite, error) {
+	suite := &Suite{}
+	if err := yaml.Unmarshal(data, suite); err != nil {
+		return nil, errors.Wrap(err, "invalid test cases")
+	}
+	return suite, nil
+}
+
+// Run runs all the test cases against the definition
+func (d *Definition) Run(suite *Suite) []Result {
+	results := make([]Result, 0, len(suite.Cases))
+	for i, c := range suite.Cases {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("case %d", i)
+		}
+		results = append(results, Result{Name: name, Failures: d.runCase(c)})
+	}
+	return results
+}
+
+func (d *Definition) runCase(c Case) []string {
+	if c.Context.ComponentType == "" && d.Type == types.TypeWorkload {
+		c.Context.ComponentType = d.Name
+	}
+	pCtx := newProcessContext(c.Context)
+	var engine definition.AbstractEngine
+	switch d.Type {
+	case types.TypeTrait:
+		if c.Workload != nil {
+			base, err := newBase(c.Workload)
+			if err != nil {
+				return []string{fmt.Sprintf("invalid workload: %v", err)}
+			}
+			pCtx.SetBase(base)
+		}
+		engine = definition.NewTraitAbstractEngine(d.Name)
+	default:
+		engine = definition.NewWorkloadAbstractEngine(d.Name)
+	}
+
+	var output, workload interface{}
+	var outputs map[string]interface{}
+	err := engine.Params(c.Parameter).TemplateType(d.Template.TemplateType).Complete(pCtx, d.Template.TemplateStr)
+	if err == nil {
+		output, outputs, workload, err = d.rendered(pCtx)
+	}
+	if c.Expected.Error != "" {
+		if err == nil {
+			return []string{fmt.Sprintf("expect error %q but got nil", c.Expected.Error)}
+		}
+		if !strings.Contains(err.Error(), c.Expected.Error) {
+			return []string{fmt.Sprintf("expect error %q but got %q", c.Expected.Error, err.Error())}
+		}
+		return nil
+	}
+	if err != nil {
+		return []string{fmt.Sprintf("render: %v", err)}
+	}
+
+	var failures []string
+	if c.Expected.Output != nil {
+		failures = append(failures, compare("output", c.Expected.Output, output)...)
+	}
+	if c.Expected.Outputs != nil {
+		failures = append(failures, compare("outputs", c.Expected.Outputs, outputs)...)
+	}
+	if c.Expected.Workload != nil {
+		failures = append(failures, compare("workload", c.Expected.Workload, workload)...)
+	}
+	if c.Expected.Healthy == nil && c.Expected.Message == nil {
+		return failures
+	}
+
+	ns := pCtx.Namespace()
+	cli, err := newFakeClient(c.Objects, ns)
+	if err != nil {
+		return append(failures, fmt.Sprintf("invalid objects: %v", err))
+	}
+	if c.Expected.Healthy != nil {
+		healthy, err := engine.HealthCheck(pCtx, cli, ns, d.Template.Health)
+		switch {
+		case err != nil:
+			failures = append(failures, fmt.Sprintf("health check: %v", err))
+		case healthy != *c.Expected.Healthy:
+			failures = append(failures, fmt.Sprintf("healthy: expect %t but got %t", *c.Expected.Healthy, healthy))
+		}
+	}
+	if c.Expected.Message != nil {
+		message, err := engine.Status(pCtx, cli, ns, d.Template.CustomStatus)
+		switch {
+		case err != nil:
+			failures = append(failures, fmt.Sprintf("status: %v", err))
+		case message != *c.Expected.Message:
+			failures = append(failures, fmt.Sprintf("message: expect %q but got %q", *c.Expected.Message, message))
+		}
+	}
+	return failures
+}
+
+// rendered returns the resources rendered by the definition, the output is the main workload for WorkloadDefinition
+func (d *Definition) rendered(pCtx process.Context) (output interface{}, outputs map[string]interface{}, workload interface{}, err error) {
+	base, assists := pCtx.Output()
+	if base != nil {
+		u, err := base.Unstructured()
+		if err != nil {
+			return nil, nil, nil, errors.WithMessage(err, "invalid workload")
+		}
+		workload = u.Object
+	}
+	if d.Type == types.TypeWorkload {
+		output = workload
+	}
+	outputs = map[string]interface{}{}
+	for _, assist := range assists {
+		u, err := assist.Ins.Unstructured()
+		if err != nil {
+			return nil, nil, nil, errors.WithMessagef(err, "invalid resource %s", assist.Name)
+		}
+		if assist.IsOutputs {
+			outputs[assist.Name] = u.Object
+		} else {
+			output = u.Object
+		}
+	}
+	return output, outputs, workload, nil
+}
+
+func newProcessContext(c Context) process.Context {
+	if c.Name == "" {
+		c.Name = defaultComponentName
+	}
+	if c.AppName == "" {
+		c.AppName = defaultAppName
+	}
+	if c.Namespace == "" {
+		c.Namespace = defaultNamespace
+	}
+	if c.Env == "" {
+		c.Env = c.Namespace
+	}
+	pCtx := process.NewContext(c.Name, c.AppName, c.AppRevision)
+	pCtx.SetNamespace(c.Namespace)
+	pCtx.SetComponentType(c.ComponentType)
+	pCtx.SetAppLabels(c.AppLabels)
+	pCtx.SetAppAnnotations(c.AppAnnotations)
+	pCtx.SetEnv(c.Env)
+	return pCtx
+}
+
+// newBase creates the base model of the workload so that traits can patch it
+func newBase(workload map[string]interface{}) (model.Instance, error) {
+	bt, err := json.Marshal(workload)
+	if err != nil {
+		return nil, err
+	}
+	var r cue.Runtime
+	inst, err := r.Compile("-", string(bt))
+	if err != nil {
+		return nil, err
+	}
+	return model.NewBase(inst.Value())
+}
+
+// newFakeClient creates a client of the fake cluster with the objects, the kinds unknown are read as unstructured.
+// The objects without namespace are put into the namespace of the application.
+func newFakeClient(objects []map[string]interface{}, namespace string) (client.Client, error) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := core.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	objs := make([]runtime.Object, 0, len(objects))
+	for _, o := range objects {
+		u := &unstructured.Unstructured{Object: o}
+		gvk := u.GroupVersionKind()
+		if gvk.Kind == "" {
+			return nil, errors.Errorf("kind of object %s is required", u.GetName())
+		}
+		if !scheme.Recognizes(gvk) {
+			scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+			scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+		}
+		if u.GetNamespace() == "" {
+			u.SetNamespace(namespace)
+		}
+		objs = append(objs, u)
+	}
+	return fake.NewFakeClientWithScheme(scheme, objs...), nil
+}
+
+// compare compares the expected with the actual after normalizing both of them into JSON values
+func compare(field string, expected, actual interface{}) []string {
+	e, err := normalize(expected)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: invalid expected value: %v", field, err)}
+	}
+	a, err := normalize(actual)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: invalid rendered value: %v", field, err)}
+	}
+	if diff := cmp.Diff(e, a); diff != "" {
+		return []string{fmt.Sprintf("%s mismatch (-expected +rendered):\n%s", field, diff)}
+	}
+	return nil
+}
+
+func normalize(v interface{}) (interface{}, error) {
+	bt, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var ret interface{}
+	if err := json.Unmarshal(bt, &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
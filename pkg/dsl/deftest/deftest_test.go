@@ -0,0 +1,66 @@
+package deftest
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/kubevela/apis/types"
+)
+
+func load(t *testing.T, definitionFile, suiteFile string) (*Definition, *Suite) {
+	data, err := ioutil.ReadFile(definitionFile)
+	assert.NoError(t, err)
+	d, err := LoadDefinition(data)
+	assert.NoError(t, err)
+	data, err = ioutil.ReadFile(suiteFile)
+	assert.NoError(t, err)
+	suite, err := LoadSuite(data)
+	assert.NoError(t, err)
+	return d, suite
+}
+
+func TestRunWorkloadDefinition(t *testing.T) {
+	d, suite := load(t, "testdata/worker.yaml", "testdata/worker_test.yaml")
+	assert.Equal(t, types.TypeWorkload, d.Type)
+	assert.Equal(t, "worker", d.Name)
+
+	results := d.Run(suite)
+	assert.Equal(t, 3, len(results))
+	for _, r := range results {
+		assert.True(t, r.Passed(), "%s: %v", r.Name, r.Failures)
+	}
+}
+
+func TestRunTraitDefinition(t *testing.T) {
+	d, suite := load(t, "testdata/scaler.yaml", "testdata/scaler_test.yaml")
+	assert.Equal(t, types.TypeTrait, d.Type)
+
+	results := d.Run(suite)
+	assert.Equal(t, 2, len(results))
+	assert.True(t, results[0].Passed(), "%s: %v", results[0].Name, results[0].Failures)
+	assert.False(t, results[1].Passed())
+	assert.Contains(t, results[1].Failures[0], "workload mismatch")
+}
+
+func TestLoadDefinition(t *testing.T) {
+	_, err := LoadDefinition([]byte(`
+apiVersion: core.oam.dev/v1alpha2
+kind: ScopeDefinition
+metadata:
+  name: healthscopes.core.oam.dev
+`))
+	assert.Error(t, err)
+
+	_, err = LoadDefinition([]byte(`
+apiVersion: core.oam.dev/v1alpha2
+kind: WorkloadDefinition
+metadata:
+  name: worker
+spec:
+  definitionRef:
+    name: deployments.apps
+`))
+	assert.Error(t, err)
+}
@@ -96,6 +96,14 @@ func NewOther(v cue.Value) (Instance, error) {
 	}, nil
 }
 
+// NewInstanceFromString create an instance from its cue format string
+func NewInstanceFromString(v string, base bool) Instance {
+	return &instance{
+		v:    v,
+		base: base,
+	}
+}
+
 func openPrint(v cue.Value) (string, error) {
 	sysopts := []cue.Option{cue.All(), cue.DisallowCycles(true), cue.ResolveReferences(true), cue.Docs(true)}
 	f, err := sets.ToFile(v.Syntax(sysopts...))
@@ -1,10 +1,12 @@
 package sets
 
 import (
+	"fmt"
 	"strings"
 
 	"cuelang.org/go/cue"
 	"cuelang.org/go/cue/ast"
+	cueerrors "cuelang.org/go/cue/errors"
 	"cuelang.org/go/cue/parser"
 	"github.com/pkg/errors"
 )
@@ -12,6 +14,13 @@ import (
 const (
 	// TagPatchKey specify the primary key of the list items
 	TagPatchKey = "patchKey"
+	// TagPatchStrategy specify the strategy to patch the field
+	TagPatchStrategy = "patchStrategy"
+
+	// StrategyReplace replaces the field in base with the one in patch
+	StrategyReplace = "replace"
+	// StrategyRetainKeys drops the keys of the struct in base which are not in patch, the retained keys are unified
+	StrategyRetainKeys = "retainKeys"
 )
 
 var (
@@ -97,6 +106,76 @@ func listMergeByKey(baseNode ast.Node) interceptor {
 	}
 }
 
+// patchStrategy applies the `+patchStrategy` tag of the fields in patch to the base,
+// the tag only takes effect on the field it's set on.
+func patchStrategy(baseNode ast.Node) interceptor {
+	return func(lnode ast.Node) (ast.Node, error) {
+		var walkErr error
+		walker := newWalker(func(node ast.Node, ctx walkCtx) {
+			field, ok := node.(*ast.Field)
+			if !ok || walkErr != nil {
+				return
+			}
+			strategy, ok := findCommentTag(field.Comments())[TagPatchStrategy]
+			if !ok {
+				return
+			}
+			label := labelStr(field.Label)
+			path := strings.Join(append(append([]string{}, ctx.Pos()...), label), ".")
+			parent, err := lookUp(baseNode, ctx.Pos()...)
+			if err != nil {
+				// nothing to patch in base
+				return
+			}
+			switch strategy {
+			case StrategyReplace:
+				removeFields(parent, func(l string) bool { return l == label })
+			case StrategyRetainKeys:
+				patchStruct, ok := field.Value.(*ast.StructLit)
+				if !ok {
+					walkErr = errors.Errorf("%s: patchStrategy %s can only be set on struct", path, strategy)
+					return
+				}
+				retained := map[string]bool{}
+				for _, elt := range patchStruct.Elts {
+					if f, ok := elt.(*ast.Field); ok {
+						retained[labelStr(f.Label)] = true
+					}
+				}
+				baseValue, err := lookUp(parent, label)
+				if err != nil {
+					return
+				}
+				removeFields(baseValue, func(l string) bool { return !retained[l] })
+			default:
+				walkErr = errors.Errorf("%s: unknown patchStrategy %s", path, strategy)
+			}
+		})
+		walker.walk(lnode)
+		return lnode, walkErr
+	}
+}
+
+// removeFields removes the fields of the struct whose label matches
+func removeFields(node ast.Node, match func(label string) bool) {
+	filter := func(decls []ast.Decl) []ast.Decl {
+		var ret []ast.Decl
+		for _, decl := range decls {
+			if f, ok := decl.(*ast.Field); ok && match(labelStr(f.Label)) {
+				continue
+			}
+			ret = append(ret, decl)
+		}
+		return ret
+	}
+	switch x := node.(type) {
+	case *ast.File:
+		x.Decls = filter(x.Decls)
+	case *ast.StructLit:
+		x.Elts = filter(x.Elts)
+	}
+}
+
 // StrategyUnify unify the objects by the strategy
 func StrategyUnify(base, patch string) (string, error) {
 	baseFile, err := parser.ParseFile("-", base, parser.ParseComments)
@@ -108,7 +187,7 @@ func StrategyUnify(base, patch string) (string, error) {
 		return "", errors.WithMessage(err, "invalid patch cue file")
 	}
 
-	return strategyUnify(baseFile, patchFile, listMergeByKey(baseFile))
+	return strategyUnify(baseFile, patchFile, listMergeByKey(baseFile), patchStrategy(baseFile))
 }
 
 func strategyUnify(baseFile *ast.File, patchFile *ast.File, patchOpts ...interceptor) (string, error) {
@@ -137,16 +216,33 @@ func strategyUnify(baseFile *ast.File, patchFile *ast.File, patchOpts ...interce
 	}
 
 	if err := ret.Err(); err != nil {
-		return rv, errors.WithMessage(err, "result check err")
+		return rv, errors.WithMessage(withPath(err), "result check err")
 	}
 
 	if err := ret.Validate(cue.All()); err != nil {
-		return rv, errors.WithMessage(err, "result validate")
+		return rv, errors.WithMessage(withPath(err), "result validate")
 	}
 
 	return rv, nil
 }
 
+// withPath formats the errors of cue with the path of the conflicting fields
+func withPath(err error) error {
+	var msgs []string
+	for _, e := range cueerrors.Errors(err) {
+		format, args := e.Msg()
+		msg := fmt.Sprintf(format, args...)
+		if path := e.Path(); len(path) > 0 {
+			msg = fmt.Sprintf("%s: %s", strings.Join(path, "."), msg)
+		}
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 {
+		return err
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
 func findCommentTag(commentGroup []*ast.CommentGroup) map[string]string {
 	marker := "+"
 	kval := map[string]string{}
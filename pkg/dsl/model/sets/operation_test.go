@@ -1,7 +1,9 @@
 package sets
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
 	"cuelang.org/go/cue"
@@ -176,3 +178,87 @@ x: null
 		"testKey4": "testValue4",
 	})
 }
+
+func TestPatchStrategy(t *testing.T) {
+	testCases := map[string]struct {
+		base   string
+		patch  string
+		result map[string]interface{}
+		errMsg string
+	}{
+		"replace list": {
+			base: `containers: [{name: "x1"},{name: "x2"},...]`,
+			patch: `
+// +patchStrategy=replace
+containers: [{name: "x3"}]`,
+			result: map[string]interface{}{
+				"containers": []interface{}{map[string]interface{}{"name": "x3"}},
+			},
+		},
+		"replace value": {
+			base: `spec: {replicas: 1, paused: false}`,
+			patch: `spec: {
+	// +patchStrategy=replace
+	replicas: 3
+}`,
+			result: map[string]interface{}{
+				"spec": map[string]interface{}{"replicas": float64(3), "paused": false},
+			},
+		},
+		"retain keys": {
+			base: `metadata: {name: "x", labels: {a: "1", b: "2"}}`,
+			patch: `metadata: {
+	// +patchStrategy=retainKeys
+	labels: {a: "1", c: "3"}
+}`,
+			result: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name":   "x",
+					"labels": map[string]interface{}{"a": "1", "c": "3"},
+				},
+			},
+		},
+		"strategy only takes effect on the tagged field": {
+			base: `spec: {replicas: 1, paused: false}`,
+			patch: `spec: {
+	// +patchStrategy=replace
+	replicas: 3
+	paused: true
+}`,
+			errMsg: "spec.paused",
+		},
+		"conflict": {
+			base:   `spec: replicas: 1`,
+			patch:  `spec: replicas: 2`,
+			errMsg: "spec.replicas",
+		},
+		"unknown strategy": {
+			base: `spec: replicas: 1`,
+			patch: `spec: {
+	// +patchStrategy=unknown
+	replicas: 2
+}`,
+			errMsg: "unknown patchStrategy",
+		},
+	}
+
+	for name, tc := range testCases {
+		v, err := StrategyUnify(tc.base, tc.patch)
+		if tc.errMsg != "" {
+			assert.Equal(t, true, err != nil, name)
+			if err != nil {
+				assert.Equal(t, true, strings.Contains(err.Error(), tc.errMsg), fmt.Sprintf("%s: %v", name, err))
+			}
+			continue
+		}
+		assert.Equal(t, nil, err, name)
+		var r cue.Runtime
+		inst, err := r.Compile("-", v)
+		assert.Equal(t, nil, err, name)
+		bt, err := inst.Value().MarshalJSON()
+		assert.Equal(t, nil, err, name)
+		result := map[string]interface{}{}
+		assert.Equal(t, nil, json.Unmarshal(bt, &result), name)
+		assert.Equal(t, tc.result, result, name)
+	}
+}
@@ -43,10 +43,15 @@ func (nwk *nodewalker) walk(node ast.Node) {
 			origin := nwk.pos
 			oriTags := nwk.tags
 			nwk.pos = append(nwk.pos, labelStr(n.Label))
-			tags := findCommentTag(n.Comments())
-			for tk, tv := range tags {
-				nwk.tags[tk] = tv
+			// the tags are inherited by the children but not the siblings
+			tags := map[string]string{}
+			for tk, tv := range oriTags {
+				tags[tk] = tv
 			}
+			for tk, tv := range findCommentTag(n.Comments()) {
+				tags[tk] = tv
+			}
+			nwk.tags = tags
 
 			nwk.walk(n.Value)
 			nwk.tags = oriTags
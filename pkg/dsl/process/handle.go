@@ -6,6 +6,8 @@ import (
 	"strings"
 	"unicode"
 
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
 	"github.com/oam-dev/kubevela/pkg/dsl/model"
 )
 
@@ -17,6 +19,16 @@ type Context interface {
 	Output() (model.Instance, []Auxiliary)
 	BaseContextFile() string
 	BaseContextLabels() map[string]string
+	SetNamespace(namespace string)
+	Namespace() string
+	SetComponentType(componentType string)
+	SetAppLabels(labels map[string]string)
+	SetAppAnnotations(annotations map[string]string)
+	SetEnv(env string)
+	SetClient(cli client.Reader)
+	Client() client.Reader
+	SetInReconcile(inReconcile bool)
+	InReconcile() bool
 }
 
 // Auxiliary are objects rendered by definition template.
@@ -40,21 +52,37 @@ type templateContext struct {
 	// name is the component name of Application
 	name string
 	// appName is the name of Application
-	appName     string
+	appName string
+	// appRevision is the revision name of Application
+	appRevision string
+	// namespace is the namespace of Application
+	namespace string
+	// componentType is the workload type of the component
+	componentType string
+	// appLabels and appAnnotations are the labels and annotations of Application
+	appLabels      map[string]string
+	appAnnotations map[string]string
+	// env is the name of the environment which Application is deployed to
+	env string
+	// cli is used by the processing tasks to read resources in cluster
+	cli client.Reader
+	// inReconcile is set when rendering in the reconcile of the controller, the processing tasks don't block it
+	inReconcile bool
 	configs     []map[string]string
 	base        model.Instance
 	auxiliaries []Auxiliary
-
-	// TODO(wonderflow): add a revision number here, and it should be a suffix combined with appName to be the name of AppConfig
 }
 
 // NewContext create render templateContext
-func NewContext(name, appName string) Context {
+func NewContext(name, appName, appRevision string) Context {
 	return &templateContext{
-		name:        name,
-		appName:     appName,
-		configs:     []map[string]string{},
-		auxiliaries: []Auxiliary{},
+		name:           name,
+		appName:        appName,
+		appRevision:    appRevision,
+		configs:        []map[string]string{},
+		auxiliaries:    []Auxiliary{},
+		appLabels:      map[string]string{},
+		appAnnotations: map[string]string{},
 	}
 }
 
@@ -73,11 +101,75 @@ func (ctx *templateContext) AppendAuxiliaries(auxiliaries ...Auxiliary) {
 	ctx.auxiliaries = append(ctx.auxiliaries, auxiliaries...)
 }
 
+// SetNamespace set the namespace of Application
+func (ctx *templateContext) SetNamespace(namespace string) {
+	ctx.namespace = namespace
+}
+
+// Namespace return the namespace of Application
+func (ctx *templateContext) Namespace() string {
+	return ctx.namespace
+}
+
+// SetComponentType set the workload type of the component
+func (ctx *templateContext) SetComponentType(componentType string) {
+	ctx.componentType = componentType
+}
+
+// SetAppLabels set the labels of Application
+func (ctx *templateContext) SetAppLabels(labels map[string]string) {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	ctx.appLabels = labels
+}
+
+// SetAppAnnotations set the annotations of Application
+func (ctx *templateContext) SetAppAnnotations(annotations map[string]string) {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	ctx.appAnnotations = annotations
+}
+
+// SetEnv set the name of the environment which Application is deployed to
+func (ctx *templateContext) SetEnv(env string) {
+	ctx.env = env
+}
+
+// SetClient set the client used to read resources in cluster when rendering
+func (ctx *templateContext) SetClient(cli client.Reader) {
+	ctx.cli = cli
+}
+
+// Client return the client used to read resources in cluster, it's nil when rendering without a cluster
+func (ctx *templateContext) Client() client.Reader {
+	return ctx.cli
+}
+
+// SetInReconcile set whether it's rendering in the reconcile of the controller
+func (ctx *templateContext) SetInReconcile(inReconcile bool) {
+	ctx.inReconcile = inReconcile
+}
+
+// InReconcile return whether it's rendering in the reconcile of the controller
+func (ctx *templateContext) InReconcile() bool {
+	return ctx.inReconcile
+}
+
 // BaseContextFile return cue format string of templateContext
 func (ctx *templateContext) BaseContextFile() string {
 	var buff string
 	buff += fmt.Sprintf("name: \"%s\"\n", ctx.name)
 	buff += fmt.Sprintf("appName: \"%s\"\n", ctx.appName)
+	buff += fmt.Sprintf("appRevision: \"%s\"\n", ctx.appRevision)
+	buff += fmt.Sprintf("namespace: \"%s\"\n", ctx.namespace)
+	buff += fmt.Sprintf("componentType: \"%s\"\n", ctx.componentType)
+	buff += fmt.Sprintf("env: \"%s\"\n", ctx.env)
+	labels, _ := json.Marshal(ctx.appLabels)
+	buff += "appLabels: " + string(labels) + "\n"
+	annotations, _ := json.Marshal(ctx.appAnnotations)
+	buff += "appAnnotations: " + string(annotations) + "\n"
 
 	if ctx.base != nil {
 		buff += fmt.Sprintf("input: %s\n", structMarshal(ctx.base.String()))
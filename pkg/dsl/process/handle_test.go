@@ -26,8 +26,12 @@ image: "myserver"
 		return
 	}
 
-	ctx := NewContext("mycomp", "myapp")
+	ctx := NewContext("mycomp", "myapp", "myapp-v1")
 	ctx.SetBase(base)
+	ctx.SetNamespace("myns")
+	ctx.SetComponentType("worker")
+	ctx.SetAppLabels(map[string]string{"team": "infra"})
+	ctx.SetEnv("prod")
 	ctxInst, err := r.Compile("-", ctx.BaseContextFile())
 	if err != nil {
 		t.Error(err)
@@ -41,6 +45,31 @@ image: "myserver"
 	myAppName, err := ctxInst.Lookup("context", "appName").String()
 	assert.Equal(t, nil, err)
 	assert.Equal(t, "myapp", myAppName)
+
+	myAppRevision, err := ctxInst.Lookup("context", "appRevision").String()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "myapp-v1", myAppRevision)
+
+	myNamespace, err := ctxInst.Lookup("context", "namespace").String()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "myns", myNamespace)
+
+	componentType, err := ctxInst.Lookup("context", "componentType").String()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "worker", componentType)
+
+	env, err := ctxInst.Lookup("context", "env").String()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "prod", env)
+
+	appLabels, err := ctxInst.Lookup("context", "appLabels").MarshalJSON()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `{"team":"infra"}`, string(appLabels))
+
+	appAnnotations, err := ctxInst.Lookup("context", "appAnnotations").MarshalJSON()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `{}`, string(appAnnotations))
+
 	inputJs, err := ctxInst.Lookup("context", "input").MarshalJSON()
 	assert.Equal(t, nil, err)
 	assert.Equal(t, `{"image":"myserver"}`, string(inputJs))
@@ -1,50 +1,191 @@
 package task
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 
 	"cuelang.org/go/cue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/oam-dev/kubevela/pkg/builtin"
+	httptask "github.com/oam-dev/kubevela/pkg/builtin/http"
 	"github.com/oam-dev/kubevela/pkg/builtin/registry"
 )
 
-// Process processing the http task
-func Process(inst *cue.Instance) (*cue.Instance, error) {
-	taskVal := inst.Lookup("processing", "http")
-	if !taskVal.Exists() {
-		return inst, errors.New("there is no http in processing")
+const (
+	// ProcessingFieldName is the name of the struct contains the processing tasks in definition template
+	ProcessingFieldName = "processing"
+	// TasksFieldName is the name of the list of tasks in processing
+	TasksFieldName = "tasks"
+	// OutputsFieldName is the name of the struct in processing which the results of tasks are filled into by task name
+	OutputsFieldName = "outputs"
+
+	// TaskNameField is the name of a task which its result can be referred by
+	TaskNameField = "name"
+	// TaskTypeField is the key of the runner registered in the registry to run a task
+	TaskTypeField = "type"
+)
+
+// Process runs the tasks in processing in order and fills their results back into the template.
+// The result of a task is filled into `processing.outputs.<name>`, so the tasks after it can refer to it.
+// The legacy form that `processing.http` fills the decoded body of response into `processing.output` is also supported,
+// and the status code of the response is filled into `processing.http.response.statusCode`.
+// The client and namespace are used by the tasks which read resources in cluster, e.g. `kube.get`.
+// The tasks don't wait to retry if they are run in the reconcile of the controller.
+func Process(inst *cue.Instance, cli client.Reader, namespace string, inReconcile bool) (*cue.Instance, error) {
+	var err error
+	if inst.Lookup(ProcessingFieldName, "http").Exists() {
+		if inst, err = processHTTP(inst, cli, namespace, inReconcile); err != nil {
+			return nil, err
+		}
+	}
+
+	tasks := inst.Lookup(ProcessingFieldName, TasksFieldName)
+	if !tasks.Exists() {
+		return inst, nil
 	}
-	resp, err := exec(taskVal)
+	names, err := taskNames(tasks)
 	if err != nil {
-		return nil, fmt.Errorf("fail to exec http task, %w", err)
+		return nil, err
+	}
+	for i, name := range names {
+		// look up the task again as it may refer to the results of the tasks before it
+		taskVal, err := lookupTask(inst, i)
+		if err != nil {
+			return nil, err
+		}
+		taskType, err := taskVal.Lookup(TaskTypeField).String()
+		if err != nil {
+			return nil, fmt.Errorf("invalid type of task %s, %w", name, err)
+		}
+
+		got, err := builtin.RunTaskByKey(taskType, taskVal, &registry.Meta{
+			Context:     context.Background(),
+			Obj:         taskVal,
+			Client:      cli,
+			Namespace:   namespace,
+			InReconcile: inReconcile,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("fail to exec task %s(type=%s), %w", name, taskType, err)
+		}
+		result, err := toCueData(got)
+		if err != nil {
+			return nil, fmt.Errorf("invalid result of task %s, %w", name, err)
+		}
+		if inst, err = inst.Fill(result, ProcessingFieldName, OutputsFieldName, name); err != nil {
+			return nil, fmt.Errorf("fail to fill result of task %s, %w", name, err)
+		}
+	}
+	return inst, nil
+}
+
+// taskNames returns the names of the tasks in order, the name of a task must be unique
+func taskNames(tasks cue.Value) ([]string, error) {
+	iter, err := tasks.List()
+	if err != nil {
+		return nil, fmt.Errorf("processing.tasks must be a list, %w", err)
+	}
+	var names []string
+	seen := map[string]bool{}
+	for iter.Next() {
+		name, err := iter.Value().Lookup(TaskNameField).String()
+		if err != nil {
+			return nil, fmt.Errorf("invalid name of the task %d in processing, %w", len(names), err)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate task %s in processing", name)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func lookupTask(inst *cue.Instance, index int) (cue.Value, error) {
+	iter, err := inst.Lookup(ProcessingFieldName, TasksFieldName).List()
+	if err != nil {
+		return cue.Value{}, fmt.Errorf("processing.tasks must be a list, %w", err)
+	}
+	for i := 0; iter.Next(); i++ {
+		if i == index {
+			return iter.Value(), nil
+		}
+	}
+	return cue.Value{}, fmt.Errorf("task %d not found in processing", index)
+}
+
+// toCueData converts the result of a task to plain data, e.g. a nil map is converted to null
+func toCueData(got interface{}) (interface{}, error) {
+	bt, err := json.Marshal(got)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	if err := json.Unmarshal(bt, &data); err != nil {
+		return nil, err
 	}
+	return data, nil
+}
 
-	appInst, err := inst.Fill(resp, "processing", "output")
+func processHTTP(inst *cue.Instance, cli client.Reader, namespace string, inReconcile bool) (*cue.Instance, error) {
+	v := inst.Lookup(ProcessingFieldName, "http")
+	output, statusCode, err := exec(v, cli, namespace, inReconcile)
+	if err != nil {
+		return nil, fmt.Errorf("fail to exec http task, %w", err)
+	}
+	if inst, err = inst.Fill(statusCode, ProcessingFieldName, "http", "response", "statusCode"); err != nil {
+		return nil, fmt.Errorf("fail to fill status code from http, %w", err)
+	}
+	if output == nil {
+		return inst, nil
+	}
+	appInst, err := inst.Fill(output, ProcessingFieldName, "output")
 	if err != nil {
 		return nil, fmt.Errorf("fail to fill output from http, %w", err)
 	}
 	return appInst, nil
 }
 
-func exec(v cue.Value) (map[string]interface{}, error) {
-	got, err := builtin.RunTaskByKey("http", cue.Value{}, &registry.Meta{Obj: v})
+// exec runs the http task and decodes the response body in `response.format` which is json by default.
+// The decoded body is nil if the response is not successful and the body cannot be decoded.
+func exec(v cue.Value, cli client.Reader, namespace string, inReconcile bool) (interface{}, int, error) {
+	format, err := httptask.ResponseFormat(v, httptask.FormatJSON)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	got, err := builtin.RunTaskByKey("http", cue.Value{}, &registry.Meta{
+		Context:     context.Background(),
+		Obj:         v,
+		Client:      cli,
+		Namespace:   namespace,
+		InReconcile: inReconcile,
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 	gotMap, ok := got.(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("fail to convert got to map")
+		return nil, 0, fmt.Errorf("fail to convert got to map")
+	}
+	statusCode, ok := gotMap["statusCode"].(int)
+	if !ok {
+		return nil, 0, fmt.Errorf("fail to convert status code to int")
+	}
+	if data, ok := gotMap["data"]; ok {
+		return data, statusCode, nil
 	}
 	body, ok := gotMap["body"].(string)
 	if !ok {
-		return nil, fmt.Errorf("fail to convert body to string")
+		return nil, 0, fmt.Errorf("fail to convert body to string")
 	}
-	resp := make(map[string]interface{})
-	if err := json.Unmarshal([]byte(body), &resp); err != nil {
-		return nil, err
+	data, err := httptask.DecodeBody([]byte(body), format)
+	if err != nil {
+		if statusCode >= 200 && statusCode < 300 {
+			return nil, 0, err
+		}
+		return nil, statusCode, nil
 	}
-	return resp, nil
+	return data, statusCode, nil
 }
@@ -55,7 +55,7 @@ func TestProcess(t *testing.T) {
 		"serviceURL": "http://127.0.0.1:8090/api/v1/token?val=test-token",
 	}, "parameter")
 
-	inst, err := Process(taskTemplate)
+	inst, err := Process(taskTemplate, nil, "", false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -64,6 +64,144 @@ func TestProcess(t *testing.T) {
 	assert.Equal(t, "{\"data\":\"test-token\"}", data)
 }
 
+const TasksTemplate = `
+import "encoding/json"
+
+parameter: {
+  serviceURL: string
+}
+
+processing: {
+  tasks: [{
+    name: "auth"
+    type: "http"
+    method: "GET"
+    url: parameter.serviceURL
+    request: header: {}
+  }, {
+    name: "echo"
+    type: "http"
+    method: "GET"
+    url: parameter.serviceURL + "-" + json.Unmarshal(processing.outputs.auth.body).token
+    request: header: {}
+  }]
+}
+
+output: {
+  data: json.Unmarshal(processing.outputs.echo.body).token
+}
+`
+
+func TestProcessTasks(t *testing.T) {
+	s := NewMock()
+	defer s.Close()
+
+	r := cue.Runtime{}
+	taskTemplate, err := r.Compile("", TasksTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskTemplate, _ = taskTemplate.Fill(map[string]interface{}{
+		"serviceURL": "http://127.0.0.1:8090/api/v1/token?val=test-token",
+	}, "parameter")
+
+	inst, err := Process(taskTemplate, nil, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := inst.Lookup("output")
+	data, _ := cueJson.Marshal(output)
+	assert.Equal(t, "{\"data\":\"test-token-test-token\"}", data)
+
+	duplicated, err := r.Compile("", `
+processing: tasks: [{
+  name: "auth"
+  type: "http"
+}, {
+  name: "auth"
+  type: "http"
+}]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Process(duplicated, nil, "", false)
+	assert.Equal(t, true, err != nil)
+
+	unknown, err := r.Compile("", `
+processing: tasks: [{
+  name: "auth"
+  type: "unknown"
+}]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Process(unknown, nil, "", false)
+	assert.Equal(t, true, err != nil)
+}
+
+func TestProcessHTTPResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/config" {
+			w.Write([]byte("image: nginx"))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal error"))
+	}))
+	defer ts.Close()
+
+	r := cue.Runtime{}
+	taskTemplate, err := r.Compile("", fmt.Sprintf(`
+processing: {
+  output: image?: string
+  http: {
+    method: "GET"
+    url: "%s/config"
+    response: format: "yaml"
+  }
+}
+output: {
+  image: processing.output.image
+  code: processing.http.response.statusCode
+}
+`, ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst, err := Process(taskTemplate, nil, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := cueJson.Marshal(inst.Lookup("output"))
+	assert.Equal(t, "{\"image\":\"nginx\",\"code\":200}", data)
+
+	failed, err := r.Compile("", fmt.Sprintf(`
+processing: {
+  output: token?: string
+  http: {
+    method: "GET"
+    url: "%s/token"
+  }
+}
+output: {
+  if processing.http.response.statusCode != 200 {
+    failed: true
+  }
+}
+`, ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst, err = Process(failed, nil, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = cueJson.Marshal(inst.Lookup("output"))
+	assert.Equal(t, "{\"failed\":true}", data)
+}
+
 func NewMock() *httptest.Server {
 	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "GET" {
@@ -85,3 +223,31 @@ func NewMock() *httptest.Server {
 	ts.Start()
 	return ts
 }
+
+func TestProcessDNSTasks(t *testing.T) {
+	r := cue.Runtime{}
+	taskTemplate, err := r.Compile("", `
+processing: tasks: [{
+  name: "dbName"
+  type: "dns.label"
+  parts: ["My_App", "db"]
+}, {
+  name: "dbHost"
+  type: "dns.service"
+  service: processing.outputs.dbName
+}]
+
+output: {
+  data: host: processing.outputs.dbHost
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst, err := Process(taskTemplate, nil, "default", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := cueJson.Marshal(inst.Lookup("output"))
+	assert.Equal(t, "{\"data\":{\"host\":\"my-app-db.default.svc.cluster.local\"}}", data)
+}
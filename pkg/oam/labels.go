@@ -27,6 +27,10 @@ const (
 	LabelAppComponentRevision = "app.oam.dev/revision"
 	// LabelOAMResourceType whether a CR is workload or trait
 	LabelOAMResourceType = "app.oam.dev/resourceType"
+	// LabelAppRevisionHash records the hash value of the ApplicationRevision
+	LabelAppRevisionHash = "app.oam.dev/app-revision-hash"
+	// LabelAppEnv records the name of the environment which the Application is deployed to
+	LabelAppEnv = "app.oam.dev/env"
 
 	// WorkloadTypeLabel indicates the type of the workloadDefinition
 	WorkloadTypeLabel = "workload.oam.dev/type"
@@ -34,6 +38,9 @@ const (
 	TraitTypeLabel = "trait.oam.dev/type"
 	// TraitResource indicates which resource it is when a trait is composed by multiple resources in KubeVela
 	TraitResource = "trait.oam.dev/resource"
+
+	// LabelClusterCredential marks the secret which holds the kubeconfig of a cluster that applications can be placed to
+	LabelClusterCredential = "cluster.oam.dev/credential"
 )
 
 const (
@@ -54,4 +61,15 @@ const (
 	// AnnotationAppRollout indicates that the application is still rolling out
 	// the application controller will not reconcile it yet
 	AnnotationAppRollout = "app.oam.dev/rollout-template"
+
+	// AnnotationAppRevision records the name of the ApplicationRevision which the AppConfig is rendered from
+	AnnotationAppRevision = "app.oam.dev/app-revision"
+
+	// AnnotationAppRollback records the name of the ApplicationRevision which the application is rolled back to,
+	// the application is rendered with the definitions snapshotted in that revision until its spec is changed
+	AnnotationAppRollback = "app.oam.dev/rollback-revision"
+
+	// AnnotationTerraformComponents records the components of the application whose cloud resources are applied by
+	// Terraform in the CLI, the application is not finalized until they're destroyed by the CLI and it's removed
+	AnnotationTerraformComponents = "app.oam.dev/terraform-components"
 )
@@ -439,6 +439,12 @@ func GenTraitName(componentName string, ct *v1alpha2.ComponentTrait, traitType s
 
 }
 
+// ConstructAppRevisionName generates the name of ApplicationRevision from the application name,
+// it will be <appName>-v<RevisionNumber>, for example: app-v1
+func ConstructAppRevisionName(appName string, revision int64) string {
+	return fmt.Sprintf("%s-v%d", appName, revision)
+}
+
 // ComputeHash returns a hash value calculated from pod template and
 // a collisionCount to avoid hash collision. The hash will be safe encoded to
 // avoid bad words.
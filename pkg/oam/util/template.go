@@ -17,14 +17,16 @@ import (
 
 // Template includes its string, health and its category
 type Template struct {
-	TemplateStr        string
+	TemplateStr string
+	// TemplateType is the data format of TemplateStr, it's CUE if empty
+	TemplateType       string
 	Health             string
 	CustomStatus       string
 	CapabilityCategory types.CapabilityCategory
 }
 
 // GetScopeGVK Get ScopeDefinition
-func GetScopeGVK(cli client.Client, dm discoverymapper.DiscoveryMapper,
+func GetScopeGVK(cli client.Reader, dm discoverymapper.DiscoveryMapper,
 	name string) (schema.GroupVersionKind, error) {
 	var gvk schema.GroupVersionKind
 	sd := new(v1alpha2.ScopeDefinition)
@@ -56,6 +58,7 @@ func LoadTemplate(cli client.Reader, key string, kd types.CapType) (*Template, e
 			return nil, errors.New("no template found in definition")
 		}
 		tmpl.CapabilityCategory = capabilityCategory
+		tmpl.TemplateType = wd.Spec.TemplateType
 		return tmpl, nil
 
 	case types.TypeTrait:
@@ -63,6 +66,9 @@ func LoadTemplate(cli client.Reader, key string, kd types.CapType) (*Template, e
 		if err != nil {
 			return nil, errors.WithMessagef(err, "LoadTemplate [%s] ", key)
 		}
+		if td.Spec.TemplateType != "" && td.Spec.TemplateType != types.CUETemplateType {
+			return nil, errors.Errorf("LoadTemplate [%s]: templateType %s is not supported by trait", key, td.Spec.TemplateType)
+		}
 		var capabilityCategory types.CapabilityCategory
 		if td.Annotations["type"] == string(types.TerraformCategory) {
 			capabilityCategory = types.TerraformCategory
@@ -1,12 +1,14 @@
 package plugins
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
 
@@ -107,6 +109,27 @@ func TestPrepareParameterTable(t *testing.T) {
 	assert.Contains(t, refContent, "cpu")
 }
 
+func TestGenerateSchemaProperties(t *testing.T) {
+	schema := &openapi3.Schema{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+	"type": "object",
+	"required": ["image"],
+	"properties": {
+		"image": {"type": "string", "description": "Which image would you like to use for your service"},
+		"port": {"type": "integer", "default": 80},
+		"env": {"type": "array", "items": {"type": "object", "properties": {"name": {"type": "string"}}}},
+		"labels": {"type": "object", "additionalProperties": {"type": "string"}}
+	}
+}`), schema))
+	ref := &ConsoleReference{}
+	consoles := ref.GenerateSchemaProperties(schema)
+	assert.Equal(t, 2, len(consoles))
+	assert.Equal(t, "# Properties", consoles[0].TableName)
+	assert.Equal(t, "## env", consoles[1].TableName)
+	assert.Equal(t, int64(80), schemaDefault(float64(80)))
+	assert.Equal(t, "0.5", schemaDefault(0.5))
+}
+
 func TestDeleteRefTestDir(t *testing.T) {
 	if _, err := os.Stat(RefTestDir); err == nil {
 		err := os.RemoveAll(RefTestDir)
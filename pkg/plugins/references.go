@@ -5,10 +5,12 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
 	"cuelang.org/go/cue"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/olekukonko/tablewriter"
 
 	"github.com/oam-dev/kubevela/apis/types"
@@ -421,3 +423,68 @@ func (ref *ConsoleReference) GenerateCapabilityProperties(capability *types.Capa
 
 	return propertyConsole, nil
 }
+
+// GenerateSchemaProperties get all properties from the OpenAPI schema of the parameters of a capability,
+// the table of an object is followed by the tables of its nested objects
+func (ref *ConsoleReference) GenerateSchemaProperties(schema *openapi3.Schema) []ConsoleReference {
+	return ref.parseSchema(schema, "Properties", 1)
+}
+
+// parseSchema parses the properties of an object schema into tables
+func (ref *ConsoleReference) parseSchema(schema *openapi3.Schema, tableName string, depth int) []ConsoleReference {
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var params []ReferenceParameter
+	var nested []ConsoleReference
+	for _, name := range names {
+		if schema.Properties[name] == nil || schema.Properties[name].Value == nil {
+			continue
+		}
+		s := schema.Properties[name].Value
+		param := ReferenceParameter{
+			Parameter: types.Parameter{
+				Name:     name,
+				Required: required[name],
+				Default:  schemaDefault(s.Default),
+				Usage:    s.Description,
+			},
+			PrintableType: s.Type,
+		}
+		switch {
+		case s.Type == "object" && len(s.Properties) > 0:
+			param.PrintableType = fmt.Sprintf("[%s](#%s)", name, name)
+			nested = append(nested, ref.parseSchema(s, name, depth+1)...)
+		case s.Type == "object" && s.AdditionalProperties != nil && s.AdditionalProperties.Value != nil:
+			param.PrintableType = fmt.Sprintf("map[string]%s", s.AdditionalProperties.Value.Type)
+		case s.Type == "array" && s.Items != nil && s.Items.Value != nil:
+			if items := s.Items.Value; items.Type == "object" && len(items.Properties) > 0 {
+				param.PrintableType = fmt.Sprintf("[[]%s](#%s)", name, name)
+				nested = append(nested, ref.parseSchema(items, name, depth+1)...)
+			} else {
+				param.PrintableType = fmt.Sprintf("[]%s", items.Type)
+			}
+		}
+		params = append(params, param)
+	}
+	console := ref.prepareParameter(fmt.Sprintf("%s %s", strings.Repeat("#", depth), tableName), params)
+	return append([]ConsoleReference{console}, nested...)
+}
+
+// schemaDefault converts the default value decoded from JSON to be printable, whole numbers are decoded as float64
+func schemaDefault(v interface{}) interface{} {
+	if f, ok := v.(float64); ok {
+		if f == float64(int64(f)) {
+			return int64(f)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return v
+}
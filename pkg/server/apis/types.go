@@ -73,6 +73,8 @@ type ApplicationMeta struct {
 	Status      string          `json:"status,omitempty"`
 	Components  []ComponentMeta `json:"components,omitempty"`
 	CreatedTime string          `json:"createdTime,omitempty"`
+	// RenderError tells why the application fails to be rendered
+	RenderError *corev1alpha2.ApplicationRenderError `json:"renderError,omitempty"`
 }
 
 // CapabilityMeta used for dashboard restful API server
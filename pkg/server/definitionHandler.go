@@ -7,17 +7,17 @@ import (
 	"github.com/oam-dev/kubevela/pkg/serverlib"
 )
 
-// GetDefinition gets OpenAPI schema from Cue section of a WorkloadDefinition/TraitDefinition
+// GetDefinition gets OpenAPI schema of the parameters of a WorkloadDefinition/TraitDefinition stored by the controller
 // @tags definitions
 // @ID GetDefinition
-// @Summary gets OpenAPI schema from Cue section of a WorkloadDefinition/TraitDefinition
+// @Summary gets OpenAPI schema of the parameters of a WorkloadDefinition/TraitDefinition
 // @Param definitionName path string true "name of workload type or trait"
 // @Success 200 {object} apis.Response{code=int,data=string}
 // @Failure 500 {object} apis.Response{code=int,data=string}
 // @Router /definitions/{definitionName} [get]
 func (s *APIServer) GetDefinition(c *gin.Context) {
 	definitionName := c.Param("name")
-	parameter, err := serverlib.GetDefinition(definitionName)
+	parameter, err := serverlib.GetDefinitionSchema(util.GetContext(c), s.KubeClient, definitionName)
 	if err != nil {
 		util.HandleError(c, util.StatusInternalServerError, err)
 		return
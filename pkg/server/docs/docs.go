@@ -37,7 +37,7 @@ var doc = `{
                 "tags": [
                     "definitions"
                 ],
-                "summary": "gets OpenAPI schema from Cue section of a WorkloadDefinition/TraitDefinition",
+                "summary": "gets OpenAPI schema of the parameters of a WorkloadDefinition/TraitDefinition",
                 "operationId": "GetDefinition",
                 "parameters": [
                     {
@@ -28,6 +28,7 @@ import (
 	cmdutil "github.com/oam-dev/kubevela/pkg/commands/util"
 	"github.com/oam-dev/kubevela/pkg/oam"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
 	"github.com/oam-dev/kubevela/pkg/server/apis"
 	"github.com/oam-dev/kubevela/pkg/utils/common"
 )
@@ -168,6 +169,15 @@ func ListComponents(ctx context.Context, c client.Client, opt Option) ([]apis.Co
 // RetrieveApplicationStatusByName will get app status
 func RetrieveApplicationStatusByName(ctx context.Context, c client.Client, applicationName string, namespace string) (apis.ApplicationMeta, error) {
 	var applicationMeta apis.ApplicationMeta
+	// the ApplicationConfiguration isn't generated if the application fails to be rendered
+	var app corev1alpha2.Application
+	if err := c.Get(ctx, client.ObjectKey{Name: applicationName, Namespace: namespace}, &app); err == nil && app.Status.RenderError != nil {
+		applicationMeta.Name = app.Name
+		applicationMeta.Status = types.StatusRenderFailed
+		applicationMeta.CreatedTime = app.CreationTimestamp.Format(time.RFC3339)
+		applicationMeta.RenderError = app.Status.RenderError
+		return applicationMeta, nil
+	}
 	var appConfig corev1alpha2.ApplicationConfiguration
 	if err := c.Get(ctx, client.ObjectKey{Name: applicationName, Namespace: namespace}, &appConfig); err != nil {
 		return applicationMeta, err
@@ -202,6 +212,12 @@ func RetrieveApplicationStatusByName(ctx context.Context, c client.Client, appli
 
 // DeleteApp will delete app including server side
 func (o *DeleteOptions) DeleteApp() (string, error) {
+	// cloud resources are applied by Terraform locally, destroy them before the appfile is deleted
+	if localApp, err := appfile.GetStorage().Get(o.Env.Name, o.AppName); err == nil {
+		if err := appfile.DestroyTerraform(appfile.GetComponents(localApp), o.Client, o.Env.Namespace); err != nil {
+			return "", err
+		}
+	}
 	if err := appfile.Delete(o.Env.Name, o.AppName); err != nil && !os.IsNotExist(err) {
 		return "", err
 	}
@@ -214,6 +230,13 @@ func (o *DeleteOptions) DeleteApp() (string, error) {
 		}
 		return "", fmt.Errorf("delete appconfig err: %w", err)
 	}
+	// the cloud resources are destroyed, so the controller is allowed to finalize the application
+	if _, ok := app.Annotations[oam.AnnotationTerraformComponents]; ok {
+		delete(app.Annotations, oam.AnnotationTerraformComponents)
+		if err := o.Client.Update(ctx, app); err != nil {
+			return "", fmt.Errorf("update application err: %w", err)
+		}
+	}
 
 	err = o.Client.Delete(ctx, app)
 	if err != nil && !apierrors.IsNotFound(err) {
@@ -224,6 +247,27 @@ func (o *DeleteOptions) DeleteApp() (string, error) {
 	return fmt.Sprintf("app \"%s\" deleted from env \"%s\"", o.AppName, o.Env.Name), nil
 }
 
+// RollbackApplication restores the spec of an application from the snapshot recorded in the given revision,
+// the application is marked to be rendered with the definitions snapshotted in the revision as well,
+// so that the changes of definitions since the revision are rolled back too.
+func RollbackApplication(ctx context.Context, c client.Client, appName string, namespace string, revision int64) error {
+	var app = new(corev1alpha2.Application)
+	if err := c.Get(ctx, client.ObjectKey{Name: appName, Namespace: namespace}, app); err != nil {
+		return errors.Wrapf(err, "get application %s", appName)
+	}
+	var appRev = new(corev1alpha2.ApplicationRevision)
+	revName := util.ConstructAppRevisionName(appName, revision)
+	if err := c.Get(ctx, client.ObjectKey{Name: revName, Namespace: namespace}, appRev); err != nil {
+		return errors.Wrapf(err, "get application revision %s", revName)
+	}
+	app.Spec = *appRev.Spec.Application.DeepCopy()
+	if app.Annotations == nil {
+		app.Annotations = map[string]string{}
+	}
+	app.Annotations[oam.AnnotationAppRollback] = revName
+	return c.Update(ctx, app)
+}
+
 // DeleteComponent will delete one component including server side.
 func (o *DeleteOptions) DeleteComponent(io cmdutil.IOStreams) (string, error) {
 	var app *api.Application
@@ -444,12 +488,35 @@ func (o *AppfileOptions) BaseAppFileRun(result *BuildResult, data []byte, dm dis
 	if err != nil {
 		return err
 	}
+	// record the components applied by Terraform, the controller keeps the application from being finalized
+	// until they're destroyed by `vela delete`
+	if cloudComponents := terraformComponents(result.application.Spec.Components, kubernetesComponent); len(cloudComponents) > 0 {
+		if result.application.Annotations == nil {
+			result.application.Annotations = map[string]string{}
+		}
+		result.application.Annotations[oam.AnnotationTerraformComponents] = strings.Join(cloudComponents, ",")
+	}
 	result.application.Spec.Components = kubernetesComponent
 
 	o.IO.Infof("\nApplying application ...\n")
 	return o.ApplyApp(result.application, result.scopes)
 }
 
+// terraformComponents returns the names of the components which are not applied to Kubernetes
+func terraformComponents(all, kubernetesComponents []corev1alpha2.ApplicationComponent) []string {
+	applied := map[string]bool{}
+	for _, comp := range kubernetesComponents {
+		applied[comp.Name] = true
+	}
+	var names []string
+	for _, comp := range all {
+		if !applied[comp.Name] {
+			names = append(names, comp.Name)
+		}
+	}
+	return names
+}
+
 func (o *AppfileOptions) saveToAppDir(f *api.AppFile) error {
 	app := &api.Application{AppFile: f}
 	return appfile.Save(app, o.Env.Name)
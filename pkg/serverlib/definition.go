@@ -2,14 +2,15 @@ package serverlib
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 
-	"github.com/getkin/kin-openapi/openapi3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	mycue "github.com/oam-dev/kubevela/pkg/cue"
 	"github.com/oam-dev/kubevela/pkg/utils/common"
@@ -19,10 +20,6 @@ import (
 const (
 	// OpenAPISchemaDir is the folder name under ~/.vela/capabilities
 	OpenAPISchemaDir = "openapi"
-	// UsageTag is usage comment annotation
-	UsageTag = "+usage="
-	// ShortTag is the short alias annotation
-	ShortTag = "+short"
 )
 
 // OpenAPISchema is the struct for OpenAPI Schema generated by Cue OpenAPI
@@ -47,19 +44,20 @@ func GetDefinition(name string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	swagger, err := openapi3.NewSwaggerLoader().LoadSwaggerFromData(openAPISchema)
-	if err != nil {
-		return nil, err
-	}
-	schemaRef := swagger.Components.Schemas["parameter"]
-	schema := schemaRef.Value
-	fixOpenAPISchema("", schema)
+	return common.FixParameterSchema(openAPISchema)
+}
 
-	parameter, err := schema.MarshalJSON()
-	if err != nil {
+// GetDefinitionSchema gets the OpenAPI schema of the parameters of a definition stored by the controller,
+// it falls back to generate the schema from the local synced definition if the schema isn't stored.
+func GetDefinitionSchema(ctx context.Context, c client.Reader, name string) ([]byte, error) {
+	schema, err := common.GetDefinitionSchema(ctx, c, system.GetDefinitionSchemaNamespace(), name)
+	if err == nil {
+		return schema, nil
+	}
+	if !apierrors.IsNotFound(err) {
 		return nil, err
 	}
-	return parameter, nil
+	return GetDefinition(name)
 }
 
 // generateOpenAPISchemaFromCapabilityParameter returns the parameter of a definition in cue.Value format
@@ -114,15 +112,9 @@ func prepareParameterCue(fileDir, fileName string, targetSchemaDir string) error
 
 	scanner := bufio.NewScanner(f)
 	var withParameterFlag bool
-	r := regexp.MustCompile("[[:space:]]*parameter:[[:space:]]*{.*")
-
 	for scanner.Scan() {
-		text := scanner.Text()
-		if r.MatchString(text) {
-			// a variable has to be refined as a definition which starts with "#"
-			text = fmt.Sprintf("parameter: #parameter\n#%s", text)
-			withParameterFlag = true
-		}
+		text, found := common.RefineParameterSection(scanner.Text())
+		withParameterFlag = withParameterFlag || found
 		if _, err := targetFile.WriteString(fmt.Sprintf("%s\n", text)); err != nil {
 			return err
 		}
@@ -149,33 +141,6 @@ func appendCueReference(cueFile string) error {
 	return nil
 }
 
-// fixOpenAPISchema fixes tainted `description` filed, missing of title `field`.
-func fixOpenAPISchema(name string, schema *openapi3.Schema) {
-	t := schema.Type
-	switch t {
-	case "object":
-		for k, v := range schema.Properties {
-			s := v.Value
-			fixOpenAPISchema(k, s)
-		}
-	case "array":
-		fixOpenAPISchema("", schema.Items.Value)
-	}
-	if name != "" {
-		schema.Title = name
-	}
-
-	description := schema.Description
-	if strings.Contains(description, UsageTag) {
-		description = strings.Split(description, UsageTag)[1]
-	}
-	if strings.Contains(description, ShortTag) {
-		description = strings.Split(description, ShortTag)[0]
-		description = strings.TrimSpace(description)
-	}
-	schema.Description = description
-}
-
 // getParameterItemName gets the name of a parameter item
 func getParameterItemName(previousLine string) (string, error) {
 	// parse property name, like from `"cmd": {\n`
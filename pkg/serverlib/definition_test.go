@@ -8,8 +8,6 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/getkin/kin-openapi/openapi3"
-
 	"github.com/crossplane/crossplane-runtime/pkg/test"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
@@ -158,33 +156,6 @@ func TestAppendCueReference(t *testing.T) {
 	os.RemoveAll(temporaryDir)
 }
 
-func TestFixOpenAPISchema(t *testing.T) {
-	cases := map[string]struct {
-		inputFile string
-		fixedFile string
-	}{
-		"StandardWorkload": {
-			inputFile: "webservice.json",
-			fixedFile: "webserviceFixed.json",
-		},
-		"ShortTagJson": {
-			inputFile: "shortTagSchema.json",
-			fixedFile: "shortTagSchemaFixed.json",
-		},
-	}
-
-	for name, tc := range cases {
-		t.Run(name, func(t *testing.T) {
-			swagger, _ := openapi3.NewSwaggerLoader().LoadSwaggerFromFile(filepath.Join(TestDir, tc.inputFile))
-			schema := swagger.Components.Schemas["parameter"].Value
-			fixOpenAPISchema("", schema)
-			fixedSchema, _ := schema.MarshalJSON()
-			expectedSchema, _ := ioutil.ReadFile(filepath.Join(TestDir, tc.fixedFile))
-			assert.Equal(t, fixedSchema, expectedSchema)
-		})
-	}
-}
-
 func TestGetParameterItemName(t *testing.T) {
 	got, err := getParameterItemName("    \"cmd\": {\n")
 	name := "cmd"
@@ -0,0 +1,169 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	mycue "github.com/oam-dev/kubevela/pkg/cue"
+)
+
+const (
+	// UsageTag is usage comment annotation
+	UsageTag = "+usage="
+	// ShortTag is the short alias annotation
+	ShortTag = "+short"
+)
+
+const (
+	// OpenAPISchemaKey is the key of the OpenAPI schema of parameters in the ConfigMap of a definition
+	OpenAPISchemaKey = "openapi-v3-json-schema"
+	// SchemaGenerationAnnotation records the generation of the definition which the schema in the ConfigMap is generated from
+	SchemaGenerationAnnotation = "definition.oam.dev/generation"
+	// parameterSchemaName is the name of the schema of parameters in the OpenAPI document generated by CUE
+	parameterSchemaName = "parameter"
+)
+
+// parameterLine matches the line which starts the `parameter` section of a CUE template
+var parameterLine = regexp.MustCompile("[[:space:]]*parameter:[[:space:]]*{.*")
+
+// SchemaConfigMapName returns the name of the ConfigMap which stores the OpenAPI schema of the parameters of a definition
+func SchemaConfigMapName(capType types.CapType, name string) string {
+	return fmt.Sprintf("schema-%s-%s", capType, name)
+}
+
+// RefineParameterSection refines the `parameter` section of a CUE template as a definition, so that it's
+// generated into the OpenAPI schema
+func RefineParameterSection(template string) (string, bool) {
+	lines := strings.Split(template, "\n")
+	var found bool
+	for i, line := range lines {
+		if parameterLine.MatchString(line) {
+			// a variable has to be refined as a definition which starts with "#"
+			lines[i] = fmt.Sprintf("parameter: #parameter\n#%s", line)
+			found = true
+		}
+	}
+	return strings.Join(lines, "\n"), found
+}
+
+// GenOpenAPISchemaFromTemplate generates the OpenAPI schema of the parameters of a CUE template,
+// the descriptions are fixed and the properties are titled by their names
+func GenOpenAPISchemaFromTemplate(template string) ([]byte, error) {
+	refined, found := RefineParameterSection(template)
+	if !found {
+		return nil, errors.New("template doesn't contain section `parameter`")
+	}
+	var r cue.Runtime
+	inst, err := r.Compile("-", refined+mycue.BaseTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "compile template")
+	}
+	openAPISchema, err := GenOpenAPI(inst)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate OpenAPI schema")
+	}
+	return FixParameterSchema(openAPISchema)
+}
+
+// FixParameterSchema picks the schema of parameters from the OpenAPI document generated by CUE and fixes it
+func FixParameterSchema(openAPISchema []byte) ([]byte, error) {
+	swagger, err := openapi3.NewSwaggerLoader().LoadSwaggerFromData(openAPISchema)
+	if err != nil {
+		return nil, err
+	}
+	schemaRef, ok := swagger.Components.Schemas[parameterSchemaName]
+	if !ok || schemaRef.Value == nil {
+		return nil, errors.New("no schema of parameter is generated")
+	}
+	schema := schemaRef.Value
+	FixOpenAPISchema("", schema)
+	return schema.MarshalJSON()
+}
+
+// FixOpenAPISchema fixes tainted `description` filed, missing of title `field`.
+func FixOpenAPISchema(name string, schema *openapi3.Schema) {
+	t := schema.Type
+	switch t {
+	case "object":
+		for k, v := range schema.Properties {
+			s := v.Value
+			FixOpenAPISchema(k, s)
+		}
+	case "array":
+		FixOpenAPISchema("", schema.Items.Value)
+	}
+	if name != "" {
+		schema.Title = name
+	}
+
+	description := schema.Description
+	if strings.Contains(description, UsageTag) {
+		description = strings.Split(description, UsageTag)[1]
+	}
+	if strings.Contains(description, ShortTag) {
+		description = strings.Split(description, ShortTag)[0]
+		description = strings.TrimSpace(description)
+	}
+	schema.Description = description
+}
+
+// GetDefinitionSchema gets the OpenAPI schema of the parameters of a WorkloadDefinition or TraitDefinition, which is
+// stored by the controller in the namespace, the schema of WorkloadDefinition is preferred if both exist.
+// A NotFound error is returned if neither exists.
+func GetDefinitionSchema(ctx context.Context, cli client.Reader, namespace, name string) ([]byte, error) {
+	var lastErr error
+	for _, capType := range []types.CapType{types.TypeWorkload, types.TypeTrait} {
+		cm := &corev1.ConfigMap{}
+		err := cli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: SchemaConfigMapName(capType, name)}, cm)
+		if kerrors.IsNotFound(err) {
+			lastErr = err
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		schema, ok := cm.Data[OpenAPISchemaKey]
+		if !ok {
+			return nil, errors.Errorf("no %s in ConfigMap %s", OpenAPISchemaKey, cm.Name)
+		}
+		return []byte(schema), nil
+	}
+	return nil, lastErr
+}
+
+// GetCurrentDefinitionSchema gets the OpenAPI schema of the parameters of the definition stored by the controller in the
+// namespace, false is returned if it's not stored yet or it's stale, i.e. generated from another generation of the definition.
+func GetCurrentDefinitionSchema(ctx context.Context, cli client.Reader, namespace string, capType types.CapType,
+	def metav1.Object) ([]byte, bool, error) {
+	cm := &corev1.ConfigMap{}
+	err := cli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: SchemaConfigMapName(capType, def.GetName())}, cm)
+	if kerrors.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	// the definition may be deleted and created again with the same name, its generation starts over
+	owner := metav1.GetControllerOf(cm)
+	if owner == nil || owner.UID != def.GetUID() ||
+		cm.GetAnnotations()[SchemaGenerationAnnotation] != strconv.FormatInt(def.GetGeneration(), 10) {
+		return nil, false, nil
+	}
+	schema, ok := cm.Data[OpenAPISchemaKey]
+	if !ok {
+		return nil, false, nil
+	}
+	return []byte(schema), true, nil
+}
@@ -0,0 +1,141 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	//lint:ignore SA1019 We will use pkg/envtest before upgrading controller-runtime to v1.0.0
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/kubevela/apis/types"
+)
+
+func TestFixOpenAPISchema(t *testing.T) {
+	cases := map[string]struct {
+		inputFile string
+		fixedFile string
+	}{
+		"StandardWorkload": {
+			inputFile: "webservice.json",
+			fixedFile: "webserviceFixed.json",
+		},
+		"ShortTagJson": {
+			inputFile: "shortTagSchema.json",
+			fixedFile: "shortTagSchemaFixed.json",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			swagger, _ := openapi3.NewSwaggerLoader().LoadSwaggerFromFile(filepath.Join("testdata", tc.inputFile))
+			schema := swagger.Components.Schemas["parameter"].Value
+			FixOpenAPISchema("", schema)
+			fixedSchema, _ := schema.MarshalJSON()
+			expectedSchema, _ := ioutil.ReadFile(filepath.Join("testdata", tc.fixedFile))
+			assert.Equal(t, fixedSchema, expectedSchema)
+		})
+	}
+}
+
+func TestGenOpenAPISchemaFromTemplate(t *testing.T) {
+	data, err := GenOpenAPISchemaFromTemplate(`
+output: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	metadata: name: context.name
+}
+parameter: {
+	// +usage=Which image would you like to use for your service
+	// +short=i
+	image: string
+	// +usage=Which port do you want customer traffic sent to
+	port: *80 | int
+	protocol: "TCP" | "UDP"
+}
+`)
+	assert.NoError(t, err)
+	schema := &openapi3.Schema{}
+	assert.NoError(t, json.Unmarshal(data, schema))
+	assert.Equal(t, "object", schema.Type)
+	image := schema.Properties["image"].Value
+	assert.Equal(t, "image", image.Title)
+	assert.Equal(t, "Which image would you like to use for your service", image.Description)
+	assert.Equal(t, float64(80), schema.Properties["port"].Value.Default)
+	assert.Equal(t, []interface{}{"TCP", "UDP"}, schema.Properties["protocol"].Value.Enum)
+
+	_, err = GenOpenAPISchemaFromTemplate(`output: kind: "Deployment"`)
+	assert.Error(t, err)
+}
+
+func TestGetDefinitionSchema(t *testing.T) {
+	cm := func(capType types.CapType, name, schema string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: SchemaConfigMapName(capType, name), Namespace: types.DefaultKubeVelaNS},
+			Data:       map[string]string{OpenAPISchemaKey: schema},
+		}
+	}
+	cli := fake.NewFakeClientWithScheme(Scheme,
+		cm(types.TypeWorkload, "webservice", `{"title":"webservice"}`),
+		cm(types.TypeTrait, "scaler", `{"title":"scaler"}`),
+	)
+	ctx := context.Background()
+	schema, err := GetDefinitionSchema(ctx, cli, types.DefaultKubeVelaNS, "webservice")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"title":"webservice"}`, string(schema))
+	schema, err = GetDefinitionSchema(ctx, cli, types.DefaultKubeVelaNS, "scaler")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"title":"scaler"}`, string(schema))
+	_, err = GetDefinitionSchema(ctx, cli, types.DefaultKubeVelaNS, "route")
+	assert.True(t, kerrors.IsNotFound(err))
+}
+
+func TestGetCurrentDefinitionSchema(t *testing.T) {
+	wd := &metav1.ObjectMeta{Name: "webservice", UID: "webservice-uid", Generation: 2}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        SchemaConfigMapName(types.TypeWorkload, "webservice"),
+			Namespace:   types.DefaultKubeVelaNS,
+			Annotations: map[string]string{SchemaGenerationAnnotation: "2"},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "core.oam.dev/v1alpha2",
+				Kind:       "WorkloadDefinition",
+				Name:       "webservice",
+				UID:        "webservice-uid",
+				Controller: pointer.BoolPtr(true),
+			}},
+		},
+		Data: map[string]string{OpenAPISchemaKey: `{"title":"webservice"}`},
+	}
+	cli := fake.NewFakeClientWithScheme(Scheme, cm)
+	ctx := context.Background()
+	schema, ok, err := GetCurrentDefinitionSchema(ctx, cli, types.DefaultKubeVelaNS, types.TypeWorkload, wd)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `{"title":"webservice"}`, string(schema))
+
+	// the definition is updated after the schema is generated
+	wd.Generation = 3
+	_, ok, err = GetCurrentDefinitionSchema(ctx, cli, types.DefaultKubeVelaNS, types.TypeWorkload, wd)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// the definition is deleted and created again
+	wd.Generation, wd.UID = 2, "another-uid"
+	_, ok, err = GetCurrentDefinitionSchema(ctx, cli, types.DefaultKubeVelaNS, types.TypeWorkload, wd)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, ok, err = GetCurrentDefinitionSchema(ctx, cli, types.DefaultKubeVelaNS, types.TypeTrait, wd)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -1,6 +1,7 @@
 package helm
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -232,6 +233,39 @@ func GetHelmRelease(ns string) ([]*release.Release, error) {
 	return results, nil
 }
 
+// RenderChart renders the manifests of a chart with the values locally without installing it,
+// the chart can be a local path, a URL of the chart archive, the name of a chart in the repository of repoURL,
+// or a chart in an OCI registry referred to by oci://host/repo/chart[:tag][@digest], which is tagged by the version if
+// neither tag nor digest is specified.
+func RenderChart(repoURL, chartName, version, releaseName, namespace string, values map[string]interface{}) (string, error) {
+	client := action.NewInstall(&action.Configuration{Log: debug})
+	client.DryRun = true
+	client.ClientOnly = true
+	client.Replace = true
+	client.ReleaseName = releaseName
+	client.Namespace = namespace
+	client.RepoURL = repoURL
+	client.Version = version
+	var (
+		chartRequested *chart.Chart
+		err            error
+	)
+	// the registry client of helm v3.2 is internal, so the chart is pulled by the client of OCI registry
+	if IsOCIChart(chartName) {
+		chartRequested, err = pullOCIChart(context.Background(), chartName, version)
+	} else {
+		chartRequested, err = GetChart(client, chartName)
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "locate chart %s", chartName)
+	}
+	rel, err := client.Run(chartRequested, values)
+	if err != nil {
+		return "", errors.Wrapf(err, "render chart %s", chartName)
+	}
+	return rel.Manifest, nil
+}
+
 // GetChart will locate chart
 func GetChart(client *action.Install, name string) (*chart.Chart, error) {
 	if os.Getenv(VelaDebugLog) != "" {
@@ -0,0 +1,168 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/distribution/reference"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+const (
+	// ociScheme is the scheme of the reference to a chart in an OCI registry
+	ociScheme = "oci://"
+	// chartLayerMediaType is the media type of the layer of the chart archive pushed by helm v3.7+
+	chartLayerMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	// legacyChartLayerMediaType is the media type of the layer of the chart archive pushed by the experimental
+	// OCI support of helm before v3.7
+	legacyChartLayerMediaType = "application/tar+gzip"
+	// maxManifestSize caps the size of the manifest of a chart read from registry
+	maxManifestSize = 4 << 20
+)
+
+// ociClient is the HTTP client to access the OCI registries, it's replaced in tests
+var ociClient = http.DefaultClient
+
+// IsOCIChart returns whether the chart is referred to in an OCI registry by oci://host/repo/chart[:tag][@digest]
+func IsOCIChart(name string) bool {
+	return strings.HasPrefix(name, ociScheme)
+}
+
+// parseOCIReference parses the reference to a chart in an OCI registry, the version is used as the tag
+// if the reference has neither tag nor digest
+func parseOCIReference(name, version string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(strings.TrimPrefix(name, ociScheme))
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid chart %s", name)
+	}
+	_, digested := named.(reference.Digested)
+	_, tagged := named.(reference.Tagged)
+	if !digested && !tagged {
+		if version == "" {
+			return "", errors.Errorf("version is required for chart %s in OCI registry", name)
+		}
+		if named, err = reference.WithTag(named, version); err != nil {
+			return "", errors.Wrapf(err, "invalid version %s of chart %s", version, name)
+		}
+	}
+	return named.String(), nil
+}
+
+// newOCIResolver creates the resolver of OCI registries, it authenticates with the credentials stored by `helm registry login`
+func newOCIResolver() remotes.Resolver {
+	return docker.NewResolver(docker.ResolverOptions{Credentials: lookupRegistryCredentials, Client: ociClient})
+}
+
+// lookupRegistryCredentials returns the username and secret of the registry in the registry config of helm,
+// which is in the format of docker config
+func lookupRegistryCredentials(host string) (string, string, error) {
+	f, err := os.Open(settings.RegistryConfig)
+	if os.IsNotExist(err) {
+		// not logged in to any registry
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", errors.Wrap(err, "open registry config")
+	}
+	//nolint:errcheck
+	defer f.Close()
+	cfg, err := config.LoadFromReader(f)
+	if err != nil {
+		return "", "", errors.Wrap(err, "load registry config")
+	}
+	auth, err := cfg.GetAuthConfig(host)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "get credentials of registry %s", host)
+	}
+	// an identity token is used as a long lived token without username
+	if auth.IdentityToken != "" {
+		return "", auth.IdentityToken, nil
+	}
+	return auth.Username, auth.Password, nil
+}
+
+// ResolveOCIChart resolves the digest of the manifest of a chart in an OCI registry, which identifies the content
+// of the chart even if it's referred to by a tag
+func ResolveOCIChart(ctx context.Context, name, version string) (string, error) {
+	ref, err := parseOCIReference(name, version)
+	if err != nil {
+		return "", err
+	}
+	_, desc, err := newOCIResolver().Resolve(ctx, ref)
+	if err != nil {
+		return "", errors.Wrapf(err, "resolve chart %s", ref)
+	}
+	return desc.Digest.String(), nil
+}
+
+// pullOCIChart pulls a chart from an OCI registry and loads it
+func pullOCIChart(ctx context.Context, name, version string) (*chart.Chart, error) {
+	ref, err := parseOCIReference(name, version)
+	if err != nil {
+		return nil, err
+	}
+	resolver := newOCIResolver()
+	resolved, desc, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolve chart %s", ref)
+	}
+	fetcher, err := resolver.Fetcher(ctx, resolved)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch chart %s", ref)
+	}
+	if desc.Size == 0 || desc.Size > maxManifestSize {
+		desc.Size = maxManifestSize
+	}
+	bt, err := fetchBlob(ctx, fetcher, desc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch manifest of chart %s", ref)
+	}
+	manifest := ocispec.Manifest{}
+	if err := json.Unmarshal(bt, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "invalid manifest of chart %s", ref)
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != chartLayerMediaType && layer.MediaType != legacyChartLayerMediaType {
+			continue
+		}
+		data, err := fetchBlob(ctx, fetcher, layer)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetch archive of chart %s", ref)
+		}
+		return loader.LoadArchive(bytes.NewReader(data))
+	}
+	return nil, errors.Errorf("no chart archive in the manifest of %s", ref)
+}
+
+// fetchBlob reads the blob of the descriptor up to its size and verifies its digest
+func fetchBlob(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor) ([]byte, error) {
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:errcheck
+	defer rc.Close()
+	bt, err := ioutil.ReadAll(io.LimitReader(rc, desc.Size))
+	if err != nil {
+		return nil, err
+	}
+	if err := desc.Digest.Validate(); err != nil {
+		return nil, err
+	}
+	if actual := desc.Digest.Algorithm().FromBytes(bt); actual != desc.Digest {
+		return nil, errors.Errorf("digest mismatch, expected %s but got %s", desc.Digest, actual)
+	}
+	return bt, nil
+}
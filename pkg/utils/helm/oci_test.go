@@ -0,0 +1,120 @@
+package helm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// chartArchive archives the files of a chart into a gzipped tarball
+func chartArchive(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		assert.Equal(t, nil, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		assert.Equal(t, nil, err)
+	}
+	assert.Equal(t, nil, tw.Close())
+	assert.Equal(t, nil, gw.Close())
+	return buf.Bytes()
+}
+
+func TestRenderOCIChart(t *testing.T) {
+	archive := chartArchive(t, map[string]string{
+		"web/Chart.yaml": "apiVersion: v2\nname: web\nversion: 0.1.0\n",
+		"web/templates/configmap.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}
+data:
+  greeting: {{ .Values.greeting }}
+`,
+	})
+	config := []byte(`{"name":"web","version":"0.1.0"}`)
+	manifest, err := json.Marshal(ocispec.Manifest{
+		Config: ocispec.Descriptor{
+			MediaType: "application/vnd.cncf.helm.config.v1+json",
+			Digest:    digest.FromBytes(config),
+			Size:      int64(len(config)),
+		},
+		Layers: []ocispec.Descriptor{{
+			MediaType: legacyChartLayerMediaType,
+			Digest:    digest.FromBytes(archive),
+			Size:      int64(len(archive)),
+		}},
+	})
+	assert.Equal(t, nil, err)
+	manifestDigest := digest.FromBytes(manifest)
+	contents := map[string][]byte{
+		"/v2/charts/web/manifests/0.1.0":                             manifest,
+		"/v2/charts/web/manifests/" + manifestDigest.String():        manifest,
+		"/v2/charts/web/blobs/" + digest.FromBytes(archive).String(): archive,
+		"/v2/charts/web/blobs/" + digest.FromBytes(config).String():  config,
+	}
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data, ok := contents[req.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if strings.Contains(req.URL.Path, "/manifests/") {
+			w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+			w.Header().Set("Docker-Content-Digest", manifestDigest.String())
+		} else {
+			w.Header().Set("Content-Type", "application/octet-stream")
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		if req.Method != http.MethodHead {
+			w.Write(data)
+		}
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "https://")
+
+	defaultClient, registryConfig := ociClient, settings.RegistryConfig
+	defer func() {
+		ociClient, settings.RegistryConfig = defaultClient, registryConfig
+	}()
+	ociClient = ts.Client()
+	dir, err := ioutil.TempDir("", "oci-chart-test")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	// not logged in to the registry
+	settings.RegistryConfig = filepath.Join(dir, "registry.json")
+
+	chartRef := "oci://" + host + "/charts/web"
+	manifests, err := RenderChart("", chartRef, "0.1.0", "myweb", "default", map[string]interface{}{"greeting": "hello"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, strings.Contains(manifests, "name: myweb"))
+	assert.Equal(t, true, strings.Contains(manifests, "greeting: hello"))
+
+	// the chart is pulled by the digest of its manifest
+	manifests, err = RenderChart("", chartRef+"@"+manifestDigest.String(), "", "myweb", "default", nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, strings.Contains(manifests, "name: myweb"))
+
+	d, err := ResolveOCIChart(context.Background(), chartRef+":0.1.0", "")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, manifestDigest.String(), d)
+
+	_, err = RenderChart("", chartRef, "", "myweb", "default", nil)
+	assert.NotEqual(t, nil, err)
+	_, err = RenderChart("", chartRef, "0.2.0", "myweb", "default", nil)
+	assert.NotEqual(t, nil, err)
+}
@@ -16,6 +16,9 @@ const (
 	VelaHomeEnv = "VELA_HOME"
 	// StorageDriverEnv defines vela storage driver env
 	StorageDriverEnv = "STORAGE_DRIVER"
+	// DefinitionSchemaNamespaceEnv defines the namespace of the ConfigMaps which store the OpenAPI schema of definitions,
+	// it has to be the same as the `--definition-schema-namespace` of the controller
+	DefinitionSchemaNamespaceEnv = "VELA_DEFINITION_SCHEMA_NAMESPACE"
 )
 
 // GetVelaHomeDir return vela home dir
@@ -30,6 +33,14 @@ func GetVelaHomeDir() (string, error) {
 	return filepath.Join(home, defaultVelaHome), nil
 }
 
+// GetDefinitionSchemaNamespace return the namespace where the OpenAPI schema of definitions is read from
+func GetDefinitionSchemaNamespace() string {
+	if custom := os.Getenv(DefinitionSchemaNamespaceEnv); custom != "" {
+		return custom
+	}
+	return types.DefaultKubeVelaNS
+}
+
 // GetDefaultFrontendDir return default vela frontend dir
 func GetDefaultFrontendDir() (string, error) {
 	home, err := GetVelaHomeDir()
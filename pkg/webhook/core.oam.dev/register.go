@@ -3,6 +3,7 @@ package core_oam_dev
 import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
 	"github.com/oam-dev/kubevela/pkg/webhook/core.oam.dev/v1alpha2/application"
 	"github.com/oam-dev/kubevela/pkg/webhook/core.oam.dev/v1alpha2/applicationconfiguration"
 	"github.com/oam-dev/kubevela/pkg/webhook/core.oam.dev/v1alpha2/applicationdeployment"
@@ -11,8 +12,8 @@ import (
 )
 
 // Register will be called in main and register all validation handlers
-func Register(mgr manager.Manager) error {
-	if err := application.RegisterValidatingHandler(mgr); err != nil {
+func Register(mgr manager.Manager, args controller.Args) error {
+	if err := application.RegisterValidatingHandler(mgr, args); err != nil {
 		return err
 	}
 	if err := applicationconfiguration.RegisterValidatingHandler(mgr); err != nil {
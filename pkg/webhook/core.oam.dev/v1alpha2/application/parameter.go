@@ -0,0 +1,331 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue"
+	cueerrors "cuelang.org/go/cue/errors"
+	"github.com/getkin/kin-openapi/openapi3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	mycue "github.com/oam-dev/kubevela/pkg/cue"
+	"github.com/oam-dev/kubevela/pkg/dsl/definition"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
+)
+
+// validateWorkloadParameters validates the settings of a component according to the template type of its workload definition
+func (h *ValidatingHandler) validateWorkloadParameters(ctx context.Context, fldPath *field.Path, wl *appfile.Workload,
+	wd metav1.Object) field.ErrorList {
+	if wl.TemplateType == types.HelmTemplateType {
+		// the values of a chart have no schema to check
+		return nil
+	}
+	if errs, ok := h.validateStoredSchema(ctx, fldPath, types.TypeWorkload, wd, wl.Params); ok {
+		return errs
+	}
+	if wl.TemplateType == types.KubeTemplateType {
+		return validateKubeParameters(fldPath, wl.Template, wl.Params)
+	}
+	return validateParameters(fldPath, wl.Template, wl.Params)
+}
+
+// validateTraitParameters validates the properties of a trait against the schema of its trait definition
+func (h *ValidatingHandler) validateTraitParameters(ctx context.Context, fldPath *field.Path, tr *appfile.Trait,
+	td metav1.Object) field.ErrorList {
+	if errs, ok := h.validateStoredSchema(ctx, fldPath, types.TypeTrait, td, tr.Params); ok {
+		return errs
+	}
+	return validateParameters(fldPath, tr.Template, tr.Params)
+}
+
+// validateStoredSchema validates the parameters against the OpenAPI schema stored by the definition controller,
+// false is returned if the schema is not stored yet or it's stale, then the template has to be compiled to validate them.
+func (h *ValidatingHandler) validateStoredSchema(ctx context.Context, fldPath *field.Path, capType types.CapType,
+	def metav1.Object, params map[string]interface{}) (field.ErrorList, bool) {
+	if h.schemaNamespace == "" {
+		return nil, false
+	}
+	data, ok, err := common.GetCurrentDefinitionSchema(ctx, h.Client, h.schemaNamespace, capType, def)
+	if err != nil || !ok {
+		return nil, false
+	}
+	schema := &openapi3.Schema{}
+	if err := schema.UnmarshalJSON(data); err != nil {
+		return nil, false
+	}
+	// the values are checked as they're decoded from JSON, e.g. all numbers are float64
+	bt, err := json.Marshal(params)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, params, err.Error())}, true
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(bt, &values); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, params, err.Error())}, true
+	}
+	return validateSchemaFields(fldPath, schema, values, true), true
+}
+
+// validateKubeParameters validates the parameters against the parameter list of the kube template,
+// unknown parameters, missing required parameters and values conflicting with the schema are reported.
+func validateKubeParameters(fldPath *field.Path, template string, params map[string]interface{}) field.ErrorList {
+	t, err := definition.ParseKubeTemplate(template)
+	if err != nil {
+		return field.ErrorList{field.InternalError(fldPath, err)}
+	}
+	var errs field.ErrorList
+	supported := make([]string, 0, len(t.Parameters))
+	for _, p := range t.Parameters {
+		supported = append(supported, p.Name)
+		v, exist := params[p.Name]
+		if !exist {
+			if p.Required && p.Default == nil {
+				errs = append(errs, field.Required(fldPath.Child(p.Name), "required by the definition"))
+			}
+			continue
+		}
+		// the type of a reference to another component is only known after it's resolved
+		if appfile.IsComponentReference(v) {
+			continue
+		}
+		if err := p.Validate(v); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child(p.Name), v, err.Error()))
+		}
+	}
+	sort.Strings(supported)
+	var names []string
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		i := sort.SearchStrings(supported, name)
+		if i < len(supported) && supported[i] == name {
+			continue
+		}
+		// the built-in config of Appfile is not a part of the template parameters
+		if name == appfile.AppfileBuiltinConfig {
+			continue
+		}
+		errs = append(errs, field.NotSupported(fldPath, name, supported))
+	}
+	return errs
+}
+
+// validateParameters validates the parameters against the `parameter` schema of the definition template,
+// unknown fields, missing required fields and values conflicting with the schema are reported with their field paths.
+// A value which is a whole reference to another component is not checked against the schema,
+// its type is only known after the reference is resolved.
+// The template is only compiled if the OpenAPI schema stored by the definition controller is missing or stale.
+func validateParameters(fldPath *field.Path, template string, params map[string]interface{}) field.ErrorList {
+	if template == "" {
+		// the definition has no CUE template, there's no schema to check
+		return nil
+	}
+	var r cue.Runtime
+	schemaInst, err := r.Compile("-", template+mycue.BaseTemplate)
+	if err != nil {
+		return field.ErrorList{field.InternalError(fldPath, fmt.Errorf("compile template: %w", err))}
+	}
+	schema := schemaInst.Lookup(definition.ParameterFieldName)
+	if !schema.Exists() {
+		return nil
+	}
+	errs := validateFields(fldPath, schema, params, true)
+
+	if len(params) == 0 {
+		return errs
+	}
+	bt, err := json.Marshal(params)
+	if err != nil {
+		return append(errs, field.Invalid(fldPath, params, err.Error()))
+	}
+	inst, err := r.Compile("-", template+mycue.BaseTemplate+fmt.Sprintf("\n%s: %s", definition.ParameterFieldName, string(bt)))
+	if err != nil {
+		return append(errs, field.Invalid(fldPath, params, err.Error()))
+	}
+	if err := inst.Lookup(definition.ParameterFieldName).Validate(); err != nil {
+		for _, e := range cueerrors.Errors(err) {
+			path := e.Path()
+			if len(path) > 0 && path[0] == definition.ParameterFieldName {
+				path = path[1:]
+			}
+			v := valueAtPath(params, path)
+			if appfile.IsComponentReference(v) {
+				continue
+			}
+			p := fldPath
+			for _, seg := range path {
+				p = p.Child(seg)
+			}
+			errs = append(errs, field.Invalid(p, v, e.Error()))
+		}
+	}
+	return errs
+}
+
+// validateFields walks the parameters along with the schema to find unknown fields and missing required fields
+func validateFields(fldPath *field.Path, schema cue.Value, params map[string]interface{}, topLevel bool) field.ErrorList {
+	st, err := schema.Struct()
+	if err != nil {
+		// the schema is not a struct, e.g. a disjunction or top, let CUE unification check it
+		return nil
+	}
+	var errs field.ErrorList
+	fields := make(map[string]cue.FieldInfo, st.Len())
+	for i := 0; i < st.Len(); i++ {
+		fi := st.Field(i)
+		if fi.IsDefinition || fi.IsHidden {
+			continue
+		}
+		fields[fi.Name] = fi
+	}
+	// a struct with pattern constraints like `[string]: string` accepts any field
+	acceptAny := schema.Template() != nil
+
+	var supported []string
+	for name := range fields {
+		supported = append(supported, name)
+	}
+	sort.Strings(supported)
+	var names []string
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		val := params[name]
+		fi, exist := fields[name]
+		if !exist {
+			// the built-in config of Appfile is not a part of the template parameters
+			if acceptAny || (topLevel && name == appfile.AppfileBuiltinConfig) {
+				continue
+			}
+			errs = append(errs, field.NotSupported(fldPath, name, supported))
+			continue
+		}
+		if sub, ok := val.(map[string]interface{}); ok {
+			errs = append(errs, validateFields(fldPath.Child(name), fi.Value, sub, false)...)
+		}
+	}
+	for _, name := range supported {
+		fi := fields[name]
+		if fi.IsOptional {
+			continue
+		}
+		if _, exist := params[name]; exist {
+			continue
+		}
+		if _, hasDefault := fi.Value.Default(); hasDefault || fi.Value.IsConcrete() {
+			continue
+		}
+		errs = append(errs, field.Required(fldPath.Child(name), "required by the definition"))
+	}
+	return errs
+}
+
+// validateSchemaFields walks the parameters along with the OpenAPI schema to find unknown fields, missing required fields
+// and values conflicting with the schema
+func validateSchemaFields(fldPath *field.Path, schema *openapi3.Schema, params map[string]interface{}, topLevel bool) field.ErrorList {
+	var errs field.ErrorList
+	supported := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		supported = append(supported, name)
+	}
+	sort.Strings(supported)
+	// an object without properties or with additional properties accepts any field
+	acceptAny := len(schema.Properties) == 0 || schema.AdditionalProperties != nil ||
+		(schema.AdditionalPropertiesAllowed != nil && *schema.AdditionalPropertiesAllowed)
+
+	var names []string
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ref, exist := schema.Properties[name]
+		if !exist {
+			// the built-in config of Appfile is not a part of the template parameters
+			if acceptAny || (topLevel && name == appfile.AppfileBuiltinConfig) {
+				continue
+			}
+			errs = append(errs, field.NotSupported(fldPath, name, supported))
+			continue
+		}
+		if ref.Value != nil {
+			errs = append(errs, validateSchemaValue(fldPath.Child(name), ref.Value, params[name])...)
+		}
+	}
+	required := append([]string(nil), schema.Required...)
+	sort.Strings(required)
+	for _, name := range required {
+		if _, exist := params[name]; exist {
+			continue
+		}
+		if ref, exist := schema.Properties[name]; exist && ref.Value != nil && ref.Value.Default != nil {
+			continue
+		}
+		errs = append(errs, field.Required(fldPath.Child(name), "required by the definition"))
+	}
+	return errs
+}
+
+// validateSchemaValue validates a value against its OpenAPI schema, the objects and arrays are walked into
+// so that the errors are reported with the paths of the fields
+func validateSchemaValue(fldPath *field.Path, schema *openapi3.Schema, val interface{}) field.ErrorList {
+	// the type of a reference to another component is only known after it's resolved
+	if appfile.IsComponentReference(val) {
+		return nil
+	}
+	switch v := val.(type) {
+	case map[string]interface{}:
+		if schema.Type == "object" {
+			return validateSchemaFields(fldPath, schema, v, false)
+		}
+	case []interface{}:
+		if schema.Type == "array" && schema.Items != nil && schema.Items.Value != nil {
+			var errs field.ErrorList
+			for i, item := range v {
+				errs = append(errs, validateSchemaValue(fldPath.Index(i), schema.Items.Value, item)...)
+			}
+			return errs
+		}
+	}
+	if err := schema.VisitJSON(val); err != nil {
+		msg := err.Error()
+		var se *openapi3.SchemaError
+		if errors.As(err, &se) && se.Reason != "" {
+			msg = se.Reason
+		}
+		return field.ErrorList{field.Invalid(fldPath, val, msg)}
+	}
+	return nil
+}
+
+// valueAtPath returns the value at the path of the parameters, nil if it doesn't exist
+func valueAtPath(params map[string]interface{}, path []string) interface{} {
+	var cur interface{} = params
+	for _, seg := range path {
+		switch val := cur.(type) {
+		case map[string]interface{}:
+			cur = val[strings.Trim(seg, `"`)]
+		case []interface{}:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(val) {
+				return nil
+			}
+			cur = val[i]
+		default:
+			return nil
+		}
+	}
+	return cur
+}
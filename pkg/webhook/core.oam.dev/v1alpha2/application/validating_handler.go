@@ -12,6 +12,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	controller "github.com/oam-dev/kubevela/pkg/controller/core.oam.dev"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 )
 
@@ -21,6 +22,8 @@ var _ admission.Handler = &ValidatingHandler{}
 type ValidatingHandler struct {
 	dm     discoverymapper.DiscoveryMapper
 	Client client.Client
+	// schemaNamespace is the namespace of the ConfigMaps which store the OpenAPI schema of definitions
+	schemaNamespace string
 	// Decoder decodes objects
 	Decoder *admission.Decoder
 }
@@ -75,12 +78,12 @@ func (h *ValidatingHandler) Handle(ctx context.Context, req admission.Request) a
 }
 
 // RegisterValidatingHandler will regsiter application validate handler to the webhook
-func RegisterValidatingHandler(mgr manager.Manager) error {
+func RegisterValidatingHandler(mgr manager.Manager, args controller.Args) error {
 	mapper, err := discoverymapper.New(mgr.GetConfig())
 	if err != nil {
 		return err
 	}
 	server := mgr.GetWebhookServer()
-	server.Register("/validating-core-oam-dev-v1alpha2-applications", &webhook.Admission{Handler: &ValidatingHandler{dm: mapper}})
+	server.Register("/validating-core-oam-dev-v1alpha2-applications", &webhook.Admission{Handler: &ValidatingHandler{dm: mapper, schemaNamespace: args.DefinitionSchemaNamespace}})
 	return nil
 }
@@ -2,13 +2,25 @@ package application
 
 import (
 	"context"
+	"encoding/json"
+	"strconv"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/types"
+	"github.com/oam-dev/kubevela/pkg/appfile"
+	"github.com/oam-dev/kubevela/pkg/utils/common"
 )
 
 var _ = Describe("Test Application Validator", func() {
@@ -98,4 +110,236 @@ var _ = Describe("Test Application Validator", func() {
 		resp := handler.Handle(ctx, req)
 		Expect(resp.Allowed).Should(BeFalse())
 	})
+
+	It("Test Application Validator [Cycle in dependsOn]", func() {
+		req := admission.Request{
+			AdmissionRequest: admissionv1beta1.AdmissionRequest{
+				Operation: admissionv1beta1.Create,
+				Resource:  metav1.GroupVersionResource{Group: "core.oam.dev", Version: "v1alpha2", Resource: "applications"},
+				Object: runtime.RawExtension{
+					Raw: []byte(`
+{"apiVersion":"core.oam.dev/v1alpha2",
+"kind":"Application",
+"metadata":{"name":"application-sample"},
+"spec":{"components":[{"name":"myweb","settings":{"cmd":["sleep","1000"],"image":"busybox"},"type":"worker","dependsOn":["mydb"]},
+{"name":"mydb","settings":{"cmd":["sleep","1000"],"image":"busybox"},"type":"worker","dependsOn":["myweb"]}]}}
+`),
+				},
+			},
+		}
+		resp := handler.Handle(ctx, req)
+		Expect(resp.Allowed).Should(BeFalse())
+	})
+
+	It("Test Application Validator [Reference to another component]", func() {
+		req := admission.Request{
+			AdmissionRequest: admissionv1beta1.AdmissionRequest{
+				Operation: admissionv1beta1.Create,
+				Resource:  metav1.GroupVersionResource{Group: "core.oam.dev", Version: "v1alpha2", Resource: "applications"},
+				Object: runtime.RawExtension{
+					Raw: []byte(`
+{"apiVersion":"core.oam.dev/v1alpha2",
+"kind":"Application",
+"metadata":{"name":"application-sample"},
+"spec":{"components":[{"name":"myweb","settings":{"image":"busybox"},
+"traits":[{"name":"scaler","properties":{"replicas":"$(components.mydb.output.spec.replicas)"}}],"type":"worker"},
+{"name":"mydb","settings":{"image":"mysql"},"type":"worker"}]}}
+`),
+				},
+			},
+		}
+		resp := handler.Handle(ctx, req)
+		Expect(resp.Allowed).Should(BeTrue())
+	})
+
+	It("Test Application Validator [Invalid policy]", func() {
+		app := &v1alpha2.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "application-sample"},
+			Spec: v1alpha2.ApplicationSpec{
+				Components: []v1alpha2.ApplicationComponent{{
+					Name:         "myweb",
+					WorkloadType: "worker",
+					Settings:     runtime.RawExtension{Raw: []byte(`{"image":"busybox"}`)},
+				}},
+				Policies: []v1alpha2.ApplicationTrait{{
+					Name:       "scaler",
+					Properties: runtime.RawExtension{Raw: []byte(`{"replicas":"two"}`)},
+				}},
+			},
+		}
+		errs := handler.ValidateCreate(app)
+		Expect(errs).ShouldNot(BeEmpty())
+		Expect(errs[0].Field).Should(HavePrefix("spec.policies[0].properties"))
+	})
+
+	It("Test validate trait conflicts of policies", func() {
+		scaler := &v1alpha2.TraitDefinition{ObjectMeta: metav1.ObjectMeta{Name: "scaler"}}
+		exclusive := &v1alpha2.TraitDefinition{ObjectMeta: metav1.ObjectMeta{Name: "exclusive"},
+			Spec: v1alpha2.TraitDefinitionSpec{ConflictsWith: []string{"*"}}}
+		app := &v1alpha2.Application{
+			Spec: v1alpha2.ApplicationSpec{
+				Components: []v1alpha2.ApplicationComponent{{
+					Name:   "myweb",
+					Traits: []v1alpha2.ApplicationTrait{{Name: "scaler"}},
+				}},
+				Policies: []v1alpha2.ApplicationTrait{{Name: "exclusive"}},
+			},
+		}
+		trPaths := []*field.Path{traitPath(app, 0, 0, "scaler"), traitPath(app, 0, 1, "exclusive")}
+		errs := validateTraitConflicts(trPaths, []*v1alpha2.TraitDefinition{scaler, exclusive})
+		Expect(errs).Should(HaveLen(1))
+		Expect(errs[0].Field).Should(Equal("spec.policies[0]"))
+	})
+
+	It("Test validate parameters with references to other components", func() {
+		template := `
+parameter: {
+	port: int
+	ports: [...int]
+	host: string
+}
+`
+		Expect(validateParameters(field.NewPath("settings"), template, map[string]interface{}{
+			"port":  "$(components.db.output.spec.port)",
+			"ports": []interface{}{float64(80), "$(components.db.output.spec.port)"},
+			"host":  "$(components.db.outputs.service.metadata.name)",
+		})).Should(BeEmpty())
+
+		By("a string containing references is still a string")
+		errs := validateParameters(field.NewPath("settings"), template, map[string]interface{}{
+			"port":  "port-$(components.db.output.spec.port)",
+			"ports": []interface{}{},
+			"host":  "mysql://$(components.db.outputs.service.metadata.name)",
+		})
+		Expect(errs).Should(HaveLen(1))
+		Expect(errs[0].Field).Should(Equal("settings.port"))
+	})
+
+	It("Test validate parameters reports errors in order", func() {
+		template := `
+parameter: {
+	image: string
+	port: int
+	env: {
+		name: string
+		value: string
+	}
+}
+`
+		errs := validateParameters(field.NewPath("settings"), template, map[string]interface{}{
+			"zone":   "a",
+			"env":    map[string]interface{}{"key": "k"},
+			"region": "b",
+		})
+		var fields []string
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).Should(Equal([]string{
+			"settings.env", "settings.env.name", "settings.env.value", "settings", "settings",
+			"settings.image", "settings.port",
+		}))
+		Expect(errs[3].BadValue).Should(Equal("region"))
+		Expect(errs[4].BadValue).Should(Equal("zone"))
+	})
+
+	It("Test validate parameters against the OpenAPI schema", func() {
+		data, err := common.GenOpenAPISchemaFromTemplate(`
+parameter: {
+	image: string
+	port: *80 | int
+	env?: [...{
+		name: string
+		value?: string
+	}]
+	labels?: [string]: string
+}
+`)
+		Expect(err).Should(BeNil())
+		schema := &openapi3.Schema{}
+		Expect(schema.UnmarshalJSON(data)).Should(BeNil())
+
+		Expect(validateSchemaFields(field.NewPath("settings"), schema, map[string]interface{}{
+			"image":  "$(components.db.output.spec.image)",
+			"env":    []interface{}{map[string]interface{}{"name": "k", "value": "$(components.db.output.spec.port)"}},
+			"labels": map[string]interface{}{"app": "web"},
+		}, true)).Should(BeEmpty())
+
+		errs := validateSchemaFields(field.NewPath("settings"), schema, map[string]interface{}{
+			"port": "80",
+			"env":  []interface{}{map[string]interface{}{"value": "v"}},
+			"zone": "a",
+		}, true)
+		var fields []string
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).Should(Equal([]string{"settings.env[0].name", "settings.port", "settings", "settings.image"}))
+	})
+
+	It("Test validate parameters by the stored schema unless it's stale", func() {
+		wd := &v1alpha2.WorkloadDefinition{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "worker"}, wd)).Should(BeNil())
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      common.SchemaConfigMapName(types.TypeWorkload, "worker"),
+				Namespace: "default",
+				Annotations: map[string]string{
+					common.SchemaGenerationAnnotation: strconv.FormatInt(wd.Generation, 10),
+				},
+			},
+			// the stored schema doesn't accept `cmd`, so that it's told apart from the template
+			Data: map[string]string{
+				common.OpenAPISchemaKey: `{"properties":{"image":{"type":"string"}},"required":["image"],"type":"object"}`,
+			},
+		}
+		Expect(controllerutil.SetControllerReference(wd, cm, testScheme)).Should(BeNil())
+		Expect(k8sClient.Create(ctx, cm)).Should(BeNil())
+		h := &ValidatingHandler{Client: k8sClient, schemaNamespace: "default"}
+		var ext struct {
+			Template string `json:"template"`
+		}
+		Expect(json.Unmarshal(wd.Spec.Extension.Raw, &ext)).Should(BeNil())
+		wl := &appfile.Workload{
+			Template: ext.Template,
+			Params:   map[string]interface{}{"image": "busybox", "cmd": []interface{}{"sleep", "1000"}},
+		}
+		errs := h.validateWorkloadParameters(ctx, field.NewPath("settings"), wl, wd)
+		Expect(errs).Should(HaveLen(1))
+		Expect(errs[0].BadValue).Should(Equal("cmd"))
+
+		By("the template is compiled if the stored schema is stale")
+		cm.Annotations[common.SchemaGenerationAnnotation] = strconv.FormatInt(wd.Generation+1, 10)
+		Expect(k8sClient.Update(ctx, cm)).Should(BeNil())
+		Expect(h.validateWorkloadParameters(ctx, field.NewPath("settings"), wl, wd)).Should(BeEmpty())
+		Expect(k8sClient.Delete(ctx, cm)).Should(BeNil())
+	})
+
+	It("Test Application Validator [Invalid parameters]", func() {
+		for _, settings := range []string{
+			// unknown field
+			`{"image":"busybox","commands":["sleep","1000"]}`,
+			// missing required field
+			`{"cmd":["sleep","1000"]}`,
+			// wrong type
+			`{"image":"busybox","cmd":"sleep 1000"}`,
+		} {
+			req := admission.Request{
+				AdmissionRequest: admissionv1beta1.AdmissionRequest{
+					Operation: admissionv1beta1.Create,
+					Resource:  metav1.GroupVersionResource{Group: "core.oam.dev", Version: "v1alpha2", Resource: "applications"},
+					Object: runtime.RawExtension{
+						Raw: []byte(`
+{"apiVersion":"core.oam.dev/v1alpha2",
+"kind":"Application",
+"metadata":{"name":"application-sample"},
+"spec":{"components":[{"name":"myworker","settings":` + settings + `,"type":"worker"}]}}
+`),
+					},
+				},
+			}
+			resp := handler.Handle(ctx, req)
+			Expect(resp.Allowed).Should(BeFalse(), settings)
+		}
+	})
 })
@@ -1,11 +1,18 @@
 package application
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
 	"github.com/oam-dev/kubevela/pkg/appfile"
 	"github.com/oam-dev/kubevela/pkg/oam"
+	"github.com/oam-dev/kubevela/pkg/oam/util"
 )
 
 // ValidateCreate validates the Application on creation
@@ -13,12 +20,68 @@ func (h *ValidatingHandler) ValidateCreate(app *v1alpha2.Application) field.Erro
 	var componentErrs field.ErrorList
 	// try to generate an app file
 	appParser := appfile.NewApplicationParser(h.Client, h.dm)
-	if _, err := appParser.GenerateAppFile(app.Name, app); err != nil {
+	af, err := appParser.GenerateAppFile(app.Name, app)
+	if err != nil {
 		componentErrs = append(componentErrs, field.Invalid(field.NewPath("spec"), app, err.Error()))
+	} else {
+		componentErrs = append(componentErrs, h.validateComponents(app, af)...)
 	}
+	componentErrs = append(componentErrs, validateDependencies(app.Spec.Components)...)
 	return componentErrs
 }
 
+// validateDependencies checks the components depended on exist and there's no cycle among the dependencies
+func validateDependencies(comps []v1alpha2.ApplicationComponent) field.ErrorList {
+	var errs field.ErrorList
+	deps := make(map[string][]string, len(comps))
+	for _, comp := range comps {
+		deps[comp.Name] = comp.DependsOn
+	}
+	for i, comp := range comps {
+		for j, dep := range comp.DependsOn {
+			if _, exist := deps[dep]; !exist {
+				errs = append(errs, field.NotFound(field.NewPath("spec", "components").Index(i).Child("dependsOn").Index(j), dep))
+			}
+		}
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make(map[string]int, len(comps))
+	var visit func(name string) bool
+	// visit returns false if a cycle is found from the component
+	visit = func(name string) bool {
+		switch states[name] {
+		case visiting:
+			return false
+		case visited:
+			return true
+		}
+		states[name] = visiting
+		for _, dep := range deps[name] {
+			if !visit(dep) {
+				return false
+			}
+		}
+		states[name] = visited
+		return true
+	}
+	for i, comp := range comps {
+		if !visit(comp.Name) {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "components").Index(i).Child("dependsOn"),
+				comp.DependsOn, "cycle detected in the dependencies of components"))
+			break
+		}
+	}
+	return errs
+}
+
 // ValidateUpdate validates the Application on update
 func (h *ValidatingHandler) ValidateUpdate(newApp, oldApp *v1alpha2.Application) field.ErrorList {
 	// check if the newApp is valid
@@ -32,3 +95,116 @@ func (h *ValidatingHandler) ValidateUpdate(newApp, oldApp *v1alpha2.Application)
 	}
 	return componentErrs
 }
+
+// validateComponents validates the settings and traits of every component against their definitions,
+// the workloads of the Appfile are in the same order as the components of the Application
+func (h *ValidatingHandler) validateComponents(app *v1alpha2.Application, af *appfile.Appfile) field.ErrorList {
+	var errs field.ErrorList
+	ctx := context.Background()
+	for i, wl := range af.Workloads {
+		compPath := field.NewPath("spec", "components").Index(i)
+		wd, err := util.GetWorkloadDefinition(ctx, h.Client, wl.Type)
+		if err != nil {
+			errs = append(errs, field.Invalid(compPath.Child("type"), wl.Type, err.Error()))
+			continue
+		}
+		errs = append(errs, h.validateWorkloadParameters(ctx, compPath.Child("settings"), wl, wd)...)
+		tds := make([]*v1alpha2.TraitDefinition, len(wl.Traits))
+		trPaths := make([]*field.Path, len(wl.Traits))
+		for j, tr := range wl.Traits {
+			trPath := traitPath(app, i, j, tr.Name)
+			trPaths[j] = trPath
+			td, err := util.GetTraitDefinition(ctx, h.Client, tr.Name)
+			if err != nil {
+				errs = append(errs, field.Invalid(trPath.Child("name"), tr.Name, err.Error()))
+				continue
+			}
+			errs = append(errs, h.validateTraitParameters(ctx, trPath.Child("properties"), tr, td)...)
+			if !traitAppliesTo(td, wd) {
+				errs = append(errs, field.Invalid(trPath.Child("name"), tr.Name,
+					fmt.Sprintf("the trait cannot apply to workload type %q (appliable: %q)", wd.Name, td.Spec.AppliesToWorkloads)))
+			}
+			tds[j] = td
+		}
+		errs = append(errs, validateTraitConflicts(trPaths, tds)...)
+	}
+	return errs
+}
+
+// traitPath returns the field path of the j-th trait of the i-th component, the traits declared by the component
+// come first and the ones attached by application-level policies follow
+func traitPath(app *v1alpha2.Application, i, j int, name string) *field.Path {
+	compTraits := app.Spec.Components[i].Traits
+	if j < len(compTraits) {
+		return field.NewPath("spec", "components").Index(i).Child("traits").Index(j)
+	}
+	for k, policy := range app.Spec.Policies {
+		if policy.Name == name {
+			return field.NewPath("spec", "policies").Index(k)
+		}
+	}
+	return field.NewPath("spec", "components").Index(i).Child("traits").Index(j)
+}
+
+// traitAppliesTo checks whether the trait is allowed to apply to the workload according to AppliesToWorkloads,
+// the rules are the same as the ones of ApplicationConfiguration
+func traitAppliesTo(td *v1alpha2.TraitDefinition, wd *v1alpha2.WorkloadDefinition) bool {
+	if len(td.Spec.AppliesToWorkloads) == 0 {
+		return true
+	}
+	crdName := wd.Spec.Reference.Name
+	workloadGroup := schema.ParseGroupResource(crdName).Group
+	for _, applyTo := range td.Spec.AppliesToWorkloads {
+		if applyTo == "*" ||
+			(strings.HasPrefix(applyTo, "*.") && workloadGroup == applyTo[2:]) ||
+			crdName == applyTo || wd.Name == applyTo {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTraitConflicts checks the ConflictsWith rules among the traits of the same component,
+// the rules are the same as the ones of ApplicationConfiguration.
+// The definition of a trait failed to be fetched is nil, it has been reported and is skipped here.
+// The conflicts are reported at the field paths of the traits, which are under spec.policies for the policy traits.
+func validateTraitConflicts(trPaths []*field.Path, tds []*v1alpha2.TraitDefinition) field.ErrorList {
+	var errs field.ErrorList
+	for i, owner := range tds {
+		if owner == nil {
+			continue
+		}
+		for _, rule := range owner.Spec.ConflictsWith {
+			if rule == "*" {
+				if len(tds) != 1 {
+					errs = append(errs, field.Invalid(trPaths[i], owner.Name, "the trait conflicts with all other traits"))
+				}
+				continue
+			}
+			var ruleLabelSelector labels.Selector
+			if strings.HasPrefix(rule, "labelSelector:") {
+				selector, err := labels.Parse(rule[len("labelSelector:"):])
+				if err != nil {
+					errs = append(errs, field.Invalid(trPaths[i], owner.Name,
+						fmt.Sprintf("labelSelector in conflict rule (%q) is invalid for %v", rule, err)))
+					continue
+				}
+				ruleLabelSelector = selector
+			}
+			for j, td := range tds {
+				if i == j || td == nil {
+					continue
+				}
+				traitCRDName := td.Spec.Reference.Name
+				traitGroup := schema.ParseGroupResource(traitCRDName).Group
+				if (strings.HasPrefix(rule, "*.") && traitGroup == rule[2:]) ||
+					traitCRDName == rule || td.Name == rule ||
+					(ruleLabelSelector != nil && ruleLabelSelector.Matches(labels.Set(td.Labels))) {
+					errs = append(errs, field.Invalid(trPaths[i], owner.Name,
+						fmt.Sprintf("conflict(rule: %q) with trait %q is detected", rule, td.Name)))
+				}
+			}
+		}
+	}
+	return errs
+}
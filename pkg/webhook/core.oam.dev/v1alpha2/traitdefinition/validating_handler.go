@@ -18,6 +18,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/apis/types"
 	"github.com/oam-dev/kubevela/pkg/oam/discoverymapper"
 )
 
@@ -25,6 +26,8 @@ const (
 	errValidateDefRef = "error occurs when validating definition reference"
 
 	failInfoDefRefOmitted = "if definition reference is omitted, patch or output with GVK is required"
+
+	errFmtTemplateType = "templateType %s is not supported by trait, only CUE template is supported"
 )
 
 var traitDefGVR = v1alpha2.SchemeGroupVersion.WithResource("traitdefinitions")
@@ -106,6 +109,7 @@ func RegisterValidatingHandler(mgr manager.Manager) error {
 		Mapper: mapper,
 		Validators: []TraitDefValidator{
 			TraitDefValidatorFn(ValidateDefinitionReference),
+			TraitDefValidatorFn(ValidateTemplateType),
 			// add more validators here
 		},
 	}})
@@ -133,3 +137,12 @@ func ValidateDefinitionReference(_ context.Context, td v1alpha2.TraitDefinition)
 	}
 	return nil
 }
+
+// ValidateTemplateType validates the template of trait definition is in CUE format,
+// the other formats are only supported by workload definition.
+func ValidateTemplateType(_ context.Context, td v1alpha2.TraitDefinition) error {
+	if td.Spec.TemplateType != "" && td.Spec.TemplateType != types.CUETemplateType {
+		return errors.Errorf(errFmtTemplateType, td.Spec.TemplateType)
+	}
+	return nil
+}
@@ -58,6 +58,24 @@ func TestValidateDefinitionReference(t *testing.T) {
 	}
 }
 
+func TestValidateTemplateType(t *testing.T) {
+	td, err := util.UnMarshalStringToTraitDefinition(traitDefStringWithTemplate(`
+  templateType: cue
+  template: |
+    patch: spec: replicas: parameter.replicas`))
+	if err != nil {
+		t.Fatal("error occurs in generating TraitDefinition string", err.Error())
+	}
+	if err := ValidateTemplateType(context.Background(), *td); err != nil {
+		t.Errorf("ValidateTemplateType: unexpected error %v", err)
+	}
+	td.Spec.TemplateType = "helm"
+	want := errors.Errorf(errFmtTemplateType, "helm")
+	if diff := cmp.Diff(want, ValidateTemplateType(context.Background(), *td), test.EquateErrors()); diff != "" {
+		t.Errorf("\nValidateTemplateType: -want , +got \n%s\n", diff)
+	}
+}
+
 func traitDefStringWithTemplate(t string) string {
 	return fmt.Sprintf(`
 apiVersion: core.oam.dev/v1alpha2